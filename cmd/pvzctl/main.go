@@ -0,0 +1,139 @@
+// pvzctl - утилита для операционных задач, работающая с базой напрямую
+// через сервисный слой, без HTTP и JWT.
+//
+//	PVZCTL_PASSWORD=secret pvzctl users create -email admin@example.com -role moderator
+//	pvzctl pvz list -o json
+//	pvzctl receptions close -pvz <id>
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"pvz-service/config"
+	"pvz-service/internal/database"
+	"pvz-service/internal/pkg/actor"
+	"pvz-service/internal/pkg/mailer"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/services"
+	"sort"
+	// расписания ПВЗ задаются в поясах IANA, база поясов может отсутствовать в образе
+	_ "time/tzdata"
+
+	"github.com/sirupsen/logrus"
+)
+
+type app struct {
+	services *services.Services
+	out      *output
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = map[string]map[string]command{
+	"users":      userCommands,
+	"pvz":        pvzCommands,
+	"receptions": receptionCommands,
+	"products":   productCommands,
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	group, ok := commands[os.Args[1]]
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+	cmd, ok := group[os.Args[2]]
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// служебные логи сервиса не должны мешать выводу команды
+	logrus.SetOutput(os.Stderr)
+	logrus.SetLevel(logrus.WarnLevel)
+
+	ctx := actor.WithActor(context.Background(), actor.Actor{
+		UserID: "pvzctl:" + osUser(),
+		Role:   "pvzctl",
+	})
+
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	pool, err := database.ConnectDB(ctx, cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	a := &app{
+		services: services.NewServices(repositories.NewRepos(cfg, pool, nil), mail),
+		out:      &output{w: os.Stdout},
+	}
+
+	err = cmd.run(ctx, a, os.Args[3:])
+	// письмо подтверждения уходит в фоне, без ожидания процесс завершится раньше
+	a.services.Background.Wait()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		pool.Close()
+		os.Exit(1)
+	}
+}
+
+// newFlagSet добавляет общий для всех команд флаг формата вывода
+func (a *app) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&a.out.format, "o", "table", "output format: table or json")
+	return fs
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: pvzctl <group> <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+
+	groups := make([]string, 0, len(commands))
+	for name := range commands {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+
+	for _, g := range groups {
+		names := make([]string, 0, len(commands[g]))
+		for name := range commands[g] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(os.Stderr, "  %s %s %s\n", g, name, commands[g][name].usage)
+		}
+	}
+}
+
+func osUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
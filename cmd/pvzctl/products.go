@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"pvz-service/internal/models"
+	"time"
+)
+
+var productCommands = map[string]command{
+	"list":        {usage: "-reception <reception id>", run: listProducts},
+	"delete-last": {usage: "-pvz <pvz id>", run: deleteLastProduct},
+}
+
+func listProducts(ctx context.Context, a *app, args []string) error {
+	fs := a.newFlagSet("products list")
+	receptionID := fs.String("reception", "", "reception id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *receptionID == "" {
+		return fmt.Errorf("-reception is required")
+	}
+
+	products, err := a.services.ProductService.GetByReceptionID(ctx, *receptionID)
+	if err != nil {
+		return err
+	}
+	if products == nil {
+		products = []models.Product{}
+	}
+
+	rows := make([][]string, 0, len(products))
+	for _, p := range products {
+		rows = append(rows, []string{p.ID, p.Type, p.DateTime.Format(time.RFC3339)})
+	}
+
+	return a.out.print(products, []string{"ID", "TYPE", "ADDED"}, rows)
+}
+
+func deleteLastProduct(ctx context.Context, a *app, args []string) error {
+	fs := a.newFlagSet("products delete-last")
+	pvzID := fs.String("pvz", "", "PVZ id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *pvzID == "" {
+		return fmt.Errorf("-pvz is required")
+	}
+
+	if err := a.services.PvzService.DeleteLastProduct(ctx, *pvzID); err != nil {
+		return err
+	}
+
+	return a.out.print(map[string]string{"message": "deleted"}, []string{"RESULT"}, [][]string{{"deleted"}})
+}
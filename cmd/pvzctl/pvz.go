@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"pvz-service/internal/models"
+	"strconv"
+	"time"
+)
+
+var pvzCommands = map[string]command{
+	"list":   {usage: "[-page 1] [-limit 50]", run: listPVZ},
+	"get":    {usage: "-id <pvz id>", run: inspectPVZ},
+	"create": {usage: "-city <city>", run: createPVZ},
+}
+
+func listPVZ(ctx context.Context, a *app, args []string) error {
+	fs := a.newFlagSet("pvz list")
+	page := fs.Int("page", 1, "page number")
+	limit := fs.Int("limit", 50, "page size")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *page < 1 || *limit < 1 {
+		return fmt.Errorf("-page and -limit must be positive")
+	}
+
+	pvzs, err := a.services.PvzService.ListPVZ(ctx, *limit, (*page-1)**limit)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(pvzs))
+	for _, pvz := range pvzs {
+		rows = append(rows, pvzRow(pvz))
+	}
+
+	return a.out.print(pvzs, []string{"ID", "CITY", "REGISTERED"}, rows)
+}
+
+// inspectPVZ показывает ПВЗ вместе с открытой приемкой и ее товарами
+func inspectPVZ(ctx context.Context, a *app, args []string) error {
+	fs := a.newFlagSet("pvz get")
+	id := fs.String("id", "", "PVZ id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return fmt.Errorf("-id is required")
+	}
+
+	pvz, err := a.services.PvzService.GetPVZByID(ctx, *id)
+	if err != nil {
+		return err
+	}
+
+	reception, err := a.services.ReceptionService.GetActiveReceptionByPVZID(ctx, pvz.ID)
+	if err != nil {
+		return err
+	}
+
+	details := struct {
+		models.PVZ
+		ActiveReception *models.Reception `json:"activeReception"`
+		Products        []models.Product  `json:"products"`
+	}{PVZ: pvz, Products: []models.Product{}}
+
+	rows := [][]string{
+		{"id", pvz.ID},
+		{"city", pvz.City},
+		{"registered", pvz.RegistrationDate.Format(time.RFC3339)},
+		{"active reception", "-"},
+	}
+
+	if reception != nil {
+		products, err := a.services.ProductService.GetByReceptionID(ctx, reception.ID)
+		if err != nil {
+			return err
+		}
+		details.ActiveReception = reception
+		if products != nil {
+			details.Products = products
+		}
+
+		rows[3][1] = reception.ID
+		rows = append(rows,
+			[]string{"reception opened", reception.DateTime.Format(time.RFC3339)},
+			[]string{"products", strconv.Itoa(len(products))},
+		)
+	}
+
+	return a.out.print(details, []string{"FIELD", "VALUE"}, rows)
+}
+
+func createPVZ(ctx context.Context, a *app, args []string) error {
+	fs := a.newFlagSet("pvz create")
+	city := fs.String("city", "", "PVZ city")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *city == "" {
+		return fmt.Errorf("-city is required")
+	}
+
+	pvz, err := a.services.PvzService.CreatePVZ(ctx, models.PVZ{City: *city})
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(pvz, []string{"ID", "CITY", "REGISTERED"}, [][]string{pvzRow(pvz)})
+}
+
+func pvzRow(pvz models.PVZ) []string {
+	return []string{pvz.ID, pvz.City, pvz.RegistrationDate.Format(time.RFC3339)}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"pvz-service/internal/models"
+	"time"
+)
+
+var receptionCommands = map[string]command{
+	"active": {usage: "-pvz <pvz id>", run: activeReception},
+	"close":  {usage: "-pvz <pvz id>", run: closeReception},
+}
+
+func activeReception(ctx context.Context, a *app, args []string) error {
+	fs := a.newFlagSet("receptions active")
+	pvzID := fs.String("pvz", "", "PVZ id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *pvzID == "" {
+		return fmt.Errorf("-pvz is required")
+	}
+
+	reception, err := a.services.ReceptionService.GetActiveReceptionByPVZID(ctx, *pvzID)
+	if err != nil {
+		return err
+	}
+	if reception == nil {
+		return fmt.Errorf("no active reception for PVZ %s", *pvzID)
+	}
+
+	return printReception(a, *reception)
+}
+
+// closeReception принудительно закрывает зависшую приемку ПВЗ
+func closeReception(ctx context.Context, a *app, args []string) error {
+	fs := a.newFlagSet("receptions close")
+	pvzID := fs.String("pvz", "", "PVZ id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *pvzID == "" {
+		return fmt.Errorf("-pvz is required")
+	}
+
+	reception, err := a.services.ReceptionService.GetActiveReceptionByPVZID(ctx, *pvzID)
+	if err != nil {
+		return err
+	}
+	if reception == nil {
+		return fmt.Errorf("no active reception for PVZ %s", *pvzID)
+	}
+
+	if err := a.services.PvzService.CloseLastReception(ctx, *pvzID); err != nil {
+		return err
+	}
+
+	reception.Status = "close"
+	return printReception(a, *reception)
+}
+
+func printReception(a *app, reception models.Reception) error {
+	return a.out.print(reception, []string{"ID", "PVZ", "STATUS", "OPENED"}, [][]string{{
+		reception.ID, reception.PvzId, reception.Status, reception.DateTime.Format(time.RFC3339),
+	}})
+}
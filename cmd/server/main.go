@@ -2,38 +2,221 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
 	"pvz-service/config"
 	"pvz-service/internal/database"
 	"pvz-service/internal/handlers"
+	"pvz-service/internal/health"
+	"pvz-service/internal/jobs"
 	"pvz-service/internal/logger"
+	"pvz-service/internal/middlewares"
+	"pvz-service/internal/pkg/mailer"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/repositories/memory"
 	"pvz-service/internal/routes"
+	"pvz-service/internal/services"
+	"pvz-service/internal/tracing"
+	"strings"
+	"syscall"
+	"time"
+	// расписания ПВЗ задаются в поясах IANA, база поясов может отсутствовать в образе
+	_ "time/tzdata"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// команда (migrate up, config print) идет перед флагами конфигурации
+	cmd, args := splitCommand(os.Args[1:])
+
 	// init config
-	cfg, err := config.NewConfig()
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "Некорректная конфигурация:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	switch {
+	case len(cmd) == 0:
+	// pvz-service migrate up|down|status
+	case cmd[0] == "migrate":
+		os.Exit(runMigrate(ctx, cfg, cmd[1:]))
+	// pvz-service config print
+	case cmd[0] == "config":
+		os.Exit(runConfig(cfg, cmd[1:]))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, usage: pvz-service [migrate|config] [flags]\n", cmd[0])
+		os.Exit(2)
 	}
 
 	// init logger
-	logger.InitLogger(cfg.LOG_LEVEL, cfg.MODE)
+	closeLogs, err := logger.InitLogger(cfg.Log, cfg.Mode)
+	if err != nil {
+		panic(err)
+	}
+	defer closeLogs()
+
+	// init storage
+	checker := health.NewChecker()
+	repos, cluster := initStorage(ctx, cfg, checker)
+	var dbStats handlers.DBStatsProvider = database.Pools{}
+	if cluster != nil {
+		defer cluster.Close()
+		dbStats = cluster
+	}
+
+	shutdownTracing, err := tracing.Init(ctx, cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	services := services.NewServices(repos, mail)
+
+	// инвалидация кэша ПВЗ, отправленная другими репликами и pvzctl
+	cacheListenerDone := make(chan struct{})
+	go func() {
+		defer close(cacheListenerDone)
+		repos.CacheNotifier.Listen(ctx, services.PVZCache.Evict, services.PVZCache.Reset)
+	}()
+
+	// init background jobs
+	scheduler := jobs.NewScheduler(repos.JobLocker)
+	jobs.RegisterJobs(scheduler, cfg, services)
+	scheduler.Start(ctx)
+	checker.Register("jobs", scheduler.Check)
 
-	// init db
-	pool := database.ConnectDB(cfg, ctx)
-	_ = pool
 	// init echo
 	e := echo.New()
+	ipExtractor, err := middlewares.ClientIPExtractor(cfg.HTTP.TrustedProxies)
+	if err != nil {
+		logrus.Fatalf("Ошибка настройки доверенных прокси: %v", err)
+	}
+	e.IPExtractor = ipExtractor
 
 	// Register Swagger
 	handlers.RegisterSwagger(e)
 
-	routes.InitRoutes(e, cfg, pool)
+	routes.InitRoutes(e, cfg, services, scheduler, checker, dbStats)
+
+	go func() {
+		if err := e.Start(cfg.HTTP.Addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Fatalf("Ошибка HTTP сервера: %v", err)
+		}
+	}()
+
+	// graceful shutdown: дожидаемся текущих запросов и фоновых задач
+	<-ctx.Done()
+	logrus.Info("Остановка сервера")
+
+	checker.SetShuttingDown()
+	time.Sleep(cfg.HTTP.ShutdownDrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		logrus.Errorf("Ошибка остановки HTTP сервера: %v", err)
+	}
+	scheduler.Wait()
+	// запросы завершены, новых писем не будет: дожидаемся уже начатых
+	services.Background.Wait()
+	<-cacheListenerDone
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logrus.Errorf("Ошибка отправки трассировки: %v", err)
+	}
+}
+
+// initStorage возвращает репозитории и кластер базы, для STORAGE=memory кластер nil
+func initStorage(ctx context.Context, cfg *config.Config, checker *health.Checker) (*repositories.Repos, *database.Cluster) {
+	if cfg.Storage == "memory" {
+		logrus.Warn("STORAGE=memory: данные хранятся в памяти и пропадут после перезапуска")
+		return memory.NewRepos(cfg), nil
+	}
+
+	cluster, err := database.ConnectCluster(ctx, cfg.DB)
+	if errors.Is(err, context.Canceled) {
+		logrus.Info("Остановка сервера до подключения к базе данных")
+		os.Exit(0)
+	}
+	if err != nil {
+		logrus.Fatalf("Ошибка подключения к базе данных: %v", err)
+	}
+	pool := cluster.Primary
 
-	e.Logger.Fatal(e.Start(":8080"))
+	if cfg.DB.AutoMigrate {
+		if err := database.AutoMigrate(ctx, pool); err != nil {
+			logrus.Fatalf("Ошибка применения миграций: %v", err)
+		}
+	}
+
+	migrationsCheck, err := database.MigrationsCheck(pool)
+	if err != nil {
+		logrus.Fatalf("Ошибка инициализации проверки миграций: %v", err)
+	}
+	checker.Register("database", database.PingCheck(pool))
+	checker.Register("migrations", migrationsCheck)
+
+	return repositories.NewRepos(cfg, pool, cluster), cluster
+}
+
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: pvz-service migrate up|down|status")
+		return 2
+	}
+
+	pool, err := database.ConnectDB(ctx, cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer pool.Close()
+
+	if err := database.Migrate(ctx, pool, args[0], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func splitCommand(args []string) (cmd, rest []string) {
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd = append(cmd, args[0])
+		args = args[1:]
+	}
+
+	return cmd, args
+}
+
+func runConfig(cfg *config.Config, args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: pvz-service config print [flags]")
+		return 2
+	}
+
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
 
-	//graseful shutdown
+	return 0
 }
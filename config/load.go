@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// field - лист дерева Config: путь yaml (он же имя флага), переменная окружения и значение
+type field struct {
+	path   string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+func fields(cfg *Config) []field {
+	var out []field
+	collectFields(reflect.ValueOf(cfg).Elem(), "", &out)
+	return out
+}
+
+func collectFields(v reflect.Value, prefix string, out *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		path := prefix + sf.Tag.Get("yaml")
+
+		if sf.Type.Kind() == reflect.Struct {
+			collectFields(v.Field(i), path+".", out)
+			continue
+		}
+
+		*out = append(*out, field{
+			path:   path,
+			env:    sf.Tag.Get("env"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+}
+
+// Load собирает конфигурацию по слоям, каждый следующий переопределяет предыдущий:
+//   - значения по умолчанию (Default);
+//   - YAML файл из флага -config или переменной CONFIG_FILE;
+//   - переменные окружения, для секретов также <ENV>_FILE с путем к файлу;
+//   - флаги командной строки вида -http.addr=:9090.
+//
+// Результат проверяется Validate.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("pvz-service", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "путь к YAML файлу конфигурации (CONFIG_FILE)")
+
+	flags := map[string]string{}
+	for _, f := range fields(&cfg) {
+		path := f.path
+		fs.Func(path, "переопределяет "+f.env, func(s string) error {
+			flags[path] = s
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields(&cfg) {
+		s, ok := flags[f.path]
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, s); err != nil {
+			return nil, fmt.Errorf("flag -%s: %w", f.path, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer file.Close()
+
+	dec := yaml.NewDecoder(file)
+	// опечатка в ключе не должна молча оставлять значение по умолчанию
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func loadEnv(cfg *Config) error {
+	for _, f := range fields(cfg) {
+		s, ok := os.LookupEnv(f.env)
+
+		if f.secret {
+			if path := os.Getenv(f.env + "_FILE"); path != "" {
+				if ok {
+					return fmt.Errorf("both %s and %s_FILE are set", f.env, f.env)
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("%s_FILE: %w", f.env, err)
+				}
+				s, ok = strings.TrimRight(string(data), "\r\n"), true
+			}
+		}
+
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, s); err != nil {
+			return fmt.Errorf("%s: %w", f.env, err)
+		}
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "xxxxx"
+
+// Redacted возвращает копию конфигурации со скрытыми секретами.
+// В строках подключения скрывается только пароль, чтобы был виден адрес.
+func (c Config) Redacted() Config {
+	for _, f := range fields(&c) {
+		if !f.secret {
+			continue
+		}
+
+		if f.value.Kind() == reflect.Slice {
+			// копия, чтобы не изменить срез исходной конфигурации
+			items := make([]string, f.value.Len())
+			for i := range items {
+				items[i] = redactValue(f.value.Index(i).String())
+			}
+			f.value.Set(reflect.ValueOf(items))
+			continue
+		}
+		f.value.SetString(redactValue(f.value.String()))
+	}
+
+	return c
+}
+
+func redactValue(s string) string {
+	if s == "" {
+		return s
+	}
+
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+
+	return redacted
+}
+
+// Print пишет итоговую конфигурацию в YAML со скрытыми секретами
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
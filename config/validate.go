@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// minProdSecretLength - минимальная длина ключа подписи JWT в MODE=prod (256 бит для HS256)
+const minProdSecretLength = 32
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки сразу.
+// В MODE=prod дополнительно запрещены небезопасные значения по умолчанию.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Mode == "dev" || c.Mode == "prod", "MODE must be dev or prod, got %q", c.Mode)
+	check(c.Storage == "postgres" || c.Storage == "memory", "STORAGE must be postgres or memory, got %q", c.Storage)
+
+	check(c.HTTP.Addr != "", "HTTP_ADDR must not be empty")
+	check(c.HTTP.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive, got %s", c.HTTP.ShutdownTimeout)
+	check(c.HTTP.ShutdownDrainDelay >= 0, "SHUTDOWN_DRAIN_DELAY must not be negative, got %s", c.HTTP.ShutdownDrainDelay)
+	for _, cidr := range c.HTTP.TrustedProxies {
+		_, _, err := net.ParseCIDR(cidr)
+		check(err == nil, "TRUSTED_PROXIES must contain CIDR ranges, got %q", cidr)
+	}
+
+	if c.Storage == "postgres" {
+		check(c.DB.URL != "", "DATABASE_URL must not be empty")
+	}
+	check(c.DB.MaxConns >= 0 && c.DB.MinConns >= 0, "DB_MAX_CONNS and DB_MIN_CONNS must not be negative")
+	check(c.DB.MaxConns == 0 || c.DB.MinConns <= c.DB.MaxConns,
+		"DB_MIN_CONNS (%d) must not exceed DB_MAX_CONNS (%d)", c.DB.MinConns, c.DB.MaxConns)
+	check(c.DB.StatementTimeout >= 0, "DB_STATEMENT_TIMEOUT must not be negative, got %s", c.DB.StatementTimeout)
+	check(c.DB.ConnectAttempts > 0, "DB_CONNECT_ATTEMPTS must be positive, got %d", c.DB.ConnectAttempts)
+	check(c.DB.ConnectBackoff > 0 && c.DB.ConnectBackoff <= c.DB.ConnectBackoffMax,
+		"DB_CONNECT_BACKOFF must be positive and not exceed DB_CONNECT_BACKOFF_MAX")
+	if len(c.DB.ReplicaURLs) > 0 {
+		check(c.DB.ReplicaMaxLag > 0, "DB_REPLICA_MAX_LAG must be positive, got %s", c.DB.ReplicaMaxLag)
+		check(c.DB.ReplicaCheckInterval > 0, "DB_REPLICA_CHECK_INTERVAL must be positive, got %s", c.DB.ReplicaCheckInterval)
+	}
+
+	check(c.Auth.Secret != "", "SECRET must not be empty")
+	check(c.Auth.TokenTTL > 0, "TOKEN_TTL must be positive, got %s", c.Auth.TokenTTL)
+	check(c.Auth.PickupCodeSecret != "", "PICKUP_CODE_SECRET must not be empty")
+	check(c.Auth.LoginMaxAttempts > 0, "LOGIN_MAX_ATTEMPTS must be positive, got %d", c.Auth.LoginMaxAttempts)
+	check(c.Auth.LockoutBase > 0 && c.Auth.LockoutBase <= c.Auth.LockoutMax,
+		"LOGIN_LOCKOUT_BASE must be positive and not exceed LOGIN_LOCKOUT_MAX")
+
+	_, err := logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL: %v", err)
+	switch c.Log.Output {
+	case "stdout":
+	case "file", "both":
+		check(c.Log.File != "", "LOG_FILE is required for LOG_OUTPUT=%s", c.Log.Output)
+	default:
+		check(false, "LOG_OUTPUT must be stdout, file or both, got %q", c.Log.Output)
+	}
+	check(c.Log.MaxSizeMB > 0, "LOG_FILE_MAX_SIZE_MB must be positive, got %d", c.Log.MaxSizeMB)
+	check(c.Log.MaxBackups >= 0 && c.Log.MaxAgeDays >= 0, "LOG_FILE_MAX_BACKUPS and LOG_FILE_MAX_AGE_DAYS must not be negative")
+
+	check(!c.RateLimit.Enabled || (c.RateLimit.AuthRate > 0 && c.RateLimit.APIRate > 0 &&
+		c.RateLimit.AuthBurst > 0 && c.RateLimit.APIBurst > 0),
+		"RATE_LIMIT_* rates and bursts must be positive")
+
+	check(c.Jobs.StaleReceptionInterval >= 0, "STALE_RECEPTION_INTERVAL must not be negative, got %s", c.Jobs.StaleReceptionInterval)
+	// настройки отключенной задачи не используются
+	if c.Jobs.StaleReceptionInterval > 0 {
+		check(c.Jobs.StaleReceptionAction == "close" || c.Jobs.StaleReceptionAction == "flag",
+			"STALE_RECEPTION_ACTION must be close or flag, got %q", c.Jobs.StaleReceptionAction)
+		check(c.Jobs.StaleReceptionAfter > 0, "STALE_RECEPTION_AFTER must be positive, got %s", c.Jobs.StaleReceptionAfter)
+	}
+
+	check(c.Cache.Size >= 0, "CACHE_SIZE must not be negative, got %d", c.Cache.Size)
+	check(c.Cache.Size == 0 || c.Cache.TTL > 0, "CACHE_TTL must be positive, got %s", c.Cache.TTL)
+
+	check(c.Mail.SendTimeout > 0, "MAILER_SEND_TIMEOUT must be positive, got %s", c.Mail.SendTimeout)
+	switch c.Mail.Driver {
+	case "log", "file":
+	case "smtp":
+		check(c.Mail.SMTPHost != "", "SMTP_HOST is required for MAILER=smtp")
+	default:
+		check(false, "MAILER must be smtp, file or log, got %q", c.Mail.Driver)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		check(false, "TRACING_EXPORTER must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	if c.Mode == "prod" {
+		errs = append(errs, c.validateProd()...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) validateProd() []error {
+	var errs []error
+
+	if c.Auth.Secret == defaultSecret {
+		errs = append(errs, errors.New("MODE=prod: SECRET must be changed from the default value"))
+	} else if len(c.Auth.Secret) < minProdSecretLength {
+		errs = append(errs, fmt.Errorf("MODE=prod: SECRET must be at least %d bytes long", minProdSecretLength))
+	}
+
+	if c.Auth.PickupCodeSecret == defaultPickupCodeSecret {
+		errs = append(errs, errors.New("MODE=prod: PICKUP_CODE_SECRET must be changed from the default value"))
+	} else if len(c.Auth.PickupCodeSecret) < minProdSecretLength {
+		errs = append(errs, fmt.Errorf("MODE=prod: PICKUP_CODE_SECRET must be at least %d bytes long", minProdSecretLength))
+	}
+
+	if c.Storage == "memory" {
+		errs = append(errs, errors.New("MODE=prod: STORAGE=memory loses all data on restart"))
+	}
+
+	if c.Auth.DummyLogin {
+		errs = append(errs, errors.New("MODE=prod: DUMMY_LOGIN issues tokens without authentication"))
+	}
+
+	if c.Storage == "postgres" && usesDefaultDatabasePassword(append([]string{c.DB.URL}, c.DB.ReplicaURLs...)...) {
+		errs = append(errs, errors.New("MODE=prod: DATABASE_URL uses the default development password"))
+	}
+
+	// log драйвер пишет в лог ссылки сброса пароля вместе с токенами
+	if c.Mail.Driver == "log" {
+		errs = append(errs, errors.New("MODE=prod: MAILER=log writes password reset links to the log"))
+	}
+
+	return errs
+}
+
+func usesDefaultDatabasePassword(dsns ...string) bool {
+	def, _ := url.Parse(defaultDatabaseURL)
+	defPassword, _ := def.User.Password()
+
+	for _, dsn := range dsns {
+		u, err := url.Parse(dsn)
+		if err != nil || u.User == nil {
+			continue
+		}
+		if password, _ := u.User.Password(); password == defPassword {
+			return true
+		}
+	}
+
+	return false
+}
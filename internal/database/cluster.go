@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"pvz-service/config"
+	"pvz-service/internal/models"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// primaryLSNQuery - текущая позиция WAL на primary, с которой сравниваются реплики
+const primaryLSNQuery = `SELECT pg_current_wal_lsn()::text`
+
+// replicaLagQuery - отставание реплики в секундах. Реплика актуальна, если
+// применила WAL до позиции primary ($1), даже если на primary давно не было
+// записей. Сравнение с primary, а не с полученными репликой WAL, не дает
+// считать актуальной реплику с отключенным WAL receiver: она перестает получать
+// WAL, и отставание растет от последней примененной транзакции.
+// На primary (не в режиме восстановления) функции возвращают NULL и отставание 0.
+const replicaLagQuery = `
+SELECT CASE
+	WHEN pg_last_wal_replay_lsn() >= $1::pg_lsn THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+type replica struct {
+	name    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// Cluster - пул primary и необязательные пулы реплик. Запросы на чтение
+// распределяются по здоровым репликам, при их отсутствии идут в primary.
+type Cluster struct {
+	Primary *pgxpool.Pool
+
+	replicas []*replica
+	next     atomic.Uint64
+	maxLag   time.Duration
+
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// ConnectCluster подключается к primary (см. ConnectDB) и создает пулы реплик.
+// Недоступные реплики не блокируют запуск, чтение идет в primary, пока они не поднимутся.
+func ConnectCluster(ctx context.Context, cfg config.DBConfig) (*Cluster, error) {
+	primary, err := ConnectDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cluster{Primary: primary, maxLag: cfg.ReplicaMaxLag}
+
+	for i, dsn := range cfg.ReplicaURLs {
+		replicaCfg := cfg
+		replicaCfg.URL = dsn
+
+		poolConfig, err := newPoolConfig(replicaCfg)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("реплика %d: %w", i, err)
+		}
+
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("реплика %d: %w", i, err)
+		}
+
+		r := &replica{name: "replica-" + strconv.Itoa(i), pool: pool}
+		// первая проверка выполняется сразу ниже и залогирует недоступную реплику
+		r.healthy.Store(true)
+		c.replicas = append(c.replicas, r)
+	}
+
+	if len(c.replicas) > 0 {
+		monitorCtx, stop := context.WithCancel(context.Background())
+		c.stop = stop
+		c.checkReplicas(monitorCtx)
+
+		c.wg.Add(1)
+		go c.monitor(monitorCtx, cfg.ReplicaCheckInterval)
+	}
+
+	return c, nil
+}
+
+// Reader возвращает пул для запроса только на чтение
+func (c *Cluster) Reader() *pgxpool.Pool {
+	n := len(c.replicas)
+	start := int(c.next.Add(1) % uint64(max(n, 1)))
+
+	for i := 0; i < n; i++ {
+		r := c.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r.pool
+		}
+	}
+
+	return c.Primary
+}
+
+func (c *Cluster) monitor(ctx context.Context, interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkReplicas(ctx)
+		}
+	}
+}
+
+func (c *Cluster) checkReplicas(ctx context.Context) {
+	primaryLSN, lsnErr := c.primaryLSN(ctx)
+
+	for _, r := range c.replicas {
+		err := lsnErr
+		if err == nil {
+			err = c.checkReplica(ctx, r, primaryLSN)
+		}
+		healthy := err == nil
+
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				logrus.Infof("Реплика %s доступна для чтения", r.name)
+			} else {
+				logrus.Warnf("Реплика %s исключена из чтения: %v", r.name, err)
+			}
+		}
+	}
+}
+
+// primaryLSN читается до опроса реплик: без нее нельзя отличить актуальную
+// реплику от отключенной, поэтому ошибка исключает из чтения все реплики
+func (c *Cluster) primaryLSN(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var lsn string
+	if err := c.Primary.QueryRow(ctx, primaryLSNQuery).Scan(&lsn); err != nil {
+		return "", fmt.Errorf("позиция WAL primary: %w", err)
+	}
+
+	return lsn, nil
+}
+
+func (c *Cluster) checkReplica(ctx context.Context, r *replica, primaryLSN string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var lagSeconds float64
+	if err := r.pool.QueryRow(ctx, replicaLagQuery, primaryLSN).Scan(&lagSeconds); err != nil {
+		return err
+	}
+
+	if lag := time.Duration(lagSeconds * float64(time.Second)); lag > c.maxLag {
+		return fmt.Errorf("отставание %s больше %s", lag.Round(time.Millisecond), c.maxLag)
+	}
+
+	return nil
+}
+
+// Pools возвращает все пулы кластера по именам для статистики
+func (c *Cluster) Pools() Pools {
+	pools := Pools{"primary": c.Primary}
+	for _, r := range c.replicas {
+		pools[r.name] = r.pool
+	}
+
+	return pools
+}
+
+func (c *Cluster) PoolStats() map[string]models.DBPoolStats {
+	return c.Pools().PoolStats()
+}
+
+// Close останавливает проверку реплик и закрывает все пулы
+func (c *Cluster) Close() {
+	if c.stop != nil {
+		c.stop()
+	}
+	c.wg.Wait()
+
+	c.Pools().Close()
+}
@@ -2,38 +2,107 @@ package database
 
 import (
 	"context"
+	"fmt"
+	"math/rand/v2"
 	"pvz-service/config"
+	"pvz-service/internal/tracing"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
 
-func ConnectDB(cfg *config.Config, ctx context.Context) *pgxpool.Pool {
-	var pool *pgxpool.Pool
-	var err error
-
-	maxRetries := 5
-	retryInterval := 5 * time.Second
-
-	for i := 1; i <= maxRetries; i++ {
-		pool, err = pgxpool.New(ctx, cfg.DATABASE_URL)
+// ConnectDB создает пул и дожидается ответа базы. Неудачные попытки повторяются
+// с экспоненциальной задержкой, отмена ctx прерывает ожидание.
+func ConnectDB(ctx context.Context, cfg config.DBConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := newPoolConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
 
+	for attempt := 1; ; attempt++ {
+		pool, err := connect(ctx, poolConfig, cfg.ConnectTimeout)
 		if err == nil {
-			err = pool.Ping(ctx)
-			if err == nil {
-				logrus.Infof("Успешно подключено к базе данных на попытке %d", i)
-				return pool
-			}
+			logrus.Infof("Успешно подключено к базе данных на попытке %d", attempt)
+			return pool, nil
 		}
 
-		logrus.Errorf("ошибка подключения к базе данных (попытка %d): %s", i, err.Error())
+		if attempt >= cfg.ConnectAttempts {
+			return nil, fmt.Errorf("не удалось подключиться к базе данных за %d попыток: %w", attempt, err)
+		}
 
-		if i < maxRetries {
-			time.Sleep(retryInterval)
+		delay := backoff(attempt, cfg.ConnectBackoff, cfg.ConnectBackoffMax)
+		logrus.Errorf("ошибка подключения к базе данных (попытка %d), повтор через %s: %v", attempt, delay.Round(time.Millisecond), err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
 		}
 	}
+}
+
+func connect(ctx context.Context, poolConfig *pgxpool.Config, timeout time.Duration) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+func newPoolConfig(cfg config.DBConfig) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный DATABASE_URL: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = tracing.NewQueryTracer(cfg.SlowQueryThreshold)
+
+	// нулевые значения оставляют параметры из DATABASE_URL или значения pgxpool по умолчанию
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	if cfg.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	return poolConfig, nil
+}
+
+// backoff - задержка перед следующей попыткой: base * 2^(attempt-1), не больше limit,
+// случайно уменьшенная до половины, чтобы реплики не подключались одновременно
+func backoff(attempt int, base, limit time.Duration) time.Duration {
+	delay := limit
+	if shift := attempt - 1; shift < 30 && base<<shift < limit {
+		delay = base << shift
+	}
 
-	logrus.Fatal("Не удалось подключиться к базе данных после нескольких попыток.")
-	return nil
+	return delay/2 + rand.N(delay/2+1)
 }
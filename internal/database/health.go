@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"pvz-service/internal/health"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PingCheck проверяет, что пул может выполнить запрос к базе
+func PingCheck(pool *pgxpool.Pool) health.Check {
+	return func(ctx context.Context) error {
+		return pool.Ping(ctx)
+	}
+}
+
+// MigrationsCheck не готов, пока в базе не применены все миграции,
+// встроенные в бинарник. Миграции не откатываются сами собой, поэтому
+// после первого успешного ответа база больше не опрашивается.
+func MigrationsCheck(pool *pgxpool.Pool) (health.Check, error) {
+	provider, err := NewMigrator(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied atomic.Bool
+	return func(ctx context.Context) error {
+		if applied.Load() {
+			return nil
+		}
+
+		pending, err := provider.HasPending(ctx)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return errors.New("database has pending migrations")
+		}
+
+		applied.Store(true)
+		return nil
+	}, nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"pvz-service/migrations"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"github.com/sirupsen/logrus"
+)
+
+// NewMigrator создает goose провайдер поверх пула с миграциями, встроенными в бинарник.
+// Версии хранятся в той же таблице goose_db_version, что и у goose CLI. Session lock
+// не дает нескольким репликам с AUTO_MIGRATE применять миграции одновременно.
+func NewMigrator(pool *pgxpool.Pool) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+
+	return goose.NewProvider(goose.DialectPostgres, stdlib.OpenDBFromPool(pool), migrations.FS,
+		goose.WithSessionLocker(locker))
+}
+
+// migrationPool открывает для миграций отдельный пул без statement_timeout:
+// DB_STATEMENT_TIMEOUT рассчитан на запросы API и прервал бы долгую миграцию
+// или ожидание session lock, пока миграции применяет другая реплика.
+func migrationPool(ctx context.Context, pool *pgxpool.Pool) (*pgxpool.Pool, error) {
+	poolConfig := pool.Config()
+	poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = "0"
+	poolConfig.MinConns = 0
+
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
+
+// Migrate выполняет команду миграций: up, down или status
+func Migrate(ctx context.Context, pool *pgxpool.Pool, command string, out io.Writer) error {
+	migrations, err := migrationPool(ctx, pool)
+	if err != nil {
+		return err
+	}
+	defer migrations.Close()
+
+	provider, err := NewMigrator(migrations)
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+
+	switch command {
+	case "up":
+		results, err := provider.Up(ctx)
+		for _, r := range results {
+			fmt.Fprintln(out, r)
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+	case "down":
+		result, err := provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			fmt.Fprintln(out, "no migrations to roll back")
+			return nil
+		}
+		if result != nil {
+			fmt.Fprintln(out, result)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			appliedAt := "-"
+			if !s.AppliedAt.IsZero() {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%-8s %-25s %s\n", s.State, appliedAt, filepath.Base(s.Source.Path))
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+
+	return nil
+}
+
+// AutoMigrate применяет все недостающие миграции при старте сервера
+func AutoMigrate(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := migrationPool(ctx, pool)
+	if err != nil {
+		return err
+	}
+	defer migrations.Close()
+
+	provider, err := NewMigrator(migrations)
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+
+	results, err := provider.Up(ctx)
+	for _, r := range results {
+		logrus.Infof("миграция применена: %s", r)
+	}
+
+	return err
+}
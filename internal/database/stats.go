@@ -0,0 +1,44 @@
+package database
+
+import (
+	"pvz-service/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pools - именованные пулы соединений, источник статистики для мониторинга
+type Pools map[string]*pgxpool.Pool
+
+func (p Pools) PoolStats() map[string]models.DBPoolStats {
+	stats := make(map[string]models.DBPoolStats, len(p))
+	for name, pool := range p {
+		stats[name] = poolStats(pool)
+	}
+
+	return stats
+}
+
+func poolStats(pool *pgxpool.Pool) models.DBPoolStats {
+	s := pool.Stat()
+
+	return models.DBPoolStats{
+		MaxConns:                s.MaxConns(),
+		TotalConns:              s.TotalConns(),
+		AcquiredConns:           s.AcquiredConns(),
+		IdleConns:               s.IdleConns(),
+		ConstructingConns:       s.ConstructingConns(),
+		AcquireCount:            s.AcquireCount(),
+		AcquireDurationMs:       float64(s.AcquireDuration().Microseconds()) / 1000,
+		EmptyAcquireCount:       s.EmptyAcquireCount(),
+		CanceledAcquireCount:    s.CanceledAcquireCount(),
+		NewConnsCount:           s.NewConnsCount(),
+		MaxLifetimeDestroyCount: s.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     s.MaxIdleDestroyCount(),
+	}
+}
+
+func (p Pools) Close() {
+	for _, pool := range p {
+		pool.Close()
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz-service/internal/models"
+	"pvz-service/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AuditHandler struct {
+	services *services.Services
+}
+
+func NewAuditHandler(services *services.Services) *AuditHandler {
+	return &AuditHandler{services: services}
+}
+
+// @Summary Журнал аудита
+// @Description Список изменяющих действий пользователей, новые записи первыми (только для модераторов)
+// @Tags audit
+// @Security bearerAuth
+// @Produce json
+// @Param actor_id query string false "ID пользователя"
+// @Param action query string false "Действие, например product.delete_last"
+// @Param target_type query string false "Тип объекта: pvz, reception, product, user"
+// @Param target_id query string false "ID объекта"
+// @Param request_id query string false "ID запроса"
+// @Param from query string false "Начало периода (2006-01-02 или RFC 3339)"
+// @Param to query string false "Конец периода (2006-01-02 или RFC 3339)"
+// @Param page query int false "Номер страницы"
+// @Param limit query int false "Размер страницы"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /audit [get]
+func (h *AuditHandler) List(c echo.Context) error {
+	page := 1
+	if p, err := strconv.Atoi(c.QueryParam("page")); err == nil && p > 0 {
+		page = p
+	}
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	filter := models.AuditFilter{
+		ActorID:    c.QueryParam("actor_id"),
+		Action:     c.QueryParam("action"),
+		TargetType: c.QueryParam("target_type"),
+		TargetID:   c.QueryParam("target_id"),
+		RequestID:  c.QueryParam("request_id"),
+		Limit:      limit,
+		Offset:     (page - 1) * limit,
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(c.QueryParam("from"), false); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid from"})
+	}
+	if filter.To, err = parseAuditTime(c.QueryParam("to"), true); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid to"})
+	}
+
+	entries, err := h.services.AuditService.List(c.Request().Context(), filter)
+	if err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not load audit log"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"data":  entries,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// @Summary Проверка целостности журнала аудита
+// @Description Пересчитывает цепочку хэшей и возвращает id первой измененной записи (только для модераторов)
+// @Tags audit
+// @Security bearerAuth
+// @Produce json
+// @Success 200 {object} models.AuditVerifyResult
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} models.AuditVerifyResult
+// @Router /audit/verify [get]
+func (h *AuditHandler) Verify(c echo.Context) error {
+	result, err := h.services.AuditService.Verify(c.Request().Context())
+	if err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not verify audit log"})
+	}
+
+	if !result.Valid {
+		return c.JSON(http.StatusConflict, result)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// parseAuditTime принимает дату или RFC 3339. Для конца периода дата без
+// времени означает конец дня.
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz-service/internal/models"
+	"pvz-service/internal/services"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditService) Verify(ctx context.Context) (models.AuditVerifyResult, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(models.AuditVerifyResult), args.Error(1)
+}
+
+func (m *MockAuditService) RecordLogLevelChange(ctx context.Context, before, after string) error {
+	args := m.Called(ctx, before, after)
+	return args.Error(0)
+}
+
+func setupAuditEcho() (*echo.Echo, *MockAuditService, *AuditHandler) {
+	e := echo.New()
+	mockService := new(MockAuditService)
+	s := &services.Services{AuditService: mockService}
+	handler := NewAuditHandler(s)
+	return e, mockService, handler
+}
+
+func TestAuditHandler_List(t *testing.T) {
+	e, mockService, handler := setupAuditEcho()
+
+	t.Run("filters are passed to service", func(t *testing.T) {
+		expected := models.AuditFilter{
+			ActorID:    "u1",
+			Action:     models.AuditProductDeleteLast,
+			TargetType: "product",
+			From:       time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+			To:         time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC).Add(24*time.Hour - time.Nanosecond),
+			Limit:      20,
+			Offset:     20,
+		}
+		mockService.On("List", mock.Anything, expected).
+			Return([]models.AuditEntry{{ID: 1, Action: models.AuditProductDeleteLast}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet,
+			"/audit?actor_id=u1&action=product.delete_last&target_type=product&from=2025-04-01&to=2025-04-02&page=2&limit=20", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.List(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response struct {
+			Data []models.AuditEntry `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Len(t, response.Data, 1)
+	})
+
+	t.Run("invalid from", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/audit?from=yesterday", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.List(c)
+		var httpErr *echo.HTTPError
+		assert.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	})
+}
+
+func TestAuditHandler_Verify(t *testing.T) {
+	e, mockService, handler := setupAuditEcho()
+
+	t.Run("broken chain", func(t *testing.T) {
+		brokenAt := int64(42)
+		mockService.On("Verify", mock.Anything).
+			Return(models.AuditVerifyResult{Valid: false, Checked: 42, BrokenAt: &brokenAt}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/audit/verify", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Verify(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
@@ -1,13 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/jwt"
 	"pvz-service/internal/services"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -22,32 +23,40 @@ func NewAuthHandler(services *services.Services) *AuthHandler {
 type registerRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
-	Role     string `json:"role"`
+	// Role можно не указывать: без приглашения создается только customer
+	Role string `json:"role"`
 }
 
 // @Summary Регистрация пользователя
-// @Description Создание нового пользователя с email, паролем и ролью
+// @Description Создание пользователя с ролью customer. На email отправляется ссылка для подтверждения. Сотрудники и модераторы регистрируются по приглашению через /register/invite
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body registerRequest true "User registration data"
 // @Success 201 {object} models.User
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /register [post]
 func (h *AuthHandler) Register(c echo.Context) error {
 	var req registerRequest
 	if err := c.Bind(&req); err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid body"})
 	}
 
-	if req.Role != "client" && req.Role != "moderator" {
+	if req.Role == "" {
+		req.Role = models.RoleCustomer
+	}
+	if !models.ValidRole(req.Role) {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid role"})
 	}
+	if req.Role != models.RoleCustomer {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "this role requires an invitation"})
+	}
 
 	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "encryption error"})
 	}
 
@@ -57,10 +66,10 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		Role:     req.Role,
 	}
 
-	logrus.Debug("creating user", user)
+	requestLogger(c).WithField("email", user.Email).Debug("creating user")
 	err = h.services.UserService.CreateUser(c.Request().Context(), user)
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		// TODO - добавить разные варианты ошибок
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "can not create user"})
 	}
@@ -68,6 +77,48 @@ func (h *AuthHandler) Register(c echo.Context) error {
 	return c.JSON(http.StatusCreated, echo.Map{"email": user.Email, "role": user.Role})
 }
 
+type registerInviteRequest struct {
+	Token    string `json:"token"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// @Summary Регистрация по приглашению
+// @Description Создание сотрудника или модератора по одноразовому приглашению. Email и роль берутся из приглашения
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body registerInviteRequest true "Invitation token and password"
+// @Success 201 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /register/invite [post]
+func (h *AuthHandler) RegisterInvite(c echo.Context) error {
+	var req registerInviteRequest
+	if err := c.Bind(&req); err != nil || req.Token == "" || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		requestLogger(c).Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "encryption error"})
+	}
+
+	user, err := h.services.InvitationService.RedeemInvitation(c.Request().Context(), req.Token, req.Email, string(hashed))
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidToken):
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invitation is invalid or expired"})
+	case errors.Is(err, apperrors.ErrAlreadyExists):
+		return c.JSON(http.StatusConflict, echo.Map{"message": "user already exists"})
+	case err != nil:
+		requestLogger(c).Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "can not create user"})
+	}
+
+	return c.JSON(http.StatusCreated, user)
+}
+
 type loginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -80,7 +131,9 @@ type loginRequest struct {
 // @Produce json
 // @Param request body loginRequest true "User login data"
 // @Success 200 {object} string "Token"
-// @Failure 401 {object} map[string]string
+// @Failure 401 {object} map[string]string "Неверные данные или учетная запись временно заблокирована"
+// @Failure 403 {object} map[string]string "Учетная запись отключена или email не подтвержден"
+// @Failure 429 {object} map[string]string
 // @Router /login [post]
 func (h *AuthHandler) Login(c echo.Context) error {
 	var req loginRequest
@@ -88,24 +141,153 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid body"})
 	}
 
-	user, err := h.services.UserService.GetUserByEmail(c.Request().Context(), req.Email)
-	if err != nil {
+	user, err := h.services.UserService.Authenticate(c.Request().Context(), req.Email, req.Password)
+	// блокировка неотличима от неверного пароля, иначе по ответу видно, что адрес зарегистрирован
+	if errors.Is(err, apperrors.ErrAccountLocked) {
+		requestLogger(c).Infof("вход в заблокированную учетную запись %s до %s", user.ID, user.LockedUntil)
 		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "invalid credentials"})
 	}
-
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+	if errors.Is(err, apperrors.ErrInvalidCredentials) {
 		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "invalid credentials"})
 	}
+	if errors.Is(err, apperrors.ErrAccountDeactivated) {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "account is deactivated"})
+	}
+	if errors.Is(err, apperrors.ErrEmailNotVerified) {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "email is not verified"})
+	}
+	if err != nil {
+		requestLogger(c).Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "could not authenticate"})
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": user.Email,
-		"role":  user.Role,
-	})
-
-	signed, err := token.SignedString([]byte(h.services.Cfg.SECRET))
+	signed, err := jwt.GenerateUserToken(user, h.services.Cfg)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "token error"})
 	}
 
 	return c.JSON(http.StatusOK, echo.Map{"token": signed})
 }
+
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+// @Summary Запрос сброса пароля
+// @Description Отправляет на email одноразовую ссылку для смены пароля. Ответ не зависит от того, зарегистрирован ли адрес
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body emailRequest true "Email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c echo.Context) error {
+	var req emailRequest
+	if err := c.Bind(&req); err != nil || req.Email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	if err := h.services.UserService.RequestPasswordReset(c.Request().Context(), req.Email); err != nil {
+		requestLogger(c).Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "could not request password reset"})
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{"message": "if the email is registered, a reset link has been sent"})
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// @Summary Сброс пароля
+// @Description Устанавливает новый пароль по ссылке из письма. Ссылка одноразовая
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body resetPasswordRequest true "Token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c echo.Context) error {
+	var req resetPasswordRequest
+	if err := c.Bind(&req); err != nil || req.Token == "" || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		requestLogger(c).Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "encryption error"})
+	}
+
+	err = h.services.UserService.ResetPassword(c.Request().Context(), req.Token, string(hashed))
+	if errors.Is(err, apperrors.ErrInvalidToken) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "reset link is invalid or expired"})
+	}
+	if err != nil {
+		requestLogger(c).Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "could not reset password"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "password changed"})
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// @Summary Подтверждение email
+// @Description Подтверждает email по ссылке из письма
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body verifyEmailRequest true "Token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/email/verify [post]
+func (h *AuthHandler) VerifyEmail(c echo.Context) error {
+	var req verifyEmailRequest
+	if err := c.Bind(&req); err != nil || req.Token == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	err := h.services.UserService.VerifyEmail(c.Request().Context(), req.Token)
+	if errors.Is(err, apperrors.ErrInvalidToken) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "verification link is invalid or expired"})
+	}
+	if err != nil {
+		requestLogger(c).Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "could not verify email"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "email verified"})
+}
+
+// @Summary Повторная отправка подтверждения
+// @Description Отправляет новую ссылку для подтверждения email, прежние ссылки перестают работать
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body emailRequest true "Email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/email/resend [post]
+func (h *AuthHandler) ResendVerification(c echo.Context) error {
+	var req emailRequest
+	if err := c.Bind(&req); err != nil || req.Email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	if err := h.services.UserService.ResendVerification(c.Request().Context(), req.Email); err != nil {
+		requestLogger(c).Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "could not send verification email"})
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{"message": "if the email awaits verification, a new link has been sent"})
+}
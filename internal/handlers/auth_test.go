@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"pvz-service/config"
 	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
 	"pvz-service/internal/services"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -29,11 +32,74 @@ func (m *MockUserService) GetUserByEmail(ctx context.Context, email string) (mod
 	return args.Get(0).(models.User), args.Error(1)
 }
 
+func (m *MockUserService) Authenticate(ctx context.Context, email, password string) (models.User, error) {
+	args := m.Called(ctx, email, password)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockUserService) UnlockUser(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) RequestPasswordReset(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, token, passwordHash string) error {
+	args := m.Called(ctx, token, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ResendVerification(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserService) GetUser(ctx context.Context, id string) (models.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockUserService) ChangeRole(ctx context.Context, id, role string) (models.User, error) {
+	args := m.Called(ctx, id, role)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockUserService) DeactivateUser(ctx context.Context, id string) (models.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockUserService) ReactivateUser(ctx context.Context, id string) (models.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockUserService) UpdateProfile(ctx context.Context, id string, update models.ProfileUpdate) (models.User, error) {
+	args := m.Called(ctx, id, update)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
 func setupAuthEcho() (*echo.Echo, *MockUserService, *AuthHandler) {
 	e := echo.New()
 	mockService := new(MockUserService)
+	cfg := config.Default()
+	cfg.Auth.Secret = "test_secret"
 	s := &services.Services{
-		UserService: &services.UserService{},
+		UserService: mockService,
+		Cfg:         &cfg,
 	}
 	handler := NewAuthHandler(s)
 	return e, mockService, handler
@@ -46,12 +112,12 @@ func TestAuthHandler_Register(t *testing.T) {
 		reqBody := map[string]string{
 			"email":    "test@example.com",
 			"password": "password123",
-			"role":     "client",
 		}
 		reqJSON, _ := json.Marshal(reqBody)
 
-		mockService.On("CreateUser", mock.Anything, mock.Anything).
-			Return(nil)
+		mockService.On("CreateUser", mock.Anything, mock.MatchedBy(func(u models.User) bool {
+			return u.Role == models.RoleCustomer
+		})).Return(nil).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(string(reqJSON)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -66,7 +132,26 @@ func TestAuthHandler_Register(t *testing.T) {
 		err = json.Unmarshal(rec.Body.Bytes(), &response)
 		assert.NoError(t, err)
 		assert.Equal(t, "test@example.com", response["email"])
-		assert.Equal(t, "client", response["role"])
+		assert.Equal(t, models.RoleCustomer, response["role"])
+	})
+
+	t.Run("privileged role requires invitation", func(t *testing.T) {
+		for _, role := range []string{models.RoleEmployee, models.RoleModerator} {
+			reqJSON, _ := json.Marshal(map[string]string{
+				"email":    "test@example.com",
+				"password": "password123",
+				"role":     role,
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(string(reqJSON)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.Register(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusForbidden, rec.Code, role)
+		}
 	})
 
 	t.Run("invalid request body", func(t *testing.T) {
@@ -102,7 +187,7 @@ func TestAuthHandler_Register(t *testing.T) {
 		reqBody := map[string]string{
 			"email":    "test@example.com",
 			"password": "password123",
-			"role":     "client",
+			"role":     "customer",
 		}
 		reqJSON, _ := json.Marshal(reqBody)
 
@@ -120,6 +205,70 @@ func TestAuthHandler_Register(t *testing.T) {
 	})
 }
 
+type MockInvitationService struct {
+	mock.Mock
+}
+
+func (m *MockInvitationService) CreateInvitation(ctx context.Context, email, role string, pvzID *string) (models.CreatedInvitation, error) {
+	args := m.Called(ctx, email, role, pvzID)
+	return args.Get(0).(models.CreatedInvitation), args.Error(1)
+}
+
+func (m *MockInvitationService) ListInvitations(ctx context.Context, limit, offset int) ([]models.Invitation, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]models.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationService) RevokeInvitation(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockInvitationService) RedeemInvitation(ctx context.Context, token, email, passwordHash string) (models.User, error) {
+	args := m.Called(ctx, token, email, passwordHash)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func TestAuthHandler_RegisterInvite(t *testing.T) {
+	e := echo.New()
+	mockService := new(MockInvitationService)
+	handler := NewAuthHandler(&services.Services{InvitationService: mockService})
+
+	tests := []struct {
+		name         string
+		body         string
+		callsService bool
+		user         models.User
+		err          error
+		expCode      int
+	}{
+		{"redeemed", `{"token":"t1","password":"secret"}`, true, models.User{ID: "u1", Email: "e@example.com", Role: models.RoleEmployee}, nil, http.StatusCreated},
+		{"invalid token", `{"token":"t1","password":"secret"}`, true, models.User{}, apperrors.ErrInvalidToken, http.StatusBadRequest},
+		{"user exists", `{"token":"t1","password":"secret"}`, true, models.User{}, apperrors.ErrAlreadyExists, http.StatusConflict},
+		{"missing token", `{"password":"secret"}`, false, models.User{}, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.callsService {
+				mockService.On("RedeemInvitation", mock.Anything, "t1", "", mock.AnythingOfType("string")).
+					Return(tt.user, tt.err).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/register/invite", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.RegisterInvite(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expCode, rec.Code)
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
+
 func TestAuthHandler_Login(t *testing.T) {
 	e, mockService, handler := setupAuthEcho()
 
@@ -131,12 +280,11 @@ func TestAuthHandler_Login(t *testing.T) {
 		reqJSON, _ := json.Marshal(reqBody)
 
 		user := models.User{
-			Email:    "test@example.com",
-			Password: "$2a$10$hashedpassword",
-			Role:     "client",
+			Email: "test@example.com",
+			Role:  "client",
 		}
-		mockService.On("GetUserByEmail", mock.Anything, "test@example.com").
-			Return(user, nil)
+		mockService.On("Authenticate", mock.Anything, "test@example.com", "password123").
+			Return(user, nil).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(reqJSON)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -171,8 +319,8 @@ func TestAuthHandler_Login(t *testing.T) {
 		}
 		reqJSON, _ := json.Marshal(reqBody)
 
-		mockService.On("GetUserByEmail", mock.Anything, "nonexistent@example.com").
-			Return(models.User{}, assert.AnError)
+		mockService.On("Authenticate", mock.Anything, "nonexistent@example.com", "password123").
+			Return(models.User{}, apperrors.ErrInvalidCredentials).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(reqJSON)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -191,13 +339,8 @@ func TestAuthHandler_Login(t *testing.T) {
 		}
 		reqJSON, _ := json.Marshal(reqBody)
 
-		user := models.User{
-			Email:    "test@example.com",
-			Password: "$2a$10$hashedpassword",
-			Role:     "client",
-		}
-		mockService.On("GetUserByEmail", mock.Anything, "test@example.com").
-			Return(user, nil)
+		mockService.On("Authenticate", mock.Anything, "test@example.com", "wrongpassword").
+			Return(models.User{}, apperrors.ErrInvalidCredentials).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(reqJSON)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -208,4 +351,167 @@ func TestAuthHandler_Login(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusUnauthorized, rec.Code)
 	})
+
+	t.Run("account locked", func(t *testing.T) {
+		reqJSON, _ := json.Marshal(map[string]string{
+			"email":    "locked@example.com",
+			"password": "password123",
+		})
+
+		until := time.Now().Add(90 * time.Second)
+		mockService.On("Authenticate", mock.Anything, "locked@example.com", "password123").
+			Return(models.User{Email: "locked@example.com", LockedUntil: &until}, apperrors.ErrAccountLocked).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(reqJSON)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Login(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "блокировка не отличается от неверного пароля")
+		assert.Empty(t, rec.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"message":"invalid credentials"}`, rec.Body.String())
+	})
+
+	t.Run("email not verified", func(t *testing.T) {
+		reqJSON, _ := json.Marshal(map[string]string{
+			"email":    "new@example.com",
+			"password": "password123",
+		})
+
+		mockService.On("Authenticate", mock.Anything, "new@example.com", "password123").
+			Return(models.User{}, apperrors.ErrEmailNotVerified).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(reqJSON)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Login(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		reqJSON, _ := json.Marshal(map[string]string{
+			"email":    "broken@example.com",
+			"password": "password123",
+		})
+
+		mockService.On("Authenticate", mock.Anything, "broken@example.com", "password123").
+			Return(models.User{}, assert.AnError).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(reqJSON)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Login(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestAuthHandler_ForgotPassword(t *testing.T) {
+	e, mockService, handler := setupAuthEcho()
+
+	tests := []struct {
+		name     string
+		body     string
+		mockErr  error
+		callMock bool
+		wantCode int
+	}{
+		{name: "accepted", body: `{"email":"test@example.com"}`, callMock: true, wantCode: http.StatusAccepted},
+		{name: "missing email", body: `{}`, wantCode: http.StatusBadRequest},
+		{name: "service error", body: `{"email":"test@example.com"}`, mockErr: assert.AnError, callMock: true, wantCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.callMock {
+				mockService.On("RequestPasswordReset", mock.Anything, "test@example.com").Return(tt.mockErr).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.ForgotPassword(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_ResetPassword(t *testing.T) {
+	e, mockService, handler := setupAuthEcho()
+
+	tests := []struct {
+		name     string
+		body     string
+		mockErr  error
+		callMock bool
+		wantCode int
+	}{
+		{name: "password changed", body: `{"token":"t1","password":"newpass"}`, callMock: true, wantCode: http.StatusOK},
+		{name: "invalid token", body: `{"token":"t1","password":"newpass"}`, mockErr: apperrors.ErrInvalidToken, callMock: true, wantCode: http.StatusBadRequest},
+		{name: "missing password", body: `{"token":"t1"}`, wantCode: http.StatusBadRequest},
+		{name: "service error", body: `{"token":"t1","password":"newpass"}`, mockErr: assert.AnError, callMock: true, wantCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.callMock {
+				mockService.On("ResetPassword", mock.Anything, "t1", mock.AnythingOfType("string")).Return(tt.mockErr).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.ResetPassword(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_VerifyEmail(t *testing.T) {
+	e, mockService, handler := setupAuthEcho()
+
+	tests := []struct {
+		name     string
+		body     string
+		mockErr  error
+		callMock bool
+		wantCode int
+	}{
+		{name: "verified", body: `{"token":"t1"}`, callMock: true, wantCode: http.StatusOK},
+		{name: "invalid token", body: `{"token":"t1"}`, mockErr: apperrors.ErrInvalidToken, callMock: true, wantCode: http.StatusBadRequest},
+		{name: "missing token", body: `{}`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.callMock {
+				mockService.On("VerifyEmail", mock.Anything, "t1").Return(tt.mockErr).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/email/verify", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.VerifyEmail(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+	mockService.AssertExpectations(t)
 }
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz-service/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DBStatsProvider - статистика пулов соединений (database.Pools)
+type DBStatsProvider interface {
+	PoolStats() map[string]models.DBPoolStats
+}
+
+type DBStatsHandler struct {
+	stats DBStatsProvider
+}
+
+func NewDBStatsHandler(stats DBStatsProvider) *DBStatsHandler {
+	return &DBStatsHandler{stats: stats}
+}
+
+// @Summary Статистика пулов соединений
+// @Description Состояние пулов соединений с базой этой реплики по имени пула (только для модераторов). Без базы (STORAGE=memory) ответ пустой
+// @Tags admin
+// @Security bearerAuth
+// @Produce json
+// @Success 200 {object} map[string]models.DBPoolStats
+// @Failure 403 {object} map[string]string
+// @Router /admin/db/stats [get]
+func (h *DBStatsHandler) Get(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.stats.PoolStats())
+}
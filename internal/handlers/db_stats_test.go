@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz-service/internal/models"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubPoolStats map[string]models.DBPoolStats
+
+func (s stubPoolStats) PoolStats() map[string]models.DBPoolStats {
+	return s
+}
+
+func TestDBStatsHandler_Get(t *testing.T) {
+	e := echo.New()
+	handler := NewDBStatsHandler(stubPoolStats{"primary": {MaxConns: 10, AcquiredConns: 3}})
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil), rec)
+
+	assert.NoError(t, handler.Get(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var stats map[string]models.DBPoolStats
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, int32(3), stats["primary"].AcquiredConns)
+}
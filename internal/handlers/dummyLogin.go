@@ -6,7 +6,6 @@ import (
 	"pvz-service/internal/services"
 
 	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
 )
 
 type DummyLoginHandler struct {
@@ -20,7 +19,7 @@ func NewDummyLoginHandler(services *services.Services) *DummyLoginHandler {
 }
 
 // @Summary Получение тестового токена
-// @Description Получение тестового токена для разработки
+// @Description Получение тестового токена для разработки. Маршрут есть только при DUMMY_LOGIN=true
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -36,18 +35,17 @@ func (h *DummyLoginHandler) DummyLogin(c echo.Context) error {
 
 	var r req
 	if err := c.Bind(&r); err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
 	}
 
 	if r.Role != "client" && r.Role != "moderator" {
-		logrus.Error("invalid role")
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid role")
 	}
 
 	token, err := jwt.GenerateToken(r.Role, h.services.Cfg)
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "could not generate token")
 	}
 
@@ -15,10 +15,10 @@ import (
 
 func setupDummyLoginEcho() (*echo.Echo, *DummyLoginHandler) {
 	e := echo.New()
+	cfg := config.Default()
+	cfg.Auth.Secret = "test_secret"
 	s := &services.Services{
-		Cfg: &config.Config{
-			SECRET: "test_secret",
-		},
+		Cfg: &cfg,
 	}
 	handler := NewDummyLoginHandler(s)
 	return e, handler
@@ -76,8 +76,7 @@ func TestDummyLoginHandler_DummyLogin(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		err := handler.DummyLogin(c)
-		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
 	})
 
 	t.Run("invalid role", func(t *testing.T) {
@@ -92,7 +91,6 @@ func TestDummyLoginHandler_DummyLogin(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		err := handler.DummyLogin(c)
-		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
 	})
 }
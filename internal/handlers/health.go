@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"pvz-service/internal/health"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReadinessChecker - источник состояния компонентов (health.Checker)
+type ReadinessChecker interface {
+	Check(ctx context.Context) health.Report
+}
+
+type HealthHandler struct {
+	checker ReadinessChecker
+}
+
+func NewHealthHandler(checker ReadinessChecker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// @Summary Liveness проба
+// @Description Процесс жив и обрабатывает HTTP запросы. Зависимости не проверяются
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (h *HealthHandler) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"status": health.StatusOK})
+}
+
+// @Summary Readiness проба
+// @Description Состояние базы, миграций и фоновых задач. Во время остановки сервера возвращает 503 со статусом shutting_down. Причины отказа компонентов пишутся только в лог
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c echo.Context) error {
+	report := h.checker.Check(c.Request().Context())
+	if report.Status != health.StatusOK {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
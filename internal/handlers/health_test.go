@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pvz-service/internal/health"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	e := echo.New()
+	checker := health.NewChecker()
+	checker.Register("database", func(ctx context.Context) error { return nil })
+	handler := NewHealthHandler(checker)
+
+	call := func() (*httptest.ResponseRecorder, health.Report) {
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		rec := httptest.NewRecorder()
+		require.NoError(t, handler.Readyz(e.NewContext(req, rec)))
+
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return rec, report
+	}
+
+	rec, report := call()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, health.StatusOK, report.Components["database"].Status)
+
+	checker.Register("migrations", func(ctx context.Context) error { return errors.New("dial tcp db.internal:5432: refused") })
+	rec, report = call()
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, health.StatusFail, report.Components["migrations"].Status)
+	assert.NotContains(t, rec.Body.String(), "db.internal", "детали ошибки не попадают в ответ")
+
+	checker.SetShuttingDown()
+	rec, report = call()
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, health.StatusShuttingDown, report.Status)
+}
+
+func TestHealthHandler_Healthz(t *testing.T) {
+	e := echo.New()
+	handler := NewHealthHandler(health.NewChecker())
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+
+	err := handler.Healthz(e.NewContext(req, rec))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
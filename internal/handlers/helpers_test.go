@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"errors"
+	"net/http/httptest"
+
+	"github.com/labstack/echo/v4"
+)
+
+// responseCode возвращает статус ответа с учетом того, что часть хендлеров
+// пишет ответ сама, а часть возвращает echo.HTTPError для общего обработчика.
+func responseCode(rec *httptest.ResponseRecorder, err error) int {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return rec.Code
+}
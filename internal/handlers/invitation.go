@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	apperrors "pvz-service/internal/pkg/errors"
+	"pvz-service/internal/services"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InvitationHandler struct {
+	services *services.Services
+}
+
+func NewInvitationHandler(services *services.Services) *InvitationHandler {
+	return &InvitationHandler{services: services}
+}
+
+type createInvitationRequest struct {
+	Email string  `json:"email"`
+	Role  string  `json:"role" enums:"client,moderator"`
+	PvzID *string `json:"pvzId"`
+}
+
+// @Summary Приглашение сотрудника или модератора
+// @Description Выпускает одноразовое приглашение с ограниченным сроком действия (только для модераторов). Токен показывается один раз
+// @Tags invitations
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param request body createInvitationRequest true "Email, role and optional PVZ"
+// @Success 201 {object} models.CreatedInvitation
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /invitations [post]
+func (h *InvitationHandler) Create(c echo.Context) error {
+	var req createInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	created, err := h.services.InvitationService.CreateInvitation(c.Request().Context(), req.Email, req.Role, req.PvzID)
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidInput):
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "email and role client or moderator are required"})
+	case errors.Is(err, apperrors.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "pvz not found"})
+	case err != nil:
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not create invitation"})
+	}
+
+	return c.JSON(http.StatusCreated, created)
+}
+
+// @Summary Список приглашений
+// @Description Приглашения, новые первыми (только для модераторов)
+// @Tags invitations
+// @Security bearerAuth
+// @Produce json
+// @Param page query int false "Номер страницы"
+// @Param limit query int false "Размер страницы"
+// @Success 200 {array} models.Invitation
+// @Failure 403 {object} map[string]string
+// @Router /invitations [get]
+func (h *InvitationHandler) List(c echo.Context) error {
+	page := 1
+	if p, err := strconv.Atoi(c.QueryParam("page")); err == nil && p > 0 {
+		page = p
+	}
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	invitations, err := h.services.InvitationService.ListInvitations(c.Request().Context(), limit, (page-1)*limit)
+	if err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not load invitations"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"data":  invitations,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// @Summary Отзыв приглашения
+// @Description Удаляет неиспользованное приглашение (только для модераторов)
+// @Tags invitations
+// @Security bearerAuth
+// @Param id path string true "ID приглашения"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /invitations/{id} [delete]
+func (h *InvitationHandler) Revoke(c echo.Context) error {
+	err := h.services.InvitationService.RevokeInvitation(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "invitation not found or already used"})
+	}
+	if err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not revoke invitation"})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
+	"pvz-service/internal/services"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupInvitationEcho() (*echo.Echo, *MockInvitationService, *InvitationHandler) {
+	e := echo.New()
+	mockService := new(MockInvitationService)
+	s := &services.Services{InvitationService: mockService}
+	handler := NewInvitationHandler(s)
+	return e, mockService, handler
+}
+
+func TestInvitationHandler_Create(t *testing.T) {
+	e, mockService, handler := setupInvitationEcho()
+
+	tests := []struct {
+		name    string
+		err     error
+		expCode int
+	}{
+		{"created", nil, http.StatusCreated},
+		{"invalid role", apperrors.ErrInvalidInput, http.StatusBadRequest},
+		{"pvz not found", apperrors.ErrNotFound, http.StatusNotFound},
+		{"service error", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService.On("CreateInvitation", mock.Anything, "new@example.com", models.RoleEmployee, mock.Anything).
+				Return(models.CreatedInvitation{Token: "secret"}, tt.err).Once()
+
+			req := httptest.NewRequest(http.MethodPost, "/invitations",
+				strings.NewReader(`{"email":"new@example.com","role":"client","pvzId":"p1"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.Create(c)
+			assert.Equal(t, tt.expCode, responseCode(rec, err))
+			if tt.err == nil {
+				assert.Contains(t, rec.Body.String(), `"token":"secret"`)
+			}
+		})
+	}
+}
+
+func TestInvitationHandler_Revoke(t *testing.T) {
+	e, mockService, handler := setupInvitationEcho()
+
+	tests := []struct {
+		name    string
+		err     error
+		expCode int
+	}{
+		{"revoked", nil, http.StatusNoContent},
+		{"not found", apperrors.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService.On("RevokeInvitation", mock.Anything, "i1").Return(tt.err).Once()
+
+			req := httptest.NewRequest(http.MethodDelete, "/invitations/i1", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("i1")
+
+			err := handler.Revoke(c)
+			assert.Equal(t, tt.expCode, responseCode(rec, err))
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz-service/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JobStatusProvider - источник состояния фоновых задач (jobs.Scheduler)
+type JobStatusProvider interface {
+	Status() []models.JobStatus
+}
+
+type JobHandler struct {
+	jobs JobStatusProvider
+}
+
+func NewJobHandler(jobs JobStatusProvider) *JobHandler {
+	return &JobHandler{jobs: jobs}
+}
+
+// @Summary Состояние фоновых задач
+// @Description Последние запуски фоновых задач этой реплики (только для модераторов). Статус skipped означает, что задачу в это время выполняла другая реплика.
+// @Tags jobs
+// @Security bearerAuth
+// @Produce json
+// @Success 200 {array} models.JobStatus
+// @Failure 403 {object} map[string]string
+// @Router /jobs [get]
+func (h *JobHandler) List(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.jobs.Status())
+}
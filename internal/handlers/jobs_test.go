@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz-service/internal/models"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubJobs []models.JobStatus
+
+func (s stubJobs) Status() []models.JobStatus {
+	return s
+}
+
+func TestJobHandler_List(t *testing.T) {
+	e := echo.New()
+	handler := NewJobHandler(stubJobs{{
+		Name:     "stale_receptions",
+		Interval: "10m0s",
+		Runs:     []models.JobRun{{Status: models.JobRunOK, Processed: 2}},
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := handler.List(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var statuses []models.JobStatus
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statuses))
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, "stale_receptions", statuses[0].Name)
+	assert.Equal(t, 2, statuses[0].Runs[0].Processed)
+}
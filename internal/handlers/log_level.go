@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz-service/internal/services"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// LogLevelController - уровень логирования процесса (logger.Levels)
+type LogLevelController interface {
+	Level() string
+	SetLevel(level string) error
+}
+
+type LogLevelHandler struct {
+	levels LogLevelController
+	audit  services.AuditServiceInterface
+}
+
+func NewLogLevelHandler(levels LogLevelController, audit services.AuditServiceInterface) *LogLevelHandler {
+	return &LogLevelHandler{levels: levels, audit: audit}
+}
+
+type logLevelBody struct {
+	Level string `json:"level" example:"debug"`
+}
+
+// @Summary Текущий уровень логирования
+// @Description Уровень логирования этой реплики (только для модераторов)
+// @Tags admin
+// @Security bearerAuth
+// @Produce json
+// @Success 200 {object} logLevelBody
+// @Failure 403 {object} map[string]string
+// @Router /admin/log-level [get]
+func (h *LogLevelHandler) Get(c echo.Context) error {
+	return c.JSON(http.StatusOK, logLevelBody{Level: h.levels.Level()})
+}
+
+// @Summary Смена уровня логирования
+// @Description Меняет уровень логирования этой реплики без перезапуска, до следующего перезапуска (только для модераторов). Уровни: trace, debug, info, warn, error. Смена записывается в журнал аудита
+// @Tags admin
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param request body logLevelBody true "Новый уровень"
+// @Success 200 {object} logLevelBody
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/log-level [put]
+func (h *LogLevelHandler) Set(c echo.Context) error {
+	var req logLevelBody
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	if _, err := logrus.ParseLevel(req.Level); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid log level"})
+	}
+
+	before := h.levels.Level()
+	// пишем до смены уровня, чтобы запись не потерялась при переходе на error
+	requestLogger(c).Warnf("смена уровня логирования: %s -> %s", before, req.Level)
+
+	// без записи в аудите уровень не меняется; уровень уже проверен, SetLevel не откажет
+	if err := h.audit.RecordLogLevelChange(c.Request().Context(), before, req.Level); err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not record audit entry"})
+	}
+	if err := h.levels.SetLevel(req.Level); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid log level"})
+	}
+
+	return c.JSON(http.StatusOK, logLevelBody{Level: h.levels.Level()})
+}
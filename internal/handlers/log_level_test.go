@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type stubLevels struct {
+	level string
+}
+
+func (s *stubLevels) Level() string { return s.level }
+
+func (s *stubLevels) SetLevel(level string) error {
+	if _, err := logrus.ParseLevel(level); err != nil {
+		return err
+	}
+	s.level = level
+	return nil
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	e := echo.New()
+	levels := &stubLevels{level: "info"}
+	audit := new(MockAuditService)
+	handler := NewLogLevelHandler(levels, audit)
+
+	t.Run("get", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/admin/log-level", nil), rec)
+
+		assert.NoError(t, handler.Get(c))
+		assert.JSONEq(t, `{"level":"info"}`, rec.Body.String())
+	})
+
+	t.Run("set", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"debug"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		audit.On("RecordLogLevelChange", mock.Anything, "info", "debug").Return(nil).Once()
+
+		assert.NoError(t, handler.Set(e.NewContext(req, rec)))
+		assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+		assert.Equal(t, "debug", levels.level)
+		audit.AssertExpectations(t)
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"verbose"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		err := handler.Set(e.NewContext(req, httptest.NewRecorder()))
+		he, ok := err.(*echo.HTTPError)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusBadRequest, he.Code)
+		assert.Equal(t, "debug", levels.level)
+		audit.AssertNumberOfCalls(t, "RecordLogLevelChange", 1)
+	})
+
+	t.Run("audit failure keeps the level", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"warn"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		audit.On("RecordLogLevelChange", mock.Anything, "debug", "warn").Return(assert.AnError).Once()
+
+		err := handler.Set(e.NewContext(req, httptest.NewRecorder()))
+		he, ok := err.(*echo.HTTPError)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusInternalServerError, he.Code)
+		assert.Equal(t, "debug", levels.level)
+	})
+}
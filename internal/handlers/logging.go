@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"pvz-service/internal/logger"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// requestLogger возвращает логгер текущего запроса с request id и пользователем
+func requestLogger(c echo.Context) *logrus.Entry {
+	return logger.FromContext(c.Request().Context())
+}
+
+// withPVZ добавляет ПВЗ в логгер запроса. Контекст с полем уходит дальше в
+// сервисы и репозитории, а итоговая строка LoggerMiddleware тоже его получит.
+func withPVZ(c echo.Context, pvzID string) {
+	if pvzID == "" {
+		return
+	}
+	ctx := logger.WithFields(c.Request().Context(), logrus.Fields{"pvz_id": pvzID})
+	c.SetRequest(c.Request().WithContext(ctx))
+}
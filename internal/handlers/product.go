@@ -1,13 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
 	"pvz-service/internal/services"
 	"strings"
 
 	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
 )
 
 type ItemHandler struct {
@@ -19,23 +20,146 @@ func NewProductHandler(services *services.Services) *ItemHandler {
 }
 
 type req struct {
-	Type  string `json:"type"`
-	PvzId string `json:"PvzId"`
+	Type           string `json:"type"`
+	PvzId          string `json:"PvzId"`
+	RecipientEmail string `json:"recipientEmail"`
 }
 
+// @Summary Добавление товара в открытую приемку
+// @Description Код получения отправляется письмом на recipientEmail и в ответ не попадает
+// @Tags products
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param request body req true "Товар"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /product [post]
 func (h *ItemHandler) AddProduct(c echo.Context) error {
 	req := req{}
-	if err := c.Bind(&req); err != nil || req.Type == "" || req.PvzId == "" {
+	if err := c.Bind(&req); err != nil || req.Type == "" || req.PvzId == "" || req.RecipientEmail == "" {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "invalid request"})
 	}
+	withPVZ(c, req.PvzId)
 
 	product := models.Product{}
 	product.Type = strings.ToLower(req.Type)
+	product.RecipientEmail = req.RecipientEmail
 
-	if err := h.services.ProductService.AddProduct(c.Request().Context(), product, req.PvzId); err != nil {
-		logrus.Error(err)
+	created, err := h.services.ProductService.AddProduct(c.Request().Context(), product, req.PvzId)
+	if errors.Is(err, apperrors.ErrInvalidInput) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+	if err != nil {
+		requestLogger(c).Error(err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "failed to add item"})
 	}
 
-	return c.JSON(http.StatusCreated, echo.Map{"message": "created"})
+	return c.JSON(http.StatusCreated, echo.Map{
+		"message":  "created",
+		"id":       created.ID,
+		"dateTime": created.DateTime,
+		"status":   created.Status,
+	})
+}
+
+// @Summary Выдача товара получателю
+// @Description Сотрудник выдает готовый к выдаче товар по коду, который называет получатель.
+// @Description Выдать товар может только сотрудник ПВЗ, в который товар принят. После 5 неверных кодов код нужно перевыпустить.
+// @Tags products
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param request body object{pickupCode=string} true "Код получения"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /products/{id}/issue [post]
+func (h *ItemHandler) Issue(c echo.Context) error {
+	var req struct {
+		PickupCode string `json:"pickupCode"`
+	}
+	if err := c.Bind(&req); err != nil || req.PickupCode == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "pickupCode is required"})
+	}
+
+	product, err := h.services.ProductService.IssueProduct(c.Request().Context(), c.Param("id"), strings.TrimSpace(req.PickupCode))
+	if err != nil {
+		return pickupError(c, err, "could not issue product")
+	}
+
+	return c.JSON(http.StatusOK, product)
+}
+
+// @Summary Возврат товара
+// @Description Возврат отправителю невостребованного товара или товара, который вернул получатель. Только для сотрудника ПВЗ товара
+// @Tags products
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {object} models.Product
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /products/{id}/return [post]
+func (h *ItemHandler) Return(c echo.Context) error {
+	product, err := h.services.ProductService.ReturnProduct(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return pickupError(c, err, "could not return product")
+	}
+
+	return c.JSON(http.StatusOK, product)
+}
+
+// @Summary Перевыпуск кода получения
+// @Description Новый код взамен утерянного или заблокированного после неверных попыток (только для модераторов).
+// @Description Код отправляется получателю письмом, старый код перестает действовать. recipientEmail заменяет адрес получателя.
+// @Tags products
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param request body object{recipientEmail=string} false "Новый email получателя"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /products/{id}/pickup_code [post]
+func (h *ItemHandler) ResetPickupCode(c echo.Context) error {
+	var req struct {
+		RecipientEmail string `json:"recipientEmail"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	product, err := h.services.ProductService.ResetPickupCode(c.Request().Context(), c.Param("id"), req.RecipientEmail)
+	if err != nil {
+		return pickupError(c, err, "could not reset pickup code")
+	}
+
+	return c.JSON(http.StatusOK, product)
+}
+
+func pickupError(c echo.Context, err error, failure string) error {
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidPickupCode), errors.Is(err, apperrors.ErrInvalidInput):
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	case errors.Is(err, apperrors.ErrWrongPVZ):
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"message": "product belongs to another PVZ"})
+	case errors.Is(err, apperrors.ErrForbidden):
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"message": "only an employee of the product's PVZ can do this"})
+	case errors.Is(err, apperrors.ErrProductStatus), errors.Is(err, apperrors.ErrPickupLocked):
+		return echo.NewHTTPError(http.StatusConflict, echo.Map{"message": err.Error()})
+	case errors.Is(err, apperrors.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "product not found"})
+	default:
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": failure})
+	}
 }
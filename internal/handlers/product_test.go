@@ -3,12 +3,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
 	"pvz-service/internal/services"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -19,9 +22,24 @@ type MockProductService struct {
 	mock.Mock
 }
 
-func (m *MockProductService) AddProduct(ctx context.Context, product models.Product, pvzId string) error {
+func (m *MockProductService) AddProduct(ctx context.Context, product models.Product, pvzId string) (models.Product, error) {
 	args := m.Called(ctx, product, pvzId)
-	return args.Error(0)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) IssueProduct(ctx context.Context, id, code string) (models.Product, error) {
+	args := m.Called(ctx, id, code)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) ReturnProduct(ctx context.Context, id string) (models.Product, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) ResetPickupCode(ctx context.Context, id, recipientEmail string) (models.Product, error) {
+	args := m.Called(ctx, id, recipientEmail)
+	return args.Get(0).(models.Product), args.Error(1)
 }
 
 func (m *MockProductService) DeleteLastProduct(ctx context.Context, pvzID string) error {
@@ -29,11 +47,16 @@ func (m *MockProductService) DeleteLastProduct(ctx context.Context, pvzID string
 	return args.Error(0)
 }
 
+func (m *MockProductService) GetByReceptionID(ctx context.Context, receptionID string) ([]models.Product, error) {
+	args := m.Called(ctx, receptionID)
+	return args.Get(0).([]models.Product), args.Error(1)
+}
+
 func setupProductEcho() (*echo.Echo, *MockProductService, *ItemHandler) {
 	e := echo.New()
 	mockService := new(MockProductService)
 	s := &services.Services{
-		ProductService: &services.ProductService{},
+		ProductService: mockService,
 	}
 	handler := NewProductHandler(s)
 	return e, mockService, handler
@@ -44,13 +67,17 @@ func TestItemHandler_AddProduct(t *testing.T) {
 
 	t.Run("successful product addition", func(t *testing.T) {
 		reqBody := map[string]string{
-			"type":  "electronics",
-			"PvzId": "1",
+			"type":           "electronics",
+			"PvzId":          "1",
+			"recipientEmail": "client@example.com",
 		}
 		reqJSON, _ := json.Marshal(reqBody)
 
-		mockService.On("AddProduct", mock.Anything, mock.Anything, "1").
-			Return(nil)
+		mockService.On("AddProduct", mock.Anything, mock.MatchedBy(func(p models.Product) bool {
+			return p.RecipientEmail == "client@example.com"
+		}), "1").
+			Return(models.Product{ID: "p1", Status: models.ProductAccepted, RecipientEmail: "client@example.com",
+				DateTime: time.Date(2025, 4, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(string(reqJSON)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -65,6 +92,10 @@ func TestItemHandler_AddProduct(t *testing.T) {
 		err = json.Unmarshal(rec.Body.Bytes(), &response)
 		assert.NoError(t, err)
 		assert.Equal(t, "created", response["message"])
+		assert.Equal(t, "2025-04-01T12:00:00+03:00", response["dateTime"])
+		// код уходит получателю письмом, сотрудник его не видит
+		assert.NotContains(t, response, "pickupCode")
+		assert.NotContains(t, rec.Body.String(), "client@example.com")
 	})
 
 	t.Run("invalid request body", func(t *testing.T) {
@@ -80,7 +111,8 @@ func TestItemHandler_AddProduct(t *testing.T) {
 
 	t.Run("missing required fields", func(t *testing.T) {
 		reqBody := map[string]string{
-			"type": "electronics",
+			"type":  "electronics",
+			"PvzId": "1",
 		}
 		reqJSON, _ := json.Marshal(reqBody)
 
@@ -94,15 +126,32 @@ func TestItemHandler_AddProduct(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 
+	t.Run("invalid recipient", func(t *testing.T) {
+		reqJSON, _ := json.Marshal(map[string]string{"type": "electronics", "PvzId": "2", "recipientEmail": "not an email"})
+
+		mockService.On("AddProduct", mock.Anything, mock.Anything, "2").
+			Return(models.Product{}, fmt.Errorf("%w: некорректный email получателя", apperrors.ErrInvalidInput)).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(string(reqJSON)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.AddProduct(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
 	t.Run("service error", func(t *testing.T) {
 		reqBody := map[string]string{
-			"type":  "electronics",
-			"PvzId": "1",
+			"type":           "electronics",
+			"PvzId":          "1",
+			"recipientEmail": "client@example.com",
 		}
 		reqJSON, _ := json.Marshal(reqBody)
 
 		mockService.On("AddProduct", mock.Anything, mock.Anything, "1").
-			Return(assert.AnError)
+			Return(models.Product{}, assert.AnError)
 
 		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(string(reqJSON)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -114,3 +163,160 @@ func TestItemHandler_AddProduct(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
+
+func TestItemHandler_Issue(t *testing.T) {
+	e, mockService, handler := setupProductEcho()
+
+	issue := func(id, body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/products/"+id+"/issue", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		return rec, handler.Issue(c)
+	}
+
+	t.Run("issued", func(t *testing.T) {
+		mockService.On("IssueProduct", mock.Anything, "p1", "123456").
+			Return(models.Product{ID: "p1", Status: models.ProductIssued}, nil).Once()
+
+		rec, err := issue("p1", `{"pickupCode":" 123456 "}`)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response models.Product
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, models.ProductIssued, response.Status)
+	})
+
+	t.Run("missing code", func(t *testing.T) {
+		rec, err := issue("p1", `{}`)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		mockService.On("IssueProduct", mock.Anything, "p1", "000000").
+			Return(models.Product{}, apperrors.ErrInvalidPickupCode).Once()
+
+		rec, err := issue("p1", `{"pickupCode":"000000"}`)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+
+	t.Run("not ready or already issued", func(t *testing.T) {
+		mockService.On("IssueProduct", mock.Anything, "p2", "123456").
+			Return(models.Product{}, apperrors.ErrProductStatus).Once()
+
+		rec, err := issue("p2", `{"pickupCode":"123456"}`)
+		assert.Equal(t, http.StatusConflict, responseCode(rec, err))
+	})
+
+	t.Run("code locked", func(t *testing.T) {
+		mockService.On("IssueProduct", mock.Anything, "p3", "123456").
+			Return(models.Product{}, apperrors.ErrPickupLocked).Once()
+
+		rec, err := issue("p3", `{"pickupCode":"123456"}`)
+		assert.Equal(t, http.StatusConflict, responseCode(rec, err))
+	})
+
+	t.Run("another PVZ", func(t *testing.T) {
+		mockService.On("IssueProduct", mock.Anything, "p4", "123456").
+			Return(models.Product{}, apperrors.ErrWrongPVZ).Once()
+
+		rec, err := issue("p4", `{"pickupCode":"123456"}`)
+		assert.Equal(t, http.StatusForbidden, responseCode(rec, err))
+	})
+
+	t.Run("not an employee", func(t *testing.T) {
+		mockService.On("IssueProduct", mock.Anything, "p5", "123456").
+			Return(models.Product{}, apperrors.ErrForbidden).Once()
+
+		rec, err := issue("p5", `{"pickupCode":"123456"}`)
+		assert.Equal(t, http.StatusForbidden, responseCode(rec, err))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService.On("IssueProduct", mock.Anything, "missing", "123456").
+			Return(models.Product{}, apperrors.ErrNotFound).Once()
+
+		rec, err := issue("missing", `{"pickupCode":"123456"}`)
+		assert.Equal(t, http.StatusNotFound, responseCode(rec, err))
+	})
+}
+
+func TestItemHandler_Return(t *testing.T) {
+	e, mockService, handler := setupProductEcho()
+
+	returnProduct := func(id string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/products/"+id+"/return", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		return rec, handler.Return(c)
+	}
+
+	t.Run("returned", func(t *testing.T) {
+		mockService.On("ReturnProduct", mock.Anything, "p1").
+			Return(models.Product{ID: "p1", Status: models.ProductReturned}, nil).Once()
+
+		rec, err := returnProduct("p1")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("another PVZ", func(t *testing.T) {
+		mockService.On("ReturnProduct", mock.Anything, "p2").
+			Return(models.Product{}, apperrors.ErrWrongPVZ).Once()
+
+		rec, err := returnProduct("p2")
+		assert.Equal(t, http.StatusForbidden, responseCode(rec, err))
+	})
+}
+
+func TestItemHandler_ResetPickupCode(t *testing.T) {
+	e, mockService, handler := setupProductEcho()
+
+	reset := func(body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/products/p1/pickup_code", strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("p1")
+		return rec, handler.ResetPickupCode(c)
+	}
+
+	t.Run("code sent to the recipient", func(t *testing.T) {
+		mockService.On("ResetPickupCode", mock.Anything, "p1", "").
+			Return(models.Product{ID: "p1", Status: models.ProductReadyForPickup, PickupCodeHash: "hash"}, nil).Once()
+
+		rec, err := reset("")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.NotContains(t, response, "pickupCode")
+		assert.NotContains(t, rec.Body.String(), "hash")
+	})
+
+	t.Run("new recipient", func(t *testing.T) {
+		mockService.On("ResetPickupCode", mock.Anything, "p1", "new@example.com").
+			Return(models.Product{ID: "p1", Status: models.ProductReadyForPickup}, nil).Once()
+
+		rec, err := reset(`{"recipientEmail":"new@example.com"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("no recipient", func(t *testing.T) {
+		mockService.On("ResetPickupCode", mock.Anything, "p1", "bad").
+			Return(models.Product{}, fmt.Errorf("%w: некорректный email получателя", apperrors.ErrInvalidInput)).Once()
+
+		rec, err := reset(`{"recipientEmail":"bad"}`)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+}
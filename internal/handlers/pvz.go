@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
 	"pvz-service/internal/services"
+	"pvz-service/internal/tracing"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
 )
 
+// maxImportBytes ограничивает тело запроса импорта, включая обертку multipart
+const maxImportBytes = 8 << 20
+
 type PVZHandler struct {
 	services *services.Services
 }
@@ -17,43 +25,85 @@ func NewPVZHandler(services *services.Services) *PVZHandler {
 	return &PVZHandler{services: services}
 }
 
+// @Summary Список ПВЗ с приемками
+// @Description Только для сотрудников и модераторов. Даты from, to, registeredFrom и registeredTo - местные календарные дни в поясе ПВЗ или в поясе tz, если он задан. Время в ответе - RFC 3339 со смещением пояса ПВЗ.
+// @Tags pvz
+// @Security bearerAuth
+// @Produce json
+// @Param page query int false "Номер страницы"
+// @Param limit query int false "Размер страницы, по умолчанию 10, не больше 100"
+// @Param from query string false "Первый день периода приемок, 2006-01-02"
+// @Param to query string false "Последний день периода приемок включительно, 2006-01-02"
+// @Param tz query string false "Пояс IANA для границ дней, например Asia/Yekaterinburg"
+// @Param city query string false "Город"
+// @Param hasOpenReception query bool false "Есть ли открытая приемка"
+// @Param registeredFrom query string false "Зарегистрирован не раньше дня, 2006-01-02"
+// @Param registeredTo query string false "Зарегистрирован не позже дня, 2006-01-02"
+// @Param minProducts query int false "Минимум товаров в приемках за период"
+// @Param sort query string false "Поле сортировки" Enums(registrationDate, city, lastReception)
+// @Param order query string false "Направление сортировки, по умолчанию desc для дат и asc для города" Enums(asc, desc)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /pvz [get]
 func (h *PVZHandler) GetAll(c echo.Context) error {
-	from := c.QueryParam("from")
-	to := c.QueryParam("to")
-	page := c.QueryParam("page")
-	limit := c.QueryParam("limit")
-
-	pvzs, err := h.services.PvzService.GetAll(c.Request().Context(), page, limit, from, to)
+	var (
+		page          int
+		filter        models.PVZFilter
+		hasOpen       bool
+		hasOpenFilter = c.QueryParam("hasOpenReception") != ""
+	)
+	err := echo.QueryParamsBinder(c).
+		Int("page", &page).
+		Int("limit", &filter.Limit).
+		Time("from", &filter.Period.From, models.DateLayout).
+		Time("to", &filter.Period.To, models.DateLayout).
+		String("tz", &filter.Period.TimeZone).
+		String("city", &filter.City).
+		Bool("hasOpenReception", &hasOpen).
+		Time("registeredFrom", &filter.Registered.From, models.DateLayout).
+		Time("registeredTo", &filter.Registered.To, models.DateLayout).
+		Int("minProducts", &filter.MinProducts).
+		String("sort", &filter.Sort).
+		String("order", &filter.Order).
+		BindError()
 	if err != nil {
-		logrus.Error(err)
-		c.JSON(http.StatusBadRequest, echo.Map{
-			"Message": "invalid body",
-		})
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "page, limit and minProducts must be numbers, dates must be 2006-01-02, hasOpenReception must be a boolean"})
 	}
-	response := echo.Map{
-		"data": pvzs,
+	if hasOpenFilter {
+		filter.HasOpenReception = &hasOpen
 	}
 
-	if page != "" {
-		response["page"] = page
-	} else {
-		response["page"] = 1
+	pvzs, err := h.services.PvzService.GetAll(c.Request().Context(), page, filter)
+	if err != nil {
+		requestLogger(c).Error(err)
+		if errors.Is(err, apperrors.ErrInvalidInput) {
+			return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": err.Error()})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not list PVZ"})
 	}
 
-	if from != "" {
-		response["from"] = from
+	response := echo.Map{
+		"data": pvzs,
 	}
 
-	if to != "" {
-		response["to"] = to
+	// параметры запроса возвращаются как переданы
+	for _, param := range []string{"page", "limit", "from", "to", "tz", "city", "hasOpenReception", "registeredFrom", "registeredTo", "minProducts", "sort", "order"} {
+		if value := c.QueryParam(param); value != "" {
+			response[param] = value
+		}
 	}
-
-	if limit != "" {
-		response["limit"] = limit
-	} else {
+	if _, ok := response["page"]; !ok {
+		response["page"] = 1
+	}
+	if _, ok := response["limit"]; !ok {
 		response["limit"] = 10
 	}
 
+	// список с приемками и товарами бывает большим, сериализация - отдельный спан
+	_, span := tracing.Start(c.Request().Context(), "PVZHandler.GetAll encode")
+	defer span.End()
+
 	return c.JSON(http.StatusOK, response)
 }
 
@@ -71,70 +121,206 @@ func (h *PVZHandler) GetAll(c echo.Context) error {
 func (h *PVZHandler) Create(c echo.Context) error {
 	var pvz models.PVZ
 	if err := c.Bind(&pvz); err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
 	}
 
 	created, err := h.services.PvzService.CreatePVZ(c.Request().Context(), pvz)
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
+		if errors.Is(err, apperrors.ErrInvalidInput) {
+			return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": err.Error()})
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not create PVZ"})
 	}
 
 	return c.JSON(http.StatusCreated, created)
 }
 
+// @Summary Поиск ближайших ПВЗ
+// @Description ПВЗ с координатами в радиусе от точки, ближайшие первыми. Расстояние в метрах.
+// @Tags pvz
+// @Security bearerAuth
+// @Produce json
+// @Param lat query number true "Широта"
+// @Param lon query number true "Долгота"
+// @Param radius query number false "Радиус в метрах (по умолчанию 5000, не больше 50000)"
+// @Param limit query int false "Количество ПВЗ (по умолчанию 10, не больше 100)"
+// @Param open query bool false "Только открытые сейчас"
+// @Success 200 {array} models.NearbyPVZ
+// @Failure 400 {object} map[string]string
+// @Router /pvz/nearby [get]
+func (h *PVZHandler) Nearby(c echo.Context) error {
+	var q models.NearbyQuery
+	err := echo.QueryParamsBinder(c).
+		MustFloat64("lat", &q.Lat).
+		MustFloat64("lon", &q.Lon).
+		Float64("radius", &q.Radius).
+		Int("limit", &q.Limit).
+		Bool("open", &q.OpenNow).
+		BindError()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "lat and lon are required numbers, radius and limit must be numbers, open must be a boolean"})
+	}
+
+	pvzs, err := h.services.PvzService.Nearby(c.Request().Context(), q)
+	if err != nil {
+		requestLogger(c).Error(err)
+		if errors.Is(err, apperrors.ErrInvalidInput) {
+			return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": err.Error()})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not search PVZ"})
+	}
+
+	return c.JSON(http.StatusOK, pvzs)
+}
+
 // @Summary Получение ПВЗ по ID
-// @Description Получение информации о пункте выдачи заказов по его ID
+// @Description Получение информации о пункте выдачи заказов по его ID (только для сотрудников и модераторов)
 // @Tags pvz
 // @Security bearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "PVZ ID"
 // @Success 200 {object} models.PVZ
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /pvz/{id} [get]
 func (h *PVZHandler) GetByID(c echo.Context) error {
 	id := c.Param("id")
+	withPVZ(c, id)
 	pvz, err := h.services.PvzService.GetPVZByID(c.Request().Context(), id)
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "PVZ not found"})
 	}
 
 	return c.JSON(http.StatusOK, pvz)
 }
 
-// @Summary Закрытие последней открытой приемки товаров в рамках ПВЗ
-// @Description Закрытие последней открытой приемки товаров в рамках ПВЗ
+// @Summary Удаление последнего товара
+// @Description Удаление последнего добавленного товара из открытой приемки ПВЗ
 // @Tags pvz
 // @Security bearerAuth
-// @Accept json
 // @Produce json
 // @Param id path string true "PVZ ID"
-// @Success 200 {object} models.PVZ
+// @Success 200 {object} map[string]string
 // @Failure 404 {object} map[string]string
-// @Router /pvz/{id}/delete_last_product [post]
+// @Failure 409 {object} map[string]string
+// @Router /pvz/{id}/delete_last_product [delete]
 func (h *PVZHandler) DeleteLastProduct(c echo.Context) error {
 	id := c.Param("id")
-	logrus.Info(id)
+	withPVZ(c, id)
 	err := h.services.PvzService.DeleteLastProduct(c.Request().Context(), id)
+	if errors.Is(err, apperrors.ErrProductStatus) {
+		return echo.NewHTTPError(http.StatusConflict, echo.Map{"message": "the last product has already left the accepted status"})
+	}
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "PVZ not found"})
 	}
 
 	return c.JSON(http.StatusOK, echo.Map{"message": "Товар удален"})
 }
 
+// @Summary Закрытие последней открытой приемки товаров в рамках ПВЗ
+// @Description Закрытие последней открытой приемки товаров в рамках ПВЗ
+// @Tags pvz
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "PVZ ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /pvz/{id}/close_last_reception [put]
 func (h *PVZHandler) CloseLastReception(c echo.Context) error {
 	id := c.Param("id")
-	logrus.Info(id)
+	withPVZ(c, id)
 	err := h.services.PvzService.CloseLastReception(c.Request().Context(), id)
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "PVZ not found"})
 	}
 
 	return c.JSON(http.StatusOK, echo.Map{"message": "Приемка закрыта"})
 }
+
+// @Summary Массовый импорт ПВЗ из CSV
+// @Description Создание ПВЗ из CSV файла (только для модераторов). Первая строка - заголовок с колонками city, registration_date, address, latitude, longitude, time_zone.
+// @Description В режиме dry_run только проверяет файл. Без dry_run создает все ПВЗ одной транзакцией или ни одного при ошибках.
+// @Tags pvz
+// @Security bearerAuth
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Produce json
+// @Param file formData file false "CSV file"
+// @Param dry_run query bool false "Только проверить файл"
+// @Success 200 {object} models.PVZImportResult
+// @Success 201 {object} models.PVZImportResult
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 422 {object} models.PVZImportResult
+// @Router /pvz/import [post]
+func (h *PVZHandler) Import(c echo.Context) error {
+	dryRun := false
+	if v := c.QueryParam("dry_run"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid dry_run"})
+		}
+		dryRun = parsed
+	}
+
+	// файл целиком не держим в памяти: тело больше maxImportBytes обрывается
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxImportBytes)
+
+	var body io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			requestLogger(c).Error(err)
+			if isBodyTooLarge(err) {
+				return importTooLarge()
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "file is required"})
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			requestLogger(c).Error(err)
+			return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid file"})
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := h.services.PvzService.ImportPVZ(c.Request().Context(), body, dryRun)
+	if err != nil {
+		requestLogger(c).Error(err)
+		if isBodyTooLarge(err) {
+			return importTooLarge()
+		}
+		if errors.Is(err, apperrors.ErrInvalidInput) {
+			return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": err.Error()})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not import PVZ"})
+	}
+
+	if len(result.Errors) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, result)
+	}
+
+	if dryRun {
+		return c.JSON(http.StatusOK, result)
+	}
+
+	return c.JSON(http.StatusCreated, result)
+}
+
+func isBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+func importTooLarge() error {
+	return echo.NewHTTPError(http.StatusRequestEntityTooLarge, echo.Map{"message": "import file is larger than " + strconv.Itoa(maxImportBytes>>20) + " MB"})
+}
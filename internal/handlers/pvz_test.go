@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
 	"pvz-service/internal/services"
 	"strings"
 	"testing"
@@ -20,8 +26,8 @@ type MockPVZService struct {
 	mock.Mock
 }
 
-func (m *MockPVZService) GetAll(ctx context.Context, page, limit, from, to string) ([]models.FullPVZ, error) {
-	args := m.Called(ctx, page, limit, from, to)
+func (m *MockPVZService) GetAll(ctx context.Context, page int, filter models.PVZFilter) ([]models.FullPVZ, error) {
+	args := m.Called(ctx, page, filter)
 	return args.Get(0).([]models.FullPVZ), args.Error(1)
 }
 
@@ -35,6 +41,16 @@ func (m *MockPVZService) GetPVZByID(ctx context.Context, id string) (models.PVZ,
 	return args.Get(0).(models.PVZ), args.Error(1)
 }
 
+func (m *MockPVZService) ListPVZ(ctx context.Context, limit, offset int) ([]models.PVZ, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]models.PVZ), args.Error(1)
+}
+
+func (m *MockPVZService) Nearby(ctx context.Context, q models.NearbyQuery) ([]models.NearbyPVZ, error) {
+	args := m.Called(ctx, q)
+	return args.Get(0).([]models.NearbyPVZ), args.Error(1)
+}
+
 func (m *MockPVZService) DeletePVZ(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -50,6 +66,12 @@ func (m *MockPVZService) CloseLastReception(ctx context.Context, id string) erro
 	return args.Error(0)
 }
 
+func (m *MockPVZService) ImportPVZ(ctx context.Context, r io.Reader, dryRun bool) (models.PVZImportResult, error) {
+	body, _ := io.ReadAll(r)
+	args := m.Called(ctx, string(body), dryRun)
+	return args.Get(0).(models.PVZImportResult), args.Error(1)
+}
+
 func setupEcho() (*echo.Echo, *MockPVZService, *PVZHandler) {
 	e := echo.New()
 	mockService := new(MockPVZService)
@@ -78,7 +100,7 @@ func TestPVZHandler_GetAll(t *testing.T) {
 				Receptions:       make(map[string]models.FullReception),
 			},
 		}
-		mockService.On("GetAll", mock.Anything, "1", "10", "", "").
+		mockService.On("GetAll", mock.Anything, 1, models.PVZFilter{Limit: 10}).
 			Return(expectedPVZs, nil)
 
 		req := httptest.NewRequest(http.MethodGet, "/pvz?page=1&limit=10", nil)
@@ -96,9 +118,74 @@ func TestPVZHandler_GetAll(t *testing.T) {
 		assert.Equal(t, "10", response["limit"])
 	})
 
+	t.Run("filters and sorting", func(t *testing.T) {
+		open := false
+		filter := models.PVZFilter{
+			Period: models.DayPeriod{
+				From:     time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC),
+				To:       time.Date(2025, time.May, 2, 0, 0, 0, 0, time.UTC),
+				TimeZone: "Asia/Yekaterinburg",
+			},
+			Registered:       models.DayPeriod{From: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+			City:             "казань",
+			HasOpenReception: &open,
+			MinProducts:      3,
+			Sort:             models.PVZSortLastReception,
+			Order:            models.SortAsc,
+		}
+		mockService.On("GetAll", mock.Anything, 0, filter).
+			Return([]models.FullPVZ{}, nil)
+
+		query := url.Values{
+			"from":             {"2025-05-01"},
+			"to":               {"2025-05-02"},
+			"tz":               {"Asia/Yekaterinburg"},
+			"city":             {"казань"},
+			"hasOpenReception": {"false"},
+			"registeredFrom":   {"2025-01-01"},
+			"minProducts":      {"3"},
+			"sort":             {"lastReception"},
+			"order":            {"asc"},
+		}
+		req := httptest.NewRequest(http.MethodGet, "/pvz?"+query.Encode(), nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.GetAll(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "Asia/Yekaterinburg", response["tz"])
+		assert.Equal(t, "lastReception", response["sort"])
+	})
+
+	t.Run("malformed date", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pvz?from=01.05.2025", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.GetAll(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		mockService.On("GetAll", mock.Anything, 0, models.PVZFilter{Sort: "id"}).
+			Return([]models.FullPVZ(nil), fmt.Errorf("%w: bad sort", apperrors.ErrInvalidInput))
+
+		req := httptest.NewRequest(http.MethodGet, "/pvz?sort=id", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.GetAll(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+		assert.Empty(t, rec.Body.String(), "после ошибки ответ со списком не пишется")
+	})
+
 	// Test case 2: Error from service
 	t.Run("service error", func(t *testing.T) {
-		mockService.On("GetAll", mock.Anything, "", "", "", "").
+		mockService.On("GetAll", mock.Anything, 0, models.PVZFilter{}).
 			Return([]models.FullPVZ{}, assert.AnError)
 
 		req := httptest.NewRequest(http.MethodGet, "/pvz", nil)
@@ -106,8 +193,7 @@ func TestPVZHandler_GetAll(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		err := handler.GetAll(c)
-		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusInternalServerError, responseCode(rec, err))
 	})
 }
 
@@ -118,7 +204,7 @@ func TestPVZHandler_Create(t *testing.T) {
 	t.Run("successful creation", func(t *testing.T) {
 		pvz := models.PVZ{
 			ID:               "1",
-			RegistrationDate: time.Now(),
+			RegistrationDate: time.Now().UTC().Truncate(time.Second),
 			City:             "Moscow",
 		}
 		mockService.On("CreatePVZ", mock.Anything, pvz).
@@ -143,8 +229,57 @@ func TestPVZHandler_Create(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		err := handler.Create(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+}
+
+func TestPVZHandler_Nearby(t *testing.T) {
+	e, mockService, handler := setupEcho()
+
+	t.Run("successful search", func(t *testing.T) {
+		lat, lon := 55.7601, 37.6186
+		expected := []models.NearbyPVZ{
+			{PVZ: models.PVZ{ID: "1", City: "москва", Address: "Тверская, 1", Latitude: &lat, Longitude: &lon}, Distance: 870.5},
+		}
+		mockService.On("Nearby", mock.Anything, models.NearbyQuery{Lat: 55.7539, Lon: 37.6208, Radius: 2000}).
+			Return(expected, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/pvz/nearby?lat=55.7539&lon=37.6208&radius=2000", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Nearby(c)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response []map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		if assert.Len(t, response, 1) {
+			assert.Equal(t, "1", response[0]["id"])
+			assert.Equal(t, "Тверская, 1", response[0]["address"])
+			assert.Equal(t, 870.5, response[0]["distance"])
+		}
+	})
+
+	t.Run("missing coordinates", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pvz/nearby?lat=55.75", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Nearby(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+
+	t.Run("invalid radius", func(t *testing.T) {
+		mockService.On("Nearby", mock.Anything, models.NearbyQuery{Lat: 55.75, Lon: 37.62, Radius: 100000}).
+			Return([]models.NearbyPVZ(nil), apperrors.ErrInvalidInput)
+
+		req := httptest.NewRequest(http.MethodGet, "/pvz/nearby?lat=55.75&lon=37.62&radius=100000", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Nearby(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
 	})
 }
 
@@ -184,8 +319,7 @@ func TestPVZHandler_GetByID(t *testing.T) {
 		c.SetParamValues("2")
 
 		err := handler.GetByID(c)
-		assert.NoError(t, err)
-		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, http.StatusNotFound, responseCode(rec, err))
 	})
 }
 
@@ -208,6 +342,20 @@ func TestPVZHandler_DeleteLastProduct(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 	})
 
+	t.Run("last product is no longer accepted", func(t *testing.T) {
+		mockService.On("DeleteLastProduct", mock.Anything, "3").
+			Return(apperrors.ErrProductStatus)
+
+		req := httptest.NewRequest(http.MethodPost, "/pvz/3/delete_last_product", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("3")
+
+		err := handler.DeleteLastProduct(c)
+		assert.Equal(t, http.StatusConflict, responseCode(rec, err))
+	})
+
 	// Test case 2: PVZ not found
 	t.Run("pvz not found", func(t *testing.T) {
 		mockService.On("DeleteLastProduct", mock.Anything, "2").
@@ -220,8 +368,7 @@ func TestPVZHandler_DeleteLastProduct(t *testing.T) {
 		c.SetParamValues("2")
 
 		err := handler.DeleteLastProduct(c)
-		assert.NoError(t, err)
-		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, http.StatusNotFound, responseCode(rec, err))
 	})
 }
 
@@ -256,7 +403,96 @@ func TestPVZHandler_CloseLastReception(t *testing.T) {
 		c.SetParamValues("2")
 
 		err := handler.CloseLastReception(c)
+		assert.Equal(t, http.StatusNotFound, responseCode(rec, err))
+	})
+}
+
+func TestPVZHandler_Import(t *testing.T) {
+	e, mockService, handler := setupEcho()
+
+	csvBody := "city\nМосква\nКазань\n"
+
+	t.Run("dry run", func(t *testing.T) {
+		mockService.On("ImportPVZ", mock.Anything, csvBody, true).
+			Return(models.PVZImportResult{DryRun: true, Total: 2, Valid: 2}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/pvz/import?dry_run=true", strings.NewReader(csvBody))
+		req.Header.Set(echo.HeaderContentType, "text/csv")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Import(c)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response models.PVZImportResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.True(t, response.DryRun)
+		assert.Equal(t, 2, response.Valid)
+	})
+
+	t.Run("multipart commit", func(t *testing.T) {
+		mockService.On("ImportPVZ", mock.Anything, csvBody, false).
+			Return(models.PVZImportResult{Total: 2, Valid: 2, Created: 2}, nil).Once()
+
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "pvz.csv")
+		part.Write([]byte(csvBody))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/pvz/import", &buf)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Import(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+
+	t.Run("row errors", func(t *testing.T) {
+		badBody := "city\nЛондон\n"
+		mockService.On("ImportPVZ", mock.Anything, badBody, false).
+			Return(models.PVZImportResult{
+				Total:  1,
+				Errors: []models.PVZImportRowError{{Row: 2, Column: "city", Message: apperrors.ErrCityNotAllowed.Error()}},
+			}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/pvz/import", strings.NewReader(badBody))
+		req.Header.Set(echo.HeaderContentType, "text/csv")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Import(c)
+		assert.Equal(t, http.StatusUnprocessableEntity, responseCode(rec, err))
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "pvz.csv")
+		part.Write([]byte("city\n"))
+		part.Write(bytes.Repeat([]byte("Москва\n"), maxImportBytes/len("Москва\n")+1))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/pvz/import", &buf)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Import(c)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, responseCode(rec, err))
+	})
+
+	t.Run("invalid dry_run", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pvz/import?dry_run=maybe", strings.NewReader(csvBody))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Import(c)
+		var httpErr *echo.HTTPError
+		assert.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
 	})
 }
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
 	"pvz-service/internal/services"
 
 	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
 )
 
 type ReceptionHandler struct {
@@ -18,7 +20,8 @@ func NewReceptionHandler(services *services.Services) *ReceptionHandler {
 }
 
 // @Summary Создание новой приемки товаров
-// @Description Создание новой приемки товаров (только для сотрудников ПВЗ)
+// @Description Создание новой приемки товаров (сотрудники ПВЗ и модераторы). В нерабочее время ПВЗ
+// @Description приемку может открыть только модератор с флагом override.
 // @Tags Reception
 // @Security bearerAuth
 // @Accept json
@@ -27,19 +30,26 @@ func NewReceptionHandler(services *services.Services) *ReceptionHandler {
 // @Success 201 {object} models.Reception
 // @Failure 400 {object} map[string]string
 // @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /receptions [post]
 func (h *ReceptionHandler) Create(c echo.Context) error {
 	var req struct {
-		PvzId string `json:"pvzId"`
+		PvzId    string `json:"pvzId"`
+		Override bool   `json:"override"`
 	}
 	if err := c.Bind(&req); err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
 	}
+	withPVZ(c, req.PvzId)
+
+	if role, _ := c.Get("role").(string); req.Override && role != "moderator" {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"message": "only moderators can override the PVZ schedule"})
+	}
 
 	active, err := h.services.ReceptionService.GetActiveReceptionByPVZID(c.Request().Context(), req.PvzId)
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "Неверный запрос или есть незакрытая приемка"})
 	}
 
@@ -52,11 +62,173 @@ func (h *ReceptionHandler) Create(c echo.Context) error {
 		Status: "in_progress",
 	}
 
-	err = h.services.ReceptionService.CreateReception(c.Request().Context(), Reception)
+	created, err := h.services.ReceptionService.CreateReception(c.Request().Context(), Reception, req.Override)
+	if errors.Is(err, apperrors.ErrPVZClosed) {
+		return echo.NewHTTPError(http.StatusConflict, echo.Map{"message": "PVZ is closed"})
+	}
+	if errors.Is(err, apperrors.ErrInvalidInput) {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "there is an active Reception for this PVZ"})
+	}
 	if err != nil {
-		logrus.Error(err)
+		requestLogger(c).Error(err)
 		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not create Reception"})
 	}
 
-	return c.JSON(http.StatusCreated, echo.Map{"message": "created"})
+	return c.JSON(http.StatusCreated, created)
+}
+
+// @Summary Приемка с товарами
+// @Description Время приемки и товаров - RFC 3339 со смещением пояса ПВЗ
+// @Tags Reception
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "Reception ID"
+// @Success 200 {object} models.FullReception
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /receptions/{id} [get]
+func (h *ReceptionHandler) Get(c echo.Context) error {
+	reception, err := h.services.ReceptionService.GetReception(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return receptionError(c, err, "could not load reception")
+	}
+	withPVZ(c, reception.PvzId)
+
+	return c.JSON(http.StatusOK, reception)
+}
+
+// @Summary Товары приемки
+// @Description Товары приемки, последние добавленные первыми
+// @Tags Reception
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "Reception ID"
+// @Param page query int false "Номер страницы"
+// @Param limit query int false "Размер страницы, по умолчанию 20, не больше 100"
+// @Success 200 {array} models.Product
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /receptions/{id}/products [get]
+func (h *ReceptionHandler) ListProducts(c echo.Context) error {
+	page, limit, offset, err := receptionPage(c)
+	if err != nil {
+		return receptionError(c, err, "could not load products")
+	}
+
+	products, err := h.services.ReceptionService.ListProducts(c.Request().Context(), c.Param("id"), limit, offset)
+	if err != nil {
+		return receptionError(c, err, "could not load products")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"data":  products,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// @Summary Приемки ПВЗ
+// @Description Приемки ПВЗ, новые первыми. from и to - местные календарные дни в поясе ПВЗ или в поясе tz.
+// @Tags Reception
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "PVZ ID"
+// @Param status query string false "Статус" Enums(in_progress, close)
+// @Param from query string false "Первый день, 2006-01-02"
+// @Param to query string false "Последний день включительно, 2006-01-02"
+// @Param tz query string false "Пояс IANA для границ дней и времени в ответе"
+// @Param page query int false "Номер страницы"
+// @Param limit query int false "Размер страницы, по умолчанию 20, не больше 100"
+// @Success 200 {array} models.Reception
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /pvz/{id}/receptions [get]
+func (h *ReceptionHandler) ListByPVZ(c echo.Context) error {
+	id := c.Param("id")
+	withPVZ(c, id)
+	page, limit, offset, err := receptionPage(c)
+	if err != nil {
+		return receptionError(c, err, "could not load receptions")
+	}
+
+	filter := models.ReceptionFilter{
+		PvzID:  id,
+		Status: c.QueryParam("status"),
+		Limit:  limit,
+		Offset: offset,
+	}
+	err = echo.QueryParamsBinder(c).
+		Time("from", &filter.Period.From, models.DateLayout).
+		Time("to", &filter.Period.To, models.DateLayout).
+		String("tz", &filter.Period.TimeZone).
+		BindError()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "from and to must be dates 2006-01-02"})
+	}
+
+	receptions, err := h.services.ReceptionService.ListReceptions(c.Request().Context(), filter)
+	if err != nil {
+		return receptionError(c, err, "could not load receptions")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"data":  receptions,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// @Summary Открытая приемка ПВЗ
+// @Description Открытая приемка ПВЗ с товарами, 404 - если открытой приемки нет
+// @Tags Reception
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "PVZ ID"
+// @Success 200 {object} models.FullReception
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /pvz/{id}/receptions/active [get]
+func (h *ReceptionHandler) GetActive(c echo.Context) error {
+	id := c.Param("id")
+	withPVZ(c, id)
+
+	reception, err := h.services.ReceptionService.GetActiveReception(c.Request().Context(), id)
+	if err != nil {
+		return receptionError(c, err, "could not load reception")
+	}
+
+	return c.JSON(http.StatusOK, reception)
+}
+
+// receptionPage читает page и limit: по умолчанию первая страница из 20, не больше 100.
+// Нечисловые, отрицательные и слишком большие значения - ErrInvalidInput.
+func receptionPage(c echo.Context) (page, limit, offset int, err error) {
+	err = echo.QueryParamsBinder(c).
+		Int("page", &page).
+		Int("limit", &limit).
+		BindError()
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("%w: page и limit должны быть числами", apperrors.ErrInvalidInput)
+	}
+
+	limit, offset, err = services.PageOffset(page, limit, 20)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return max(page, 1), limit, offset, nil
+}
+
+func receptionError(c echo.Context, err error, failure string) error {
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidInput):
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	case errors.Is(err, apperrors.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "not found"})
+	default:
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": failure})
+	}
 }
@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
 	"pvz-service/internal/services"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -27,16 +29,41 @@ func (m *MockReceptionService) GetActiveReceptionByPVZID(ctx context.Context, pv
 	return args.Get(0).(*models.Reception), args.Error(1)
 }
 
-func (m *MockReceptionService) CreateReception(ctx context.Context, reception models.Reception) error {
-	args := m.Called(ctx, reception)
-	return args.Error(0)
+func (m *MockReceptionService) CreateReception(ctx context.Context, reception models.Reception, override bool) (models.Reception, error) {
+	args := m.Called(ctx, reception, override)
+	return args.Get(0).(models.Reception), args.Error(1)
+}
+
+func (m *MockReceptionService) ProcessStaleReceptions(ctx context.Context, idleFor time.Duration, action string) (int, error) {
+	args := m.Called(ctx, idleFor, action)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockReceptionService) GetReception(ctx context.Context, id string) (models.FullReception, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.FullReception), args.Error(1)
+}
+
+func (m *MockReceptionService) GetActiveReception(ctx context.Context, pvzID string) (models.FullReception, error) {
+	args := m.Called(ctx, pvzID)
+	return args.Get(0).(models.FullReception), args.Error(1)
+}
+
+func (m *MockReceptionService) ListReceptions(ctx context.Context, filter models.ReceptionFilter) ([]models.Reception, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.Reception), args.Error(1)
+}
+
+func (m *MockReceptionService) ListProducts(ctx context.Context, receptionID string, limit, offset int) ([]models.Product, error) {
+	args := m.Called(ctx, receptionID, limit, offset)
+	return args.Get(0).([]models.Product), args.Error(1)
 }
 
 func setupReceptionEcho() (*echo.Echo, *MockReceptionService, *ReceptionHandler) {
 	e := echo.New()
 	mockService := new(MockReceptionService)
 	s := &services.Services{
-		ReceptionService: &services.ReceptionService{},
+		ReceptionService: mockService,
 	}
 	handler := NewReceptionHandler(s)
 	return e, mockService, handler
@@ -52,9 +79,10 @@ func TestReceptionHandler_Create(t *testing.T) {
 		reqJSON, _ := json.Marshal(reqBody)
 
 		mockService.On("GetActiveReceptionByPVZID", mock.Anything, "1").
-			Return(nil, nil)
-		mockService.On("CreateReception", mock.Anything, mock.Anything).
-			Return(nil)
+			Return(nil, nil).Once()
+		created := time.Date(2025, 4, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+		mockService.On("CreateReception", mock.Anything, mock.Anything, false).
+			Return(models.Reception{ID: "r1", PvzId: "1", Status: "in_progress", DateTime: created}, nil).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/receptions", strings.NewReader(string(reqJSON)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -68,7 +96,8 @@ func TestReceptionHandler_Create(t *testing.T) {
 		var response map[string]string
 		err = json.Unmarshal(rec.Body.Bytes(), &response)
 		assert.NoError(t, err)
-		assert.Equal(t, "created", response["message"])
+		assert.Equal(t, "r1", response["id"])
+		assert.Equal(t, "2025-04-01T12:00:00+03:00", response["DateTime"], "время во времени ПВЗ")
 	})
 
 	t.Run("invalid request body", func(t *testing.T) {
@@ -78,8 +107,7 @@ func TestReceptionHandler_Create(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		err := handler.Create(c)
-		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
 	})
 
 	t.Run("active reception exists", func(t *testing.T) {
@@ -93,16 +121,59 @@ func TestReceptionHandler_Create(t *testing.T) {
 			Status: "in_progress",
 		}
 		mockService.On("GetActiveReceptionByPVZID", mock.Anything, "1").
-			Return(activeReception, nil)
+			Return(activeReception, nil).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/receptions", strings.NewReader(string(reqJSON)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
+		err := handler.Create(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+
+	t.Run("pvz is closed", func(t *testing.T) {
+		mockService.On("GetActiveReceptionByPVZID", mock.Anything, "2").
+			Return(nil, nil).Once()
+		mockService.On("CreateReception", mock.Anything, mock.Anything, false).
+			Return(models.Reception{}, apperrors.ErrPVZClosed).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/receptions", strings.NewReader(`{"pvzId":"2"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("role", "client")
+
+		err := handler.Create(c)
+		assert.Equal(t, http.StatusConflict, responseCode(rec, err))
+	})
+
+	t.Run("override by employee is forbidden", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/receptions", strings.NewReader(`{"pvzId":"2","override":true}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("role", "client")
+
+		err := handler.Create(c)
+		assert.Equal(t, http.StatusForbidden, responseCode(rec, err))
+	})
+
+	t.Run("override by moderator", func(t *testing.T) {
+		mockService.On("GetActiveReceptionByPVZID", mock.Anything, "2").
+			Return(nil, nil).Once()
+		mockService.On("CreateReception", mock.Anything, mock.Anything, true).
+			Return(models.Reception{ID: "r2"}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/receptions", strings.NewReader(`{"pvzId":"2","override":true}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("role", "moderator")
+
 		err := handler.Create(c)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusCreated, rec.Code)
 	})
 
 	t.Run("service error", func(t *testing.T) {
@@ -120,7 +191,184 @@ func TestReceptionHandler_Create(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		err := handler.Create(c)
+		assert.Equal(t, http.StatusInternalServerError, responseCode(rec, err))
+	})
+}
+
+func TestReceptionHandler_Get(t *testing.T) {
+	e, mockService, handler := setupReceptionEcho()
+
+	t.Run("with products", func(t *testing.T) {
+		reception := models.FullReception{
+			ID:       "r1",
+			PvzId:    "1",
+			Status:   models.ReceptionInProgress,
+			Products: []models.Product{{ID: "p1", Type: "обувь"}},
+		}
+		mockService.On("GetReception", mock.Anything, "r1").Return(reception, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/receptions/r1", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("r1")
+
+		err := handler.Get(c)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response models.FullReception
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, reception, response)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService.On("GetReception", mock.Anything, "missing").
+			Return(models.FullReception{}, apperrors.ErrNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/receptions/missing", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("missing")
+
+		err := handler.Get(c)
+		assert.Equal(t, http.StatusNotFound, responseCode(rec, err))
+	})
+}
+
+func TestReceptionHandler_ListByPVZ(t *testing.T) {
+	e, mockService, handler := setupReceptionEcho()
+
+	t.Run("filters and pagination", func(t *testing.T) {
+		day := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+		filter := models.ReceptionFilter{
+			PvzID:  "1",
+			Status: models.ReceptionClosed,
+			Period: models.DayPeriod{From: day, To: day, TimeZone: "Asia/Omsk"},
+			Limit:  5,
+			Offset: 5,
+		}
+		mockService.On("ListReceptions", mock.Anything, filter).
+			Return([]models.Reception{{ID: "r1", PvzId: "1"}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/pvz/1/receptions?status=close&from=2025-05-01&to=2025-05-01&tz=Asia/Omsk&page=2&limit=5", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		err := handler.ListByPVZ(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, float64(2), response["page"])
+		assert.Len(t, response["data"], 1)
+	})
+
+	t.Run("malformed date", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pvz/1/receptions?from=yesterday", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		err := handler.ListByPVZ(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		mockService.On("ListReceptions", mock.Anything, models.ReceptionFilter{PvzID: "1", Status: "open", Limit: 20}).
+			Return([]models.Reception(nil), apperrors.ErrInvalidInput).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/pvz/1/receptions?status=open", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		err := handler.ListByPVZ(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+}
+
+func TestReceptionHandler_GetActive(t *testing.T) {
+	e, mockService, handler := setupReceptionEcho()
+
+	t.Run("no active reception", func(t *testing.T) {
+		mockService.On("GetActiveReception", mock.Anything, "1").
+			Return(models.FullReception{}, apperrors.ErrNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/pvz/1/receptions/active", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		err := handler.GetActive(c)
+		assert.Equal(t, http.StatusNotFound, responseCode(rec, err))
+	})
+
+	t.Run("active reception", func(t *testing.T) {
+		mockService.On("GetActiveReception", mock.Anything, "2").
+			Return(models.FullReception{ID: "r2", PvzId: "2", Status: models.ReceptionInProgress}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/pvz/2/receptions/active", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("2")
+
+		err := handler.GetActive(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestReceptionHandler_ListProducts(t *testing.T) {
+	e, mockService, handler := setupReceptionEcho()
+
+	t.Run("limit is capped", func(t *testing.T) {
+		mockService.On("ListProducts", mock.Anything, "r1", 100, 0).
+			Return([]models.Product{{ID: "p1"}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/receptions/r1/products?limit=1000", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("r1")
+
+		err := handler.ListProducts(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	for _, query := range []string{"page=abc", "limit=-1", "page=9223372036854775807&limit=100"} {
+		t.Run("invalid page "+query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/receptions/r1/products?"+query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("r1")
+
+			err := handler.ListProducts(c)
+			assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+		})
+	}
+
+	t.Run("reception not found", func(t *testing.T) {
+		mockService.On("ListProducts", mock.Anything, "missing", 20, 0).
+			Return([]models.Product(nil), apperrors.ErrNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/receptions/missing/products", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("missing")
+
+		err := handler.ListProducts(c)
+		assert.Equal(t, http.StatusNotFound, responseCode(rec, err))
 	})
 }
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
+	"pvz-service/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ScheduleHandler struct {
+	services *services.Services
+}
+
+func NewScheduleHandler(services *services.Services) *ScheduleHandler {
+	return &ScheduleHandler{services: services}
+}
+
+// @Summary Расписание ПВЗ
+// @Description Часовой пояс, недельные часы работы, актуальные исключения и открыт ли ПВЗ сейчас
+// @Tags pvz
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "PVZ ID"
+// @Success 200 {object} models.PVZSchedule
+// @Failure 404 {object} map[string]string
+// @Router /pvz/{id}/schedule [get]
+func (h *ScheduleHandler) Get(c echo.Context) error {
+	id := c.Param("id")
+	withPVZ(c, id)
+
+	schedule, err := h.services.ScheduleService.GetSchedule(c.Request().Context(), id)
+	if err != nil {
+		return scheduleError(c, err, "could not load schedule")
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// @Summary Изменение расписания ПВЗ
+// @Description Заменяет недельные часы работы (только для модераторов). Пустой список - ПВЗ открыт всегда.
+// @Description День недели от 1 (понедельник) до 7 (воскресенье), время местное 15:04, закрытие до 24:00.
+// @Tags pvz
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "PVZ ID"
+// @Param request body models.PVZSchedule true "timeZone (необязательно) и week"
+// @Success 200 {object} models.PVZSchedule
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /pvz/{id}/schedule [put]
+func (h *ScheduleHandler) Update(c echo.Context) error {
+	id := c.Param("id")
+	withPVZ(c, id)
+
+	var req struct {
+		TimeZone string                `json:"timeZone"`
+		Week     []models.WorkingHours `json:"week"`
+	}
+	if err := c.Bind(&req); err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	schedule, err := h.services.ScheduleService.UpdateSchedule(c.Request().Context(), id, models.PVZSchedule{TimeZone: req.TimeZone, Week: req.Week})
+	if err != nil {
+		return scheduleError(c, err, "could not update schedule")
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// @Summary Исключение из расписания ПВЗ
+// @Description Праздник или временное закрытие (без opens/closes) либо особые часы работы на даты
+// @Description с startDate по endDate включительно (только для модераторов)
+// @Tags pvz
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "PVZ ID"
+// @Param request body models.ScheduleException true "Exception"
+// @Success 201 {object} models.ScheduleException
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /pvz/{id}/schedule/exceptions [post]
+func (h *ScheduleHandler) AddException(c echo.Context) error {
+	id := c.Param("id")
+	withPVZ(c, id)
+
+	var e models.ScheduleException
+	if err := c.Bind(&e); err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+	e.ID = ""
+	e.PvzID = id
+
+	created, err := h.services.ScheduleService.AddException(c.Request().Context(), e)
+	if err != nil {
+		return scheduleError(c, err, "could not add exception")
+	}
+
+	return c.JSON(http.StatusCreated, created)
+}
+
+// @Summary Удаление исключения из расписания ПВЗ
+// @Description Только для модераторов
+// @Tags pvz
+// @Security bearerAuth
+// @Param id path string true "PVZ ID"
+// @Param exceptionId path string true "Exception ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /pvz/{id}/schedule/exceptions/{exceptionId} [delete]
+func (h *ScheduleHandler) DeleteException(c echo.Context) error {
+	id := c.Param("id")
+	withPVZ(c, id)
+
+	if err := h.services.ScheduleService.DeleteException(c.Request().Context(), id, c.Param("exceptionId")); err != nil {
+		return scheduleError(c, err, "could not delete exception")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func scheduleError(c echo.Context, err error, failure string) error {
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidInput):
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	case errors.Is(err, apperrors.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "not found"})
+	default:
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": failure})
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz-service/internal/models"
+	apperrors "pvz-service/internal/pkg/errors"
+	"pvz-service/internal/services"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockScheduleService struct {
+	mock.Mock
+}
+
+func (m *MockScheduleService) GetSchedule(ctx context.Context, pvzID string) (models.PVZSchedule, error) {
+	args := m.Called(ctx, pvzID)
+	return args.Get(0).(models.PVZSchedule), args.Error(1)
+}
+
+func (m *MockScheduleService) UpdateSchedule(ctx context.Context, pvzID string, update models.PVZSchedule) (models.PVZSchedule, error) {
+	args := m.Called(ctx, pvzID, update)
+	return args.Get(0).(models.PVZSchedule), args.Error(1)
+}
+
+func (m *MockScheduleService) AddException(ctx context.Context, e models.ScheduleException) (models.ScheduleException, error) {
+	args := m.Called(ctx, e)
+	return args.Get(0).(models.ScheduleException), args.Error(1)
+}
+
+func (m *MockScheduleService) DeleteException(ctx context.Context, pvzID, id string) error {
+	args := m.Called(ctx, pvzID, id)
+	return args.Error(0)
+}
+
+func setupScheduleEcho() (*echo.Echo, *MockScheduleService, *ScheduleHandler) {
+	e := echo.New()
+	mockService := new(MockScheduleService)
+	handler := NewScheduleHandler(&services.Services{ScheduleService: mockService})
+	return e, mockService, handler
+}
+
+func newScheduleContext(e *echo.Echo, method, body string, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames(params[:len(params)/2]...)
+	c.SetParamValues(params[len(params)/2:]...)
+	return c, rec
+}
+
+func TestScheduleHandler_Get(t *testing.T) {
+	e, mockService, handler := setupScheduleEcho()
+
+	mockService.On("GetSchedule", mock.Anything, "1").Return(models.PVZSchedule{
+		PvzID:      "1",
+		TimeZone:   "Europe/Moscow",
+		Week:       []models.WorkingHours{{Weekday: 1, Opens: "09:00", Closes: "21:00"}},
+		Exceptions: []models.ScheduleException{},
+		IsOpenNow:  true,
+	}, nil)
+	mockService.On("GetSchedule", mock.Anything, "2").Return(models.PVZSchedule{}, apperrors.ErrNotFound)
+
+	c, rec := newScheduleContext(e, http.MethodGet, "", "id", "1")
+	assert.NoError(t, handler.Get(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, true, response["isOpenNow"])
+	assert.Equal(t, "Europe/Moscow", response["timeZone"])
+
+	c, rec = newScheduleContext(e, http.MethodGet, "", "id", "2")
+	assert.Equal(t, http.StatusNotFound, responseCode(rec, handler.Get(c)))
+}
+
+func TestScheduleHandler_Update(t *testing.T) {
+	e, mockService, handler := setupScheduleEcho()
+
+	week := []models.WorkingHours{{Weekday: 1, Opens: "09:00", Closes: "21:00"}}
+	mockService.On("UpdateSchedule", mock.Anything, "1", models.PVZSchedule{TimeZone: "Europe/Samara", Week: week}).
+		Return(models.PVZSchedule{PvzID: "1", TimeZone: "Europe/Samara", Week: week}, nil)
+	mockService.On("UpdateSchedule", mock.Anything, "1", models.PVZSchedule{Week: []models.WorkingHours{{Weekday: 8, Opens: "09:00", Closes: "21:00"}}}).
+		Return(models.PVZSchedule{}, apperrors.ErrInvalidInput)
+
+	c, rec := newScheduleContext(e, http.MethodPut, `{"timeZone":"Europe/Samara","week":[{"weekday":1,"opens":"09:00","closes":"21:00"}]}`, "id", "1")
+	assert.NoError(t, handler.Update(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	c, rec = newScheduleContext(e, http.MethodPut, `{"week":[{"weekday":8,"opens":"09:00","closes":"21:00"}]}`, "id", "1")
+	assert.Equal(t, http.StatusBadRequest, responseCode(rec, handler.Update(c)))
+
+	c, rec = newScheduleContext(e, http.MethodPut, `invalid`, "id", "1")
+	assert.Equal(t, http.StatusBadRequest, responseCode(rec, handler.Update(c)))
+}
+
+func TestScheduleHandler_Exceptions(t *testing.T) {
+	e, mockService, handler := setupScheduleEcho()
+
+	holiday := models.ScheduleException{PvzID: "1", StartDate: "2030-01-01", EndDate: "2030-01-08", Reason: "Новый год"}
+	created := holiday
+	created.ID = "e1"
+	mockService.On("AddException", mock.Anything, holiday).Return(created, nil)
+	mockService.On("DeleteException", mock.Anything, "1", "e1").Return(nil)
+	mockService.On("DeleteException", mock.Anything, "1", "missing").Return(apperrors.ErrNotFound)
+
+	// id и ПВЗ из тела запроса игнорируются
+	c, rec := newScheduleContext(e, http.MethodPost, `{"id":"x","pvzId":"2","startDate":"2030-01-01","endDate":"2030-01-08","reason":"Новый год"}`, "id", "1")
+	assert.NoError(t, handler.AddException(c))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	c, rec = newScheduleContext(e, http.MethodDelete, "", "id", "exceptionId", "1", "e1")
+	assert.NoError(t, handler.DeleteException(c))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	c, rec = newScheduleContext(e, http.MethodDelete, "", "id", "exceptionId", "1", "missing")
+	assert.Equal(t, http.StatusNotFound, responseCode(rec, handler.DeleteException(c)))
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	apperrors "pvz-service/internal/pkg/errors"
+	"pvz-service/internal/services"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type UserHandler struct {
+	services *services.Services
+}
+
+func NewUserHandler(services *services.Services) *UserHandler {
+	return &UserHandler{services: services}
+}
+
+// @Summary Разблокировка учетной записи
+// @Description Снимает блокировку после серии неудачных входов и обнуляет счетчик попыток (только для модераторов)
+// @Tags users
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/unlock [post]
+func (h *UserHandler) Unlock(c echo.Context) error {
+	err := h.services.UserService.UnlockUser(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "user not found"})
+	}
+	if err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not unlock user"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "unlocked"})
+}
+
+// @Summary Список пользователей
+// @Description Поиск пользователей по email, роли и состоянию учетной записи (только для модераторов)
+// @Tags users
+// @Security bearerAuth
+// @Produce json
+// @Param q query string false "Подстрока email"
+// @Param role query string false "Роль: client, moderator, customer"
+// @Param status query string false "Состояние: active или deactivated"
+// @Param page query int false "Номер страницы"
+// @Param limit query int false "Размер страницы"
+// @Success 200 {array} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /users [get]
+func (h *UserHandler) List(c echo.Context) error {
+	page := 1
+	if p, err := strconv.Atoi(c.QueryParam("page")); err == nil && p > 0 {
+		page = p
+	}
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	users, err := h.services.UserService.ListUsers(c.Request().Context(), models.UserFilter{
+		Query:  c.QueryParam("q"),
+		Role:   c.QueryParam("role"),
+		Status: c.QueryParam("status"),
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+	if errors.Is(err, apperrors.ErrInvalidInput) {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid role or status"})
+	}
+	if err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not load users"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"data":  users,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// @Summary Пользователь
+// @Description Карточка пользователя (только для модераторов)
+// @Tags users
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Success 200 {object} models.User
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id} [get]
+func (h *UserHandler) Get(c echo.Context) error {
+	user, err := h.services.UserService.GetUser(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "user not found"})
+	}
+	if err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not load user"})
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+type changeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// @Summary Смена роли
+// @Description Меняет роль пользователя. Свою роль изменить нельзя (только для модераторов)
+// @Tags users
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Param request body changeRoleRequest true "Новая роль"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/role [patch]
+func (h *UserHandler) ChangeRole(c echo.Context) error {
+	var req changeRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	user, err := h.services.UserService.ChangeRole(c.Request().Context(), c.Param("id"), req.Role)
+	return h.userResult(c, user, err, "could not change role")
+}
+
+// @Summary Отключение учетной записи
+// @Description Запрещает вход, уже выданные токены перестают действовать. Себя отключить нельзя (только для модераторов)
+// @Tags users
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/deactivate [post]
+func (h *UserHandler) Deactivate(c echo.Context) error {
+	user, err := h.services.UserService.DeactivateUser(c.Request().Context(), c.Param("id"))
+	return h.userResult(c, user, err, "could not deactivate user")
+}
+
+// @Summary Включение учетной записи
+// @Description Снова разрешает вход отключенному пользователю (только для модераторов)
+// @Tags users
+// @Security bearerAuth
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/reactivate [post]
+func (h *UserHandler) Reactivate(c echo.Context) error {
+	user, err := h.services.UserService.ReactivateUser(c.Request().Context(), c.Param("id"))
+	return h.userResult(c, user, err, "could not reactivate user")
+}
+
+func (h *UserHandler) userResult(c echo.Context, user models.User, err error, failure string) error {
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidInput):
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid role"})
+	case errors.Is(err, apperrors.ErrSelfAction):
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "cannot modify your own account"})
+	case errors.Is(err, apperrors.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"message": "user not found"})
+	case err != nil:
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": failure})
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// @Summary Мой профиль
+// @Description Профиль текущего пользователя. Недоступен для тестовых токенов /dummyLogin
+// @Tags users
+// @Security bearerAuth
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 403 {object} map[string]string
+// @Router /me [get]
+func (h *UserHandler) Me(c echo.Context) error {
+	id := actor.FromContext(c.Request().Context()).UserID
+	if id == "" {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"message": "profile is available only for registered users"})
+	}
+
+	user, err := h.services.UserService.GetUser(c.Request().Context(), id)
+	if err != nil {
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not load profile"})
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+type updateProfileRequest struct {
+	Email           string `json:"email"`
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// @Summary Изменение профиля
+// @Description Смена email и/или пароля текущего пользователя. Требует текущий пароль, новый email нужно подтвердить по ссылке из письма. После смены пароля ранее выданные токены, включая текущий, перестают действовать
+// @Tags users
+// @Security bearerAuth
+// @Accept json
+// @Produce json
+// @Param request body updateProfileRequest true "Изменения профиля"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /me [patch]
+func (h *UserHandler) UpdateMe(c echo.Context) error {
+	id := actor.FromContext(c.Request().Context()).UserID
+	if id == "" {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"message": "profile is available only for registered users"})
+	}
+
+	var req updateProfileRequest
+	if err := c.Bind(&req); err != nil || req.CurrentPassword == "" || (req.Email == "" && req.NewPassword == "") {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "invalid body"})
+	}
+
+	update := models.ProfileUpdate{Email: req.Email, CurrentPassword: req.CurrentPassword}
+	if req.NewPassword != "" {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			requestLogger(c).Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "encryption error"})
+		}
+		update.NewPasswordHash = string(hashed)
+	}
+
+	user, err := h.services.UserService.UpdateProfile(c.Request().Context(), id, update)
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidCredentials):
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"message": "current password is incorrect"})
+	case errors.Is(err, apperrors.ErrAlreadyExists):
+		return echo.NewHTTPError(http.StatusConflict, echo.Map{"message": "email is already taken"})
+	case err != nil:
+		requestLogger(c).Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "could not update profile"})
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
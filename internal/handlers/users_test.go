@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	apperrors "pvz-service/internal/pkg/errors"
+	"pvz-service/internal/services"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupUserEcho() (*echo.Echo, *MockUserService, *UserHandler) {
+	e := echo.New()
+	mockService := new(MockUserService)
+	s := &services.Services{UserService: mockService}
+	handler := NewUserHandler(s)
+	return e, mockService, handler
+}
+
+func TestUserHandler_Unlock(t *testing.T) {
+	e, mockService, handler := setupUserEcho()
+
+	tests := []struct {
+		name    string
+		err     error
+		expCode int
+	}{
+		{"unlocked", nil, http.StatusOK},
+		{"not found", apperrors.ErrNotFound, http.StatusNotFound},
+		{"service error", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService.On("UnlockUser", mock.Anything, "u1").Return(tt.err).Once()
+
+			req := httptest.NewRequest(http.MethodPost, "/users/u1/unlock", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("u1")
+
+			err := handler.Unlock(c)
+			assert.Equal(t, tt.expCode, responseCode(rec, err))
+		})
+	}
+}
+
+func TestUserHandler_List(t *testing.T) {
+	e, mockService, handler := setupUserEcho()
+
+	t.Run("filters and paging", func(t *testing.T) {
+		filter := models.UserFilter{Query: "ivan", Role: models.RoleEmployee, Status: models.UserStatusActive, Limit: 10, Offset: 10}
+		mockService.On("ListUsers", mock.Anything, filter).Return([]models.User{{ID: "u1"}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/users?q=ivan&role=client&status=active&page=2&limit=10", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.List(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		mockService.On("ListUsers", mock.Anything, mock.Anything).Return([]models.User(nil), apperrors.ErrInvalidInput).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/users?status=gone", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.List(c)
+		assert.Equal(t, http.StatusBadRequest, responseCode(rec, err))
+	})
+}
+
+func TestUserHandler_ChangeRole(t *testing.T) {
+	e, mockService, handler := setupUserEcho()
+
+	tests := []struct {
+		name    string
+		err     error
+		expCode int
+	}{
+		{"changed", nil, http.StatusOK},
+		{"invalid role", apperrors.ErrInvalidInput, http.StatusBadRequest},
+		{"own account", apperrors.ErrSelfAction, http.StatusBadRequest},
+		{"not found", apperrors.ErrNotFound, http.StatusNotFound},
+		{"service error", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService.On("ChangeRole", mock.Anything, "u1", models.RoleModerator).Return(models.User{ID: "u1"}, tt.err).Once()
+
+			req := httptest.NewRequest(http.MethodPatch, "/users/u1/role", strings.NewReader(`{"role":"moderator"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("u1")
+
+			err := handler.ChangeRole(c)
+			assert.Equal(t, tt.expCode, responseCode(rec, err))
+		})
+	}
+}
+
+func TestUserHandler_Deactivate(t *testing.T) {
+	e, mockService, handler := setupUserEcho()
+
+	tests := []struct {
+		name    string
+		err     error
+		expCode int
+	}{
+		{"deactivated", nil, http.StatusOK},
+		{"own account", apperrors.ErrSelfAction, http.StatusBadRequest},
+		{"not found", apperrors.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService.On("DeactivateUser", mock.Anything, "u1").Return(models.User{ID: "u1"}, tt.err).Once()
+
+			req := httptest.NewRequest(http.MethodPost, "/users/u1/deactivate", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("u1")
+
+			err := handler.Deactivate(c)
+			assert.Equal(t, tt.expCode, responseCode(rec, err))
+		})
+	}
+}
+
+func TestUserHandler_Me(t *testing.T) {
+	e, mockService, handler := setupUserEcho()
+
+	t.Run("registered user", func(t *testing.T) {
+		mockService.On("GetUser", mock.Anything, "u1").Return(models.User{ID: "u1", Email: "me@example.com"}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req = req.WithContext(actor.WithActor(req.Context(), actor.Actor{UserID: "u1"}))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Me(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "me@example.com")
+	})
+
+	t.Run("dummy token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Me(c)
+		assert.Equal(t, http.StatusForbidden, responseCode(rec, err))
+	})
+}
+
+func TestUserHandler_UpdateMe(t *testing.T) {
+	e, mockService, handler := setupUserEcho()
+
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		callMock bool
+		expCode  int
+	}{
+		{"email changed", `{"email":"new@example.com","currentPassword":"old"}`, nil, true, http.StatusOK},
+		{"wrong password", `{"email":"new@example.com","currentPassword":"bad"}`, apperrors.ErrInvalidCredentials, true, http.StatusForbidden},
+		{"email taken", `{"email":"new@example.com","currentPassword":"old"}`, apperrors.ErrAlreadyExists, true, http.StatusConflict},
+		{"no current password", `{"email":"new@example.com"}`, nil, false, http.StatusBadRequest},
+		{"nothing to change", `{"currentPassword":"old"}`, nil, false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.callMock {
+				mockService.On("UpdateProfile", mock.Anything, "u1", mock.MatchedBy(func(u models.ProfileUpdate) bool {
+					return u.Email == "new@example.com" && u.NewPasswordHash == ""
+				})).Return(models.User{ID: "u1"}, tt.err).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPatch, "/me", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = req.WithContext(actor.WithActor(req.Context(), actor.Actor{UserID: "u1"}))
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.UpdateMe(c)
+			assert.Equal(t, tt.expCode, responseCode(rec, err))
+		})
+	}
+	mockService.AssertExpectations(t)
+}
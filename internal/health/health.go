@@ -0,0 +1,120 @@
+// Package health собирает состояние компонентов сервиса для проб
+// оркестратора: /healthz отвечает, жив ли процесс, /readyz - готов ли он
+// принимать запросы.
+package health
+
+import (
+	"context"
+	"pvz-service/internal/logger"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+// checkTimeout ограничивает одну проверку, чтобы зависшая база не
+// задерживала ответ пробе дольше ее собственного таймаута
+const checkTimeout = 2 * time.Second
+
+// Check проверяет один компонент и возвращает ошибку, если он не готов
+type Check func(ctx context.Context) error
+
+// Component - результат проверки одного компонента. Текст ошибки пишется
+// только в лог: пробы доступны без авторизации, а ошибки драйвера раскрывают
+// адреса и детали инфраструктуры
+type Component struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+type Checker struct {
+	mu           sync.Mutex
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Register добавляет компонент в проверку готовности
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// SetShuttingDown переводит готовность в fail до конца работы процесса,
+// чтобы балансировщик перестал слать запросы, пока идет graceful shutdown
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check выполняет все проверки параллельно
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.Unlock()
+
+	results := make([]Component, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, names[i], check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(names))}
+	for i, name := range names {
+		report.Components[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+
+	return report
+}
+
+func run(ctx context.Context, name string, check Check) Component {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	component := Component{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000.0,
+	}
+	if err != nil {
+		component.Status = StatusFail
+		logger.FromContext(ctx).WithError(err).WithField("component", name).Warn("readiness check failed")
+	}
+
+	return component
+}
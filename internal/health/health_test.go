@@ -0,0 +1,42 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	c := NewChecker()
+	c.Register("database", func(ctx context.Context) error { return nil })
+
+	report := c.Check(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Components["database"].Status)
+
+	c.Register("migrations", func(ctx context.Context) error { return errors.New("2 pending") })
+	report = c.Check(context.Background())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusFail, report.Components["migrations"].Status)
+	assert.Equal(t, StatusOK, report.Components["database"].Status)
+
+	c.SetShuttingDown()
+	assert.Equal(t, StatusShuttingDown, c.Check(context.Background()).Status)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	c := NewChecker()
+	c.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := c.Check(ctx)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusFail, report.Components["slow"].Status)
+}
@@ -0,0 +1,18 @@
+package jobs
+
+import (
+	"context"
+	"pvz-service/config"
+	"pvz-service/internal/services"
+)
+
+const StaleReceptionsJob = "stale_receptions"
+
+// RegisterJobs регистрирует включенные в конфигурации задачи
+func RegisterJobs(s *Scheduler, cfg *config.Config, services *services.Services) {
+	if cfg.Jobs.StaleReceptionInterval > 0 {
+		s.Register(StaleReceptionsJob, cfg.Jobs.StaleReceptionInterval, func(ctx context.Context) (int, error) {
+			return services.ReceptionService.ProcessStaleReceptions(ctx, cfg.Jobs.StaleReceptionAfter, cfg.Jobs.StaleReceptionAction)
+		})
+	}
+}
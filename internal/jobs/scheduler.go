@@ -0,0 +1,193 @@
+// Package jobs - периодические фоновые задачи сервера. Каждый запуск
+// задачи защищен блокировкой, поэтому при нескольких репликах задачу
+// выполняет только одна из них.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	"pvz-service/internal/repositories"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxJobRuns - сколько последних запусков хранится для /jobs
+const maxJobRuns = 20
+
+// Func выполняет один проход задачи и возвращает число обработанных объектов
+type Func func(ctx context.Context) (int, error)
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Func
+
+	running   bool
+	stopped   bool
+	nextRunAt time.Time
+	runs      []models.JobRun
+}
+
+type Scheduler struct {
+	locker repositories.JobLockerInterface
+
+	mu      sync.Mutex
+	jobs    []*job
+	started bool
+	wg      sync.WaitGroup
+}
+
+func NewScheduler(locker repositories.JobLockerInterface) *Scheduler {
+	return &Scheduler{locker: locker}
+}
+
+// Register добавляет задачу. Вызывается до Start.
+func (s *Scheduler) Register(name string, interval time.Duration, fn Func) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs = append(s.jobs, &job{name: name, interval: interval, fn: fn})
+}
+
+// Start запускает задачи: первый проход сразу, дальше раз в interval.
+// Задачи останавливаются при отмене ctx, дождаться их можно через Wait.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.started = true
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}()
+	}
+}
+
+// Check - проверка готовности для /readyz: планировщик запущен и ни одна
+// задача не остановилась
+func (s *Scheduler) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return errors.New("scheduler is not started")
+	}
+	for _, j := range s.jobs {
+		if j.stopped {
+			return fmt.Errorf("job %s is stopped", j.name)
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	defer func() {
+		s.mu.Lock()
+		j.stopped = true
+		s.mu.Unlock()
+	}()
+
+	for {
+		s.runOnce(ctx, j)
+
+		s.mu.Lock()
+		j.nextRunAt = time.Now().Add(j.interval)
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, j *job) {
+	s.mu.Lock()
+	j.running = true
+	s.mu.Unlock()
+
+	run := models.JobRun{StartedAt: time.Now()}
+	run.Status, run.Processed, run.Error = s.execute(ctx, j)
+	run.FinishedAt = time.Now()
+
+	log := logger.FromContext(ctx).WithFields(logrus.Fields{"job": j.name, "processed": run.Processed})
+	switch run.Status {
+	case models.JobRunFailed:
+		log.Errorf("фоновая задача завершилась с ошибкой: %s", run.Error)
+	case models.JobRunOK:
+		log.Debug("фоновая задача выполнена")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j.running = false
+	j.runs = append(j.runs, run)
+	if len(j.runs) > maxJobRuns {
+		j.runs = j.runs[len(j.runs)-maxJobRuns:]
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, j *job) (string, int, string) {
+	unlock, ok, err := s.locker.TryLock(ctx, j.name)
+	if err != nil {
+		return models.JobRunFailed, 0, err.Error()
+	}
+	if !ok {
+		return models.JobRunSkipped, 0, ""
+	}
+	defer unlock()
+
+	// проход не должен пересекаться со следующим
+	ctx, cancel := context.WithTimeout(ctx, j.interval)
+	defer cancel()
+	ctx = actor.WithActor(ctx, actor.Actor{UserID: "job:" + j.name, Role: "system"})
+	ctx = logger.WithFields(ctx, logrus.Fields{"job": j.name})
+
+	processed, err := j.fn(ctx)
+	if err != nil {
+		return models.JobRunFailed, processed, err.Error()
+	}
+
+	return models.JobRunOK, processed, ""
+}
+
+// Status возвращает состояние задач этой реплики, последние запуски первыми
+func (s *Scheduler) Status() []models.JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]models.JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		runs := make([]models.JobRun, 0, len(j.runs))
+		for i := len(j.runs) - 1; i >= 0; i-- {
+			runs = append(runs, j.runs[i])
+		}
+
+		statuses = append(statuses, models.JobStatus{
+			Name:      j.name,
+			Interval:  j.interval.String(),
+			Running:   j.running,
+			NextRunAt: j.nextRunAt,
+			Runs:      runs,
+		})
+	}
+
+	return statuses
+}
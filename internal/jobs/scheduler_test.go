@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	"pvz-service/internal/repositories/memory"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	locker := memory.NewRepos(nil).JobLocker
+	s := NewScheduler(locker)
+
+	var mu sync.Mutex
+	var actors []actor.Actor
+	s.Register("ok", time.Hour, func(ctx context.Context) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		actors = append(actors, actor.FromContext(ctx))
+		return 3, nil
+	})
+	s.Register("failing", time.Hour, func(ctx context.Context) (int, error) {
+		return 0, errors.New("boom")
+	})
+
+	// задачу "busy" держит другая реплика
+	unlock, ok, err := locker.TryLock(context.Background(), "busy")
+	require.NoError(t, err)
+	require.True(t, ok)
+	defer unlock()
+	s.Register("busy", time.Hour, func(ctx context.Context) (int, error) {
+		t.Error("задача не должна запускаться без блокировки")
+		return 0, nil
+	})
+
+	assert.Error(t, s.Check(context.Background()), "до Start планировщик не готов")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	assert.NoError(t, s.Check(context.Background()))
+
+	require.Eventually(t, func() bool {
+		for _, st := range s.Status() {
+			if len(st.Runs) == 0 {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	s.Wait()
+	assert.Error(t, s.Check(context.Background()), "остановленные задачи")
+
+	statuses := s.Status()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, models.JobRunOK, statuses[0].Runs[0].Status)
+	assert.Equal(t, 3, statuses[0].Runs[0].Processed)
+	assert.Equal(t, models.JobRunFailed, statuses[1].Runs[0].Status)
+	assert.Equal(t, "boom", statuses[1].Runs[0].Error)
+	assert.Equal(t, models.JobRunSkipped, statuses[2].Runs[0].Status)
+
+	require.Len(t, actors, 1)
+	assert.Equal(t, "job:ok", actors[0].UserID)
+}
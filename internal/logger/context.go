@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ctxKey struct{}
+
+// WithFields возвращает контекст, логгер которого дополнен полями.
+// Так request id, пользователь и ПВЗ попадают во все строки одного запроса.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return context.WithValue(ctx, ctxKey{}, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext возвращает логгер контекста. Вне запроса это стандартный
+// логгер logrus без дополнительных полей.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(ctxKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+
+	return logrus.NewEntry(logrus.StandardLogger())
+}
@@ -0,0 +1,20 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFields(t *testing.T) {
+	ctx := WithFields(context.Background(), logrus.Fields{"request_id": "r1"})
+	ctx = WithFields(ctx, logrus.Fields{"user_id": "u1"})
+
+	entry := FromContext(ctx)
+	assert.Equal(t, "r1", entry.Data["request_id"])
+	assert.Equal(t, "u1", entry.Data["user_id"])
+
+	assert.Empty(t, FromContext(context.Background()).Data)
+}
@@ -2,45 +2,78 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"pvz-service/config"
 	"runtime"
 
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-const logPath = "/app/logs/base.log"
-
-func InitLogger(logLevel, mode string) {
-	level, err := logrus.ParseLevel(logLevel)
-	if err != nil {
-		logrus.Fatalf("Ошибка при парсинге уровня логирования: %v", err)
+// InitLogger настраивает уровень, формат и вывод логов. Возвращенная функция
+// закрывает файл логов, ее нужно вызвать при остановке сервиса.
+func InitLogger(cfg config.LogConfig, mode string) (func() error, error) {
+	if err := SetLevel(cfg.Level); err != nil {
+		return nil, err
 	}
-	logrus.SetLevel(level)
 	logrus.SetReportCaller(true)
 
+	callerPrettyfier := func(frame *runtime.Frame) (function string, file string) {
+		return fmt.Sprintf("%s:%d ", frame.Function, frame.Line), ""
+	}
 	if mode == "prod" {
-		logrus.SetFormatter(&logrus.JSONFormatter{
-			CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
-				return fmt.Sprintf("%s:%d ", frame.Function, frame.Line), ""
-			},
-		})
-
-		if err := os.MkdirAll("/app/logs", 0777); err != nil {
-			logrus.Fatal("Ошибка создания папки логов: ", err)
-		}
-
-		logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-		if err != nil {
-			logrus.Fatal("Ошибка открытия файла логов: ", err)
-		}
-		logrus.SetOutput(logFile)
+		logrus.SetFormatter(&logrus.JSONFormatter{CallerPrettyfier: callerPrettyfier})
+	} else {
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true, CallerPrettyfier: callerPrettyfier})
+	}
+
+	if cfg.Output == "stdout" {
+		logrus.SetOutput(os.Stdout)
+		return func() error { return nil }, nil
+	}
+
+	// lumberjack создает файлы с правами 0600, каталог создаем без доступа на запись для остальных
+	if err := os.MkdirAll(filepath.Dir(cfg.File), 0o750); err != nil {
+		return nil, fmt.Errorf("ошибка создания папки логов: %w", err)
+	}
+	file := &lumberjack.Logger{
+		Filename:   cfg.File,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeDays,
+		Compress:   cfg.Compress,
+	}
+
+	if cfg.Output == "both" {
+		logrus.SetOutput(io.MultiWriter(os.Stdout, file))
 	} else {
-		logrus.SetFormatter(&logrus.TextFormatter{
-			FullTimestamp: true,
-			CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
-				return fmt.Sprintf("%s:%d ", frame.Function, frame.Line), ""
-			},
-		})
+		logrus.SetOutput(file)
 	}
 
+	return file.Close, nil
+}
+
+// Level возвращает текущий уровень логирования
+func Level() string {
+	return logrus.GetLevel().String()
+}
+
+// SetLevel меняет уровень логирования без перезапуска сервиса
+func SetLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	logrus.SetLevel(parsed)
+	return nil
 }
+
+// Levels дает доступ к уровню логирования процесса через интерфейс,
+// например для административного HTTP обработчика
+type Levels struct{}
+
+func (Levels) Level() string { return Level() }
+
+func (Levels) SetLevel(level string) error { return SetLevel(level) }
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"pvz-service/config"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitLogger_File(t *testing.T) {
+	t.Cleanup(func() {
+		logrus.SetOutput(os.Stderr)
+		logrus.SetLevel(logrus.InfoLevel)
+	})
+
+	cfg := config.Default().Log
+	cfg.Output = "file"
+	cfg.File = filepath.Join(t.TempDir(), "logs", "pvz.log")
+	cfg.Level = "info"
+
+	closeLogs, err := InitLogger(cfg, "prod")
+	require.NoError(t, err)
+
+	logrus.Debug("скрыто")
+	logrus.Info("записано")
+	require.NoError(t, closeLogs())
+
+	data, err := os.ReadFile(cfg.File)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"записано"`)
+	assert.NotContains(t, string(data), "скрыто")
+
+	info, err := os.Stat(cfg.File)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(func() { logrus.SetLevel(logrus.InfoLevel) })
+
+	require.NoError(t, SetLevel("warn"))
+	assert.Equal(t, "warning", Level())
+	assert.Error(t, SetLevel("verbose"))
+	assert.Equal(t, "warning", Level())
+}
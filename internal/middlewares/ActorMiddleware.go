@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"pvz-service/internal/pkg/actor"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ActorMiddleware кладет в контекст запроса IP и request id для журнала аудита.
+// Данные пользователя добавляет JWTMiddleware.
+func ActorMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+			ctx := actor.WithActor(req.Context(), actor.Actor{
+				RequestID: requestID,
+				IP:        c.RealIP(),
+			})
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
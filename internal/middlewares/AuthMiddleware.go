@@ -1,22 +1,36 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"pvz-service/config"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	apperrors "pvz-service/internal/pkg/errors"
 	j "pvz-service/internal/pkg/jwt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 )
 
+// UserProvider возвращает актуальное состояние пользователя из токена
+type UserProvider interface {
+	GetUser(ctx context.Context, id string) (models.User, error)
+}
+
 type AuthMiddleware struct {
-	cfg *config.Config
+	cfg   *config.Config
+	users UserProvider
 }
 
-func NewAuthMiddleware(cfg *config.Config) *AuthMiddleware {
-	return &AuthMiddleware{cfg: cfg}
+func NewAuthMiddleware(cfg *config.Config, users UserProvider) *AuthMiddleware {
+	return &AuthMiddleware{cfg: cfg, users: users}
 }
 
 func (m *AuthMiddleware) JWTMiddleware() echo.MiddlewareFunc {
@@ -35,11 +49,11 @@ func (m *AuthMiddleware) JWTMiddleware() echo.MiddlewareFunc {
 			tokenStr := parts[1]
 
 			token, err := jwt.ParseWithClaims(tokenStr, &j.Claims{}, func(token *jwt.Token) (interface{}, error) {
-				return []byte(m.cfg.SECRET), nil
+				return []byte(m.cfg.Auth.Secret), nil
 			})
 
 			if err != nil || !token.Valid {
-				logrus.Error(err)
+				logger.FromContext(c.Request().Context()).Warn(err)
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
 			}
 
@@ -48,17 +62,67 @@ func (m *AuthMiddleware) JWTMiddleware() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid claims")
 			}
 
+			// токены без user_id выдает только /dummyLogin, вне разработки они не принимаются
+			if claims.UserID == "" && !m.cfg.Auth.DummyLogin {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+			}
+
+			// токены зарегистрированных пользователей сверяются с базой, чтобы
+			// отключение учетной записи и смена роли действовали сразу
+			if claims.UserID != "" && m.users != nil {
+				user, err := m.users.GetUser(c.Request().Context(), claims.UserID)
+				if errors.Is(err, apperrors.ErrNotFound) || err == nil && user.DeactivatedAt != nil {
+					return echo.NewHTTPError(http.StatusUnauthorized, "account is deactivated")
+				}
+				if err != nil {
+					logger.FromContext(c.Request().Context()).Error(err)
+					return echo.NewHTTPError(http.StatusInternalServerError, "could not load user")
+				}
+				if issuedBeforePasswordChange(claims, user) {
+					return echo.NewHTTPError(http.StatusUnauthorized, "token is revoked")
+				}
+				claims.Role = user.Role
+				claims.Email = user.Email
+			}
+
 			c.Set("role", claims.Role)
+			c.Set("user_id", claims.UserID)
+
+			a := actor.FromContext(c.Request().Context())
+			a.UserID = claims.UserID
+			a.Email = claims.Email
+			a.Role = claims.Role
+			ctx := actor.WithActor(c.Request().Context(), a)
+			if claims.UserID != "" {
+				ctx = logger.WithFields(ctx, logrus.Fields{"user_id": claims.UserID})
+			}
+			c.SetRequest(c.Request().WithContext(ctx))
+
 			return next(c)
 		}
 	}
 }
 
-func (m *AuthMiddleware) RequireRole(required string) echo.MiddlewareFunc {
+// issuedBeforePasswordChange сообщает, что токен выпущен до последней смены
+// пароля. iat хранится с точностью до секунды, поэтому токены, выпущенные в
+// ту же секунду, что и смена, остаются действительными.
+func issuedBeforePasswordChange(claims *j.Claims, user models.User) bool {
+	if user.PasswordChangedAt == nil {
+		return false
+	}
+	if claims.IssuedAt == nil {
+		return true
+	}
+
+	return claims.IssuedAt.Time.Before(user.PasswordChangedAt.Truncate(time.Second))
+}
+
+// RequireRole пропускает пользователей с любой из ролей allowed
+func (m *AuthMiddleware) RequireRole(allowed ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			role, ok := c.Get("role").(string)
-			if !ok || role != required {
+			if !ok || !slices.Contains(allowed, role) {
 				return echo.NewHTTPError(http.StatusForbidden, "Доступ запрещен")
 			}
 			return next(c)
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/pkg/ratelimit"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RateKeyFunc определяет, чей лимит расходует запрос
+type RateKeyFunc func(c echo.Context) string
+
+// RateKeyByIP полагается на echo.IPExtractor из ClientIPExtractor: без него
+// RealIP доверяет X-Forwarded-For от любого клиента
+func RateKeyByIP(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// RateKeyByUser требует JWTMiddleware раньше в цепочке. Токены без user_id
+// (dummyLogin) ограничиваются по IP.
+func RateKeyByUser(c echo.Context) string {
+	if id, ok := c.Get("user_id").(string); ok && id != "" {
+		return "user:" + id
+	}
+	return RateKeyByIP(c)
+}
+
+// ClientIPExtractor берет IP клиента из X-Forwarded-For только за доверенными
+// прокси trusted (CIDR), иначе - адрес соединения
+func ClientIPExtractor(trusted []string) (echo.IPExtractor, error) {
+	if len(trusted) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, cidr := range trusted {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// RateLimitMiddleware отвечает 429 с Retry-After, когда лимит исчерпан.
+// Ошибка хранилища не блокирует запросы.
+func RateLimitMiddleware(store ratelimit.Store, scope string, limit ratelimit.Limit, key RateKeyFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ok, retryAfter, err := store.Allow(c.Request().Context(), scope+":"+key(c), limit)
+			if err != nil {
+				logger.FromContext(c.Request().Context()).Errorf("rate limit: %v", err)
+				return next(c)
+			}
+
+			if !ok {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				return echo.NewHTTPError(http.StatusTooManyRequests, echo.Map{"message": "too many requests"})
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/tracing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware берет X-Request-ID из запроса или генерирует новый,
+// возвращает его в ответе и добавляет в логгер контекста вместе с trace id.
+// Должен стоять сразу после middleware трассировки, чтобы request id был у
+// всех остальных.
+func RequestIDMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			requestID := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+				req.Header.Set(echo.HeaderXRequestID, requestID)
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			fields := logrus.Fields{"request_id": requestID}
+			if traceID := tracing.TraceID(req.Context()); traceID != "" {
+				fields["trace_id"] = traceID
+			}
+			ctx := logger.WithFields(req.Context(), fields)
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// validRequestID пропускает только короткие id из печатных ASCII символов,
+// чтобы клиент не мог подсунуть в логи переводы строк или мегабайты текста
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,13 +1,14 @@
 package middlewares
 
 import (
+	"pvz-service/internal/logger"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 )
 
-// LoggerMiddleware возвращает Echo middleware с логированием через logrus
+// LoggerMiddleware возвращает Echo middleware с логированием через логгер запроса
 func LoggerMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -20,7 +21,9 @@ func LoggerMiddleware() echo.MiddlewareFunc {
 			stop := time.Now()
 			latency := stop.Sub(start)
 
-			entry := logrus.WithFields(logrus.Fields{
+			// контекст читается после обработки: к этому моменту JWTMiddleware
+			// и обработчик добавили в логгер пользователя и ПВЗ
+			entry := logger.FromContext(c.Request().Context()).WithFields(logrus.Fields{
 				"time":       stop.Format(time.RFC3339),
 				"remote_ip":  c.RealIP(),
 				"host":       req.Host,
@@ -30,13 +33,15 @@ func LoggerMiddleware() echo.MiddlewareFunc {
 				"status":     res.Status,
 				"latency":    latency,
 				"latency_ms": float64(latency.Microseconds()) / 1000.0,
-				"request_id": res.Header().Get(echo.HeaderXRequestID),
 			})
 
-			if err != nil {
+			switch {
+			case err != nil:
 				entry = entry.WithField("error", err)
 				entry.Error("request failed")
-			} else {
+			case IsProbe(c):
+				entry.Debug("request handled")
+			default:
 				entry.Info("request handled")
 			}
 
@@ -44,3 +49,10 @@ func LoggerMiddleware() echo.MiddlewareFunc {
 		}
 	}
 }
+
+// IsProbe - запрос пробы оркестратора. Пробы приходят каждые несколько
+// секунд, поэтому пишутся в лог только на уровне debug.
+func IsProbe(c echo.Context) bool {
+	path := c.Path()
+	return path == "/healthz" || path == "/readyz"
+}
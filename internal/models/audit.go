@@ -0,0 +1,112 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditPVZCreate          = "pvz.create"
+	AuditPVZImport          = "pvz.import"
+	AuditPVZDelete          = "pvz.delete"
+	AuditPVZScheduleUpdate  = "pvz.schedule_update"
+	AuditPVZExceptionCreate = "pvz.schedule_exception_create"
+	AuditPVZExceptionDelete = "pvz.schedule_exception_delete"
+	AuditReceptionCreate    = "reception.create"
+	// приемка открыта модератором в нерабочее время ПВЗ
+	AuditReceptionOverride  = "reception.create_override"
+	AuditReceptionClose     = "reception.close"
+	AuditReceptionAutoClose = "reception.auto_close"
+	AuditReceptionStale     = "reception.stale"
+	AuditProductAdd         = "product.add"
+	AuditProductDeleteLast  = "product.delete_last"
+	AuditProductIssue       = "product.issue"
+	AuditProductIssueDenied = "product.issue_denied"
+	AuditProductReturn      = "product.return"
+	AuditProductPickupCode  = "product.pickup_code_reset"
+	AuditUserRegister       = "user.register"
+	AuditUserLock           = "user.lock"
+	AuditInvitationCreate   = "invitation.create"
+	AuditInvitationRevoke   = "invitation.revoke"
+	AuditInvitationRedeem   = "invitation.redeem"
+	AuditUserUnlock         = "user.unlock"
+	AuditUserPasswordReset  = "user.password_reset"
+	AuditUserEmailVerified  = "user.email_verified"
+	AuditUserRoleChange     = "user.role_change"
+	AuditUserDeactivate     = "user.deactivate"
+	AuditUserReactivate     = "user.reactivate"
+	AuditUserProfileUpdate  = "user.profile_update"
+	AuditLogLevelChange     = "admin.log_level_change"
+)
+
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	ActorID    string          `json:"actorId"`
+	ActorEmail string          `json:"actorEmail"`
+	ActorRole  string          `json:"actorRole"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType"`
+	TargetID   string          `json:"targetId"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	RequestID  string          `json:"requestId"`
+	IP         string          `json:"ip"`
+	PrevHash   string          `json:"prevHash"`
+	Hash       string          `json:"hash"`
+}
+
+// ComputeHash считает хэш записи вместе с хэшем предыдущей, образуя цепочку.
+// Изменение любой записи задним числом ломает хэши всех последующих.
+func (e AuditEntry) ComputeHash() string {
+	payload, _ := json.Marshal(struct {
+		PrevHash   string          `json:"prevHash"`
+		CreatedAt  string          `json:"createdAt"`
+		ActorID    string          `json:"actorId"`
+		ActorEmail string          `json:"actorEmail"`
+		ActorRole  string          `json:"actorRole"`
+		Action     string          `json:"action"`
+		TargetType string          `json:"targetType"`
+		TargetID   string          `json:"targetId"`
+		Before     json.RawMessage `json:"before"`
+		After      json.RawMessage `json:"after"`
+		RequestID  string          `json:"requestId"`
+		IP         string          `json:"ip"`
+	}{
+		PrevHash:   e.PrevHash,
+		CreatedAt:  e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ActorID:    e.ActorID,
+		ActorEmail: e.ActorEmail,
+		ActorRole:  e.ActorRole,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Before:     e.Before,
+		After:      e.After,
+		RequestID:  e.RequestID,
+		IP:         e.IP,
+	})
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditVerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	BrokenAt *int64 `json:"brokenAt,omitempty"`
+}
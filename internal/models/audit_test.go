@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEntry_ComputeHash(t *testing.T) {
+	first := AuditEntry{
+		CreatedAt: time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC),
+		Action:    AuditPVZCreate,
+		After:     json.RawMessage(`{"id":"1"}`),
+	}
+	first.Hash = first.ComputeHash()
+
+	second := AuditEntry{CreatedAt: first.CreatedAt, Action: AuditPVZDelete, PrevHash: first.Hash}
+	second.Hash = second.ComputeHash()
+
+	tampered := first
+	tampered.After = json.RawMessage(`{"id":"2"}`)
+	assert.NotEqual(t, first.Hash, tampered.ComputeHash())
+
+	second.PrevHash = tampered.ComputeHash()
+	assert.NotEqual(t, second.Hash, second.ComputeHash())
+}
@@ -0,0 +1,20 @@
+package models
+
+// DBPoolStats - состояние пула соединений с базой для мониторинга
+type DBPoolStats struct {
+	MaxConns          int32 `json:"maxConns"`
+	TotalConns        int32 `json:"totalConns"`
+	AcquiredConns     int32 `json:"acquiredConns"`
+	IdleConns         int32 `json:"idleConns"`
+	ConstructingConns int32 `json:"constructingConns"`
+
+	// Счетчики с момента старта: ожидание свободного соединения (EmptyAcquireCount)
+	// при постоянном росте говорит о нехватке MaxConns
+	AcquireCount            int64   `json:"acquireCount"`
+	AcquireDurationMs       float64 `json:"acquireDurationMs"`
+	EmptyAcquireCount       int64   `json:"emptyAcquireCount"`
+	CanceledAcquireCount    int64   `json:"canceledAcquireCount"`
+	NewConnsCount           int64   `json:"newConnsCount"`
+	MaxLifetimeDestroyCount int64   `json:"maxLifetimeDestroyCount"`
+	MaxIdleDestroyCount     int64   `json:"maxIdleDestroyCount"`
+}
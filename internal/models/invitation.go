@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Invitation - одноразовое приглашение создать учетную запись с ролью
+// сотрудника или модератора. Токен хранится только в виде хэша.
+type Invitation struct {
+	ID        string     `json:"id"`
+	TokenHash string     `json:"-"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	PvzID     *string    `json:"pvzId,omitempty"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	UsedBy    *string    `json:"usedBy,omitempty"`
+}
+
+// CreatedInvitation возвращается модератору один раз: токен больше нигде не хранится
+type CreatedInvitation struct {
+	Invitation
+	Token string `json:"token"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	JobRunOK      = "ok"
+	JobRunFailed  = "failed"
+	JobRunSkipped = "skipped" // задачу выполняет другая реплика
+)
+
+type JobRun struct {
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Status     string    `json:"status"`
+	Processed  int       `json:"processed"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type JobStatus struct {
+	Name      string    `json:"name"`
+	Interval  string    `json:"interval"`
+	Running   bool      `json:"running"`
+	NextRunAt time.Time `json:"nextRunAt"`
+	Runs      []JobRun  `json:"runs"`
+}
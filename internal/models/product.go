@@ -2,9 +2,29 @@ package models
 
 import "time"
 
+// Статусы товара. Принятый товар становится готовым к выдаче при закрытии
+// приемки, готовый выдается получателю по коду или возвращается отправителю.
+const (
+	ProductAccepted       = "accepted"
+	ProductReadyForPickup = "ready_for_pickup"
+	ProductIssued         = "issued"
+	ProductReturned       = "returned"
+)
+
+// MaxPickupAttempts - сколько раз можно ошибиться в коде получения, дальше
+// код нужно перевыпустить
+const MaxPickupAttempts = 5
+
 type Product struct {
-	ID          string    `json:"id"`
-	DateTime    time.Time `json:"dateTime"`
-	Type        string    `json:"type"`
-	ReceptionId string    `json:"receptionId,omitempty"`
+	ID          string     `json:"id"`
+	DateTime    time.Time  `json:"dateTime"`
+	Type        string     `json:"type"`
+	ReceptionId string     `json:"receptionId,omitempty"`
+	Status      string     `json:"status"`
+	IssuedAt    *time.Time `json:"issuedAt,omitempty"`
+	// RecipientEmail - куда отправляется код получения. Сотрудникам ПВЗ код
+	// не показывается: выдать товар может только тот, кто получил письмо
+	RecipientEmail string `json:"-"`
+	PickupCodeHash string `json:"-"`
+	PickupAttempts int    `json:"-"`
 }
@@ -2,15 +2,127 @@ package models
 
 import "time"
 
+// PVZCities - города, в которых открываются ПВЗ. Тот же список задает
+// CHECK pvz_city_check в базе, memory хранилище проверяет его само.
+var PVZCities = map[string]bool{
+	"москва":          true,
+	"санкт-петербург": true,
+	"казань":          true,
+}
+
 type PVZ struct {
 	ID               string    `json:"id"`
 	RegistrationDate time.Time `json:"registrationDate"`
 	City             string    `json:"city"`
+	Address          string    `json:"address,omitempty"`
+	// координаты задаются парой, ПВЗ без координат не попадают в поиск ближайших
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	// TimeZone - часовой пояс IANA, в котором задано расписание
+	TimeZone string `json:"timeZone,omitempty"`
+	// IsOpenNow вычисляется по расписанию при чтении, не хранится
+	IsOpenNow bool `json:"isOpenNow"`
 }
 
 type FullPVZ struct {
 	ID               string                   `json:"id"`
 	RegistrationDate time.Time                `json:"registrationDate"`
 	City             string                   `json:"city"`
+	Address          string                   `json:"address,omitempty"`
+	Latitude         *float64                 `json:"latitude,omitempty"`
+	Longitude        *float64                 `json:"longitude,omitempty"`
+	TimeZone         string                   `json:"timeZone,omitempty"`
+	IsOpenNow        bool                     `json:"isOpenNow"`
 	Receptions       map[string]FullReception `json:"receptions"`
 }
+
+// DayPeriod - местные календарные дни с From по To включительно, у границ
+// учитывается только дата. Дни считаются в поясе TimeZone, если он задан,
+// иначе в поясе каждого ПВЗ: приемка в 01:00 по Москве относится к
+// московскому дню, а не к предыдущему дню по UTC.
+type DayPeriod struct {
+	From     time.Time
+	To       time.Time
+	TimeZone string
+}
+
+func (p DayPeriod) IsZero() bool {
+	return p.From.IsZero() && p.To.IsZero()
+}
+
+// Bounds возвращает начало первого дня и начало дня после последнего в поясе loc.
+// Нулевая граница остается нулевой.
+func (p DayPeriod) Bounds(loc *time.Location) (from, to time.Time) {
+	if !p.From.IsZero() {
+		from = time.Date(p.From.Year(), p.From.Month(), p.From.Day(), 0, 0, 0, 0, loc)
+	}
+	if !p.To.IsZero() {
+		to = time.Date(p.To.Year(), p.To.Month(), p.To.Day()+1, 0, 0, 0, 0, loc)
+	}
+
+	return from, to
+}
+
+// Поля сортировки списка ПВЗ
+const (
+	PVZSortRegistrationDate = "registrationDate"
+	PVZSortCity             = "city"
+	PVZSortLastReception    = "lastReception"
+)
+
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// PVZFilter - условия выборки списка ПВЗ. Приемки и товары для MinProducts
+// берутся только за Period, если он задан. Registered считается в том же
+// поясе, что и Period.
+type PVZFilter struct {
+	Limit  int
+	Offset int
+
+	Period     DayPeriod
+	Registered DayPeriod
+	City       string
+	// HasOpenReception - nil, если наличие открытой приемки не важно
+	HasOpenReception *bool
+	MinProducts      int
+
+	Sort  string
+	Order string
+}
+
+// NearbyQuery - поиск ПВЗ в радиусе Radius метров от точки
+type NearbyQuery struct {
+	Lat    float64
+	Lon    float64
+	Radius float64
+	Limit  int
+	Offset int
+	// OpenNow оставляет только открытые сейчас ПВЗ
+	OpenNow bool
+}
+
+type NearbyPVZ struct {
+	PVZ
+	// Distance - расстояние до точки поиска в метрах
+	Distance float64 `json:"distance"`
+}
+
+// PVZImportRowError описывает ошибку валидации строки CSV при импорте ПВЗ.
+// Row - номер строки в файле начиная с 1 (заголовок - строка 1).
+type PVZImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type PVZImportResult struct {
+	DryRun  bool                `json:"dryRun"`
+	Total   int                 `json:"total"`
+	Valid   int                 `json:"valid"`
+	Created int                 `json:"created"`
+	Errors  []PVZImportRowError `json:"errors"`
+	PVZs    []PVZ               `json:"pvzs"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDayPeriod_Bounds(t *testing.T) {
+	moscow := Location("Europe/Moscow")
+	period := DayPeriod{
+		From: time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, time.May, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	from, to := period.Bounds(moscow)
+	assert.True(t, from.Equal(time.Date(2025, time.April, 30, 21, 0, 0, 0, time.UTC)), "начало 1 мая по Москве")
+	assert.True(t, to.Equal(time.Date(2025, time.May, 2, 21, 0, 0, 0, time.UTC)), "граница - начало 3 мая, последний день включается целиком")
+
+	from, to = DayPeriod{To: period.To}.Bounds(moscow)
+	assert.True(t, from.IsZero())
+	assert.False(t, to.IsZero())
+	assert.True(t, DayPeriod{TimeZone: "UTC"}.IsZero())
+}
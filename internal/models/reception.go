@@ -2,6 +2,28 @@ package models
 
 import "time"
 
+// Что делать с приемкой, в которой давно нет активности
+const (
+	StaleReceptionClose = "close"
+	StaleReceptionFlag  = "flag"
+)
+
+// Статусы приемки
+const (
+	ReceptionInProgress = "in_progress"
+	ReceptionClosed     = "close"
+)
+
+// ReceptionFilter - выборка приемок ПВЗ, новые первыми. Period считается в
+// поясе ПВЗ или в явно заданном Period.TimeZone.
+type ReceptionFilter struct {
+	PvzID  string
+	Status string
+	Period DayPeriod
+	Limit  int
+	Offset int
+}
+
 type Reception struct {
 	ID       string    `json:"id"`
 	PvzId    string    `json:"pvzId"`
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DateLayout - формат дат расписания (местная дата ПВЗ)
+const DateLayout = "2006-01-02"
+
+// WorkingHours - часы работы в день недели (1 - понедельник, 7 - воскресенье).
+// Время местное в формате 15:04, закрытие в 24:00 - работа до конца дня.
+type WorkingHours struct {
+	Weekday int    `json:"weekday"`
+	Opens   string `json:"opens"`
+	Closes  string `json:"closes"`
+}
+
+// ScheduleException переопределяет недельное расписание на даты с StartDate по
+// EndDate включительно: праздник или временное закрытие, если Opens не задан,
+// иначе особые часы работы.
+type ScheduleException struct {
+	ID        string `json:"id"`
+	PvzID     string `json:"pvzId"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	Opens     string `json:"opens,omitempty"`
+	Closes    string `json:"closes,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// PVZSchedule - расписание ПВЗ. Пустое недельное расписание означает, что
+// часы работы не настроены и ПВЗ открыт всегда, кроме дней исключений.
+type PVZSchedule struct {
+	PvzID      string              `json:"pvzId"`
+	TimeZone   string              `json:"timeZone"`
+	Week       []WorkingHours      `json:"week"`
+	Exceptions []ScheduleException `json:"exceptions"`
+	IsOpenNow  bool                `json:"isOpenNow"`
+}
+
+// IsOpenAt сообщает, открыт ли ПВЗ в момент t по его местному времени
+func (s PVZSchedule) IsOpenAt(t time.Time) bool {
+	local := t.In(Location(s.TimeZone))
+	date := local.Format(DateLayout)
+	minute := local.Hour()*60 + local.Minute()
+
+	for _, e := range s.Exceptions {
+		if date < e.StartDate || date > e.EndDate {
+			continue
+		}
+		return e.Opens != "" && withinHours(e.Opens, e.Closes, minute)
+	}
+
+	if len(s.Week) == 0 {
+		return true
+	}
+
+	weekday := int(local.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	for _, h := range s.Week {
+		if h.Weekday == weekday {
+			return withinHours(h.Opens, h.Closes, minute)
+		}
+	}
+
+	return false
+}
+
+func withinHours(opens, closes string, minute int) bool {
+	from, err := ParseClock(opens)
+	if err != nil {
+		return false
+	}
+	to, err := ParseClock(closes)
+	if err != nil {
+		return false
+	}
+
+	return minute >= from && minute < to
+}
+
+// ParseClock переводит время 15:04 (или 24:00) в минуты от начала дня
+func ParseClock(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("ожидается время в формате 15:04: %q", s)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+var locations sync.Map
+
+// Location возвращает часовой пояс по имени IANA. Пояса кэшируются: расписание
+// проверяется для каждого ПВЗ в списке. Неизвестное имя трактуется как UTC,
+// имена проверяются при сохранении.
+func Location(name string) *time.Location {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	locations.Store(name, loc)
+
+	return loc
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPVZSchedule_IsOpenAt(t *testing.T) {
+	schedule := PVZSchedule{
+		TimeZone: "Europe/Moscow",
+		Week: []WorkingHours{
+			{Weekday: 1, Opens: "09:00", Closes: "21:00"},
+			{Weekday: 6, Opens: "10:00", Closes: "24:00"},
+		},
+		Exceptions: []ScheduleException{
+			{StartDate: "2025-05-01", EndDate: "2025-05-02"},
+			{StartDate: "2025-05-12", EndDate: "2025-05-12", Opens: "12:00", Closes: "15:00"},
+		},
+	}
+
+	// 2025-05-05 - понедельник, Москва UTC+3
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.Parse(time.RFC3339, value)
+		assert.NoError(t, err)
+		return parsed
+	}
+
+	tests := []struct {
+		name string
+		at   string
+		open bool
+	}{
+		{"рабочие часы по местному времени", "2025-05-05T06:00:00Z", true},
+		{"до открытия по местному времени", "2025-05-05T05:59:00Z", false},
+		{"закрытие не включается", "2025-05-05T18:00:00Z", false},
+		{"выходной день", "2025-05-06T10:00:00Z", false},
+		{"работа до конца суток", "2025-05-10T20:59:00Z", true},
+		{"праздник", "2025-05-01T10:00:00Z", false},
+		{"последний день закрытия", "2025-05-02T10:00:00Z", false},
+		{"особые часы", "2025-05-12T10:00:00Z", true},
+		{"вне особых часов", "2025-05-12T07:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.open, schedule.IsOpenAt(at(tt.at)))
+		})
+	}
+
+	t.Run("без недельного расписания открыт всегда, кроме исключений", func(t *testing.T) {
+		s := PVZSchedule{TimeZone: "Europe/Moscow", Exceptions: schedule.Exceptions[:1]}
+		assert.True(t, s.IsOpenAt(at("2025-05-06T01:00:00Z")))
+		assert.False(t, s.IsOpenAt(at("2025-05-01T10:00:00Z")))
+	})
+}
+
+func TestParseClock(t *testing.T) {
+	minutes, err := ParseClock("09:30")
+	assert.NoError(t, err)
+	assert.Equal(t, 570, minutes)
+	assert.Equal(t, "09:30", FormatClock(minutes))
+
+	minutes, err = ParseClock("24:00")
+	assert.NoError(t, err)
+	assert.Equal(t, "24:00", FormatClock(minutes))
+
+	_, err = ParseClock("25:00")
+	assert.Error(t, err)
+	_, err = ParseClock("9")
+	assert.Error(t, err)
+}
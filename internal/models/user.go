@@ -1,8 +1,60 @@
 package models
 
+import "time"
+
+const (
+	// RoleEmployee - сотрудник ПВЗ, исторически называется client
+	RoleEmployee  = "client"
+	RoleModerator = "moderator"
+	// RoleCustomer получают все, кто регистрируется без приглашения
+	RoleCustomer = "customer"
+)
+
+// ValidRole сообщает, существует ли роль
+func ValidRole(role string) bool {
+	return role == RoleEmployee || role == RoleModerator || role == RoleCustomer
+}
+
 type User struct {
 	ID       string `json:"id"`
 	Email    string `json:"email"`
 	Password string `json:"-"`
 	Role     string `json:"role"`
+	// PvzID - ПВЗ сотрудника, если он указан в приглашении
+	PvzID *string `json:"pvzId,omitempty"`
+	// FailedLogins - неудачные попытки входа подряд, LockedUntil - до какого
+	// момента вход запрещен после превышения лимита попыток
+	FailedLogins int        `json:"failedLogins"`
+	LockedUntil  *time.Time `json:"lockedUntil,omitempty"`
+	// EmailVerifiedAt - когда пользователь подтвердил email, nil - не подтвердил
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	// DeactivatedAt - когда учетная запись отключена модератором
+	DeactivatedAt *time.Time `json:"deactivatedAt,omitempty"`
+	// PasswordChangedAt - когда пароль меняли последний раз, токены выпущенные
+	// раньше недействительны
+	PasswordChangedAt *time.Time `json:"-"`
+}
+
+// Фильтр по состоянию учетной записи в UserFilter
+const (
+	UserStatusActive      = "active"
+	UserStatusDeactivated = "deactivated"
+)
+
+// UserFilter - параметры поиска пользователей для модераторов
+type UserFilter struct {
+	// Query - подстрока email без учета регистра
+	Query  string
+	Role   string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// ProfileUpdate - изменения, которые пользователь вносит в свой профиль.
+// Пустые поля не меняются.
+type ProfileUpdate struct {
+	Email           string
+	CurrentPassword string
+	NewPasswordHash string
 }
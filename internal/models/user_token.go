@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Назначение одноразовых токенов из писем
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+// UserToken - одноразовый токен из письма. Хранится только хэш.
+type UserToken struct {
+	ID        string
+	UserID    string
+	Purpose   string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
@@ -0,0 +1,24 @@
+package actor
+
+import "context"
+
+type ctxKey struct{}
+
+// Actor - кто выполняет запрос: данные из JWT и параметры HTTP запроса
+type Actor struct {
+	UserID    string
+	Email     string
+	Role      string
+	RequestID string
+	IP        string
+}
+
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, ctxKey{}, a)
+}
+
+// FromContext возвращает пустого Actor, если запрос пришел не через HTTP
+func FromContext(ctx context.Context) Actor {
+	a, _ := ctx.Value(ctxKey{}).(Actor)
+	return a
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 5, 10, 12, 0, 0, 0, time.UTC)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "pvz:1", []byte("a"), time.Minute)
+	c.Set(ctx, "pvz:2", []byte("b"), time.Minute)
+
+	value, ok, _ := c.Get(ctx, "pvz:1")
+	assert.True(t, ok)
+	assert.Equal(t, "a", string(value))
+
+	c.Set(ctx, "pvz:3", []byte("c"), time.Minute)
+	_, ok, _ = c.Get(ctx, "pvz:2")
+	assert.False(t, ok, "вытесняется запись, к которой дольше не обращались")
+	_, ok, _ = c.Get(ctx, "pvz:1")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok, _ = c.Get(ctx, "pvz:1")
+	assert.False(t, ok, "просроченная запись")
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestLRU_DeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	c.Set(ctx, "pvz:list:1", []byte("a"), time.Minute)
+	c.Set(ctx, "pvz:list:2", []byte("b"), time.Minute)
+	c.Set(ctx, "pvz:1", []byte("c"), time.Minute)
+
+	c.DeletePrefix(ctx, "pvz:list:")
+	assert.Equal(t, 1, c.Len())
+
+	c.Delete(ctx, "pvz:1")
+	assert.Equal(t, 0, c.Len())
+}
@@ -1,11 +1,43 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrCityNotAllowed     = errors.New("недопустимый город")
 	ErrCategoryNotAllowed = errors.New("недопустимая категория")
 	ErrNotFound           = errors.New("не найдено")
+	ErrAlreadyExists      = errors.New("уже существует")
 	ErrInvalidInput       = errors.New("не верный ввод")
 	ErrNoReceprionsFound  = errors.New("не нашли открытых приемок")
+	ErrInvalidCredentials = errors.New("неверный email или пароль")
+	ErrAccountLocked      = errors.New("учетная запись временно заблокирована")
+	ErrInvalidToken       = errors.New("ссылка недействительна или истекла")
+	ErrEmailNotVerified   = errors.New("email не подтвержден")
+	ErrAccountDeactivated = errors.New("учетная запись отключена")
+	ErrSelfAction         = errors.New("нельзя изменить собственную учетную запись")
+	ErrPVZClosed          = errors.New("ПВЗ закрыт по расписанию")
+	ErrProductStatus      = errors.New("действие недоступно в текущем статусе товара")
+	ErrInvalidPickupCode  = errors.New("неверный код получения")
+	ErrPickupLocked       = errors.New("код получения недействителен, его нужно перевыпустить")
+	ErrForbidden          = errors.New("действие недоступно для этого пользователя")
+	// ErrWrongPVZ - сотрудник закреплен за другим ПВЗ, это частный случай ErrForbidden
+	ErrWrongPVZ = fmt.Errorf("%w: товар принят в другом ПВЗ", ErrForbidden)
 )
+
+// BatchError - ошибка записи элемента Index пакета, например строки импорта.
+// Пакет пишется одной транзакцией, поэтому остальные элементы тоже не записаны.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("элемент %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
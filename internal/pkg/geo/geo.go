@@ -0,0 +1,59 @@
+// Package geo - расчет расстояний по поверхности Земли без PostGIS.
+package geo
+
+import "math"
+
+// EarthRadius - средний радиус Земли в метрах
+const EarthRadius = 6371000.0
+
+// Distance возвращает расстояние в метрах между двумя точками по формуле гаверсинусов
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Pow(math.Sin(dLon/2), 2)
+
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Box - прямоугольник в градусах, содержащий круг радиуса radius вокруг точки.
+// Им отсекаются строки по индексу до точного расчета расстояния.
+type Box struct {
+	MinLat, MaxLat float64
+	MinLon, MaxLon float64
+	// AllLon - круг захватывает полюс или линию перемены дат,
+	// ограничение по долготе не применяется
+	AllLon bool
+}
+
+func BoundingBox(lat, lon, radius float64) Box {
+	dLat := degrees(radius / EarthRadius)
+	box := Box{
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLat: math.Min(lat+dLat, 90),
+		MinLon: -180,
+		MaxLon: 180,
+		AllLon: true,
+	}
+
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+
+	dLon := degrees(math.Asin(math.Min(1, math.Sin(radius/EarthRadius)/math.Cos(radians(lat)))))
+	if lon-dLon < -180 || lon+dLon > 180 {
+		return box
+	}
+
+	box.MinLon, box.MaxLon, box.AllLon = lon-dLon, lon+dLon, false
+	return box
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
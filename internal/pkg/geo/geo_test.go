@@ -0,0 +1,31 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	// Красная площадь - Дворцовая площадь, около 634 км
+	d := Distance(55.7539, 37.6208, 59.9390, 30.3158)
+	assert.InDelta(t, 634000, d, 3000)
+
+	assert.Zero(t, Distance(55.75, 37.62, 55.75, 37.62))
+}
+
+func TestBoundingBox(t *testing.T) {
+	lat, lon, radius := 55.75, 37.62, 5000.0
+	box := BoundingBox(lat, lon, radius)
+	assert.False(t, box.AllLon)
+
+	// точки на границе круга по сторонам света должны попадать в прямоугольник
+	for _, p := range [][2]float64{
+		{box.MinLat, lon}, {box.MaxLat, lon}, {lat, box.MinLon}, {lat, box.MaxLon},
+	} {
+		assert.InDelta(t, radius, Distance(lat, lon, p[0], p[1]), radius*0.01)
+	}
+
+	assert.True(t, BoundingBox(89.99, 0, 5000).AllLon, "круг вокруг полюса")
+	assert.True(t, BoundingBox(0, 179.99, 5000).AllLon, "круг через линию перемены дат")
+}
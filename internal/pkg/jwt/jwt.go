@@ -2,13 +2,16 @@ package jwt
 
 import (
 	"pvz-service/config"
+	"pvz-service/internal/models"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type Claims struct {
-	Role string `json:"role"`
+	Role   string `json:"role"`
+	UserID string `json:"user_id,omitempty"`
+	Email  string `json:"email,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -16,10 +19,29 @@ func GenerateToken(role string, cfg *config.Config) (string, error) {
 	claims := Claims{
 		Role: role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(cfg.Auth.TokenTTL)),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.SECRET))
+	return token.SignedString([]byte(cfg.Auth.Secret))
+}
+
+// GenerateUserToken выпускает токен зарегистрированного пользователя,
+// чтобы действия можно было привязать к нему в журнале аудита
+func GenerateUserToken(user models.User, cfg *config.Config) (string, error) {
+	claims := Claims{
+		Role:   user.Role,
+		UserID: user.ID,
+		Email:  user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: user.ID,
+			// по IssuedAt отзываются токены, выпущенные до смены пароля
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(cfg.Auth.TokenTTL)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.Auth.Secret))
 }
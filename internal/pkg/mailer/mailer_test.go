@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "noreply@pvz.local")
+	require.NoError(t, err)
+
+	body := "Ссылка для сброса пароля: http://localhost/reset?token=abc"
+	require.NoError(t, m.Send(context.Background(), Message{To: "user@example.com", Subject: "Сброс пароля", Body: body}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+
+	parsed, err := mail.ReadMessage(f)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", parsed.Header.Get("To"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Сброс пароля", subject)
+
+	date, err := parsed.Header.Date()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), date, time.Minute)
+}
+
+func TestRender_QuotedPrintable(t *testing.T) {
+	data, err := render("from@example.com", Message{To: "to@example.com", Subject: "s", Body: "привет"}, time.Now())
+	require.NoError(t, err)
+
+	parts := strings.SplitN(string(data), "\r\n\r\n", 2)
+	require.Len(t, parts, 2)
+	assert.NotContains(t, parts[1], "привет", "тело закодировано")
+	assert.Contains(t, parts[0], "Content-Transfer-Encoding: quoted-printable")
+}
+
+func TestLogMailer_RedactsTokens(t *testing.T) {
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(os.Stderr)
+	ctx := context.Background()
+
+	body := "Ссылка: http://localhost/reset-password?token=secret-token&lang=ru"
+	require.NoError(t, LogMailer{}.Send(ctx, Message{To: "user@example.com", Subject: "Сброс пароля", Body: body}))
+
+	assert.NotContains(t, buf.String(), "secret-token")
+	assert.Contains(t, buf.String(), "token=xxxxx&lang=ru")
+}
+
+func TestSMTPMailer_RespectsContext(t *testing.T) {
+	// сервер принимает соединение и молчит
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	m := NewSMTPMailer(host, portNum, "", "", "noreply@pvz.local")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = m.Send(ctx, Message{To: "user@example.com", Subject: "s", Body: "b"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "зависший сервер не держит отправку дольше дедлайна")
+}
@@ -0,0 +1,87 @@
+// Package ratelimit - ограничение частоты запросов по алгоритму token bucket.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit: Rate токенов в секунду пополняют корзину емкостью Burst
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Store хранит состояние корзин. In-memory реализация работает в пределах
+// одной реплики; для общего лимита между репликами достаточно реализовать
+// Store поверх внешнего хранилища.
+type Store interface {
+	// Allow списывает токен по ключу. Если токенов нет, возвращает false и
+	// время, через которое появится следующий.
+	Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// bucket помнит свой лимит: в одном хранилище бывают корзины с разными
+// лимитами, и удалять их при очистке можно только по собственному
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// sweepInterval - как часто удаляются полностью пополнившиеся корзины
+const sweepInterval = time.Minute
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (s *MemoryStore) Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second)), nil
+}
+
+// sweep удаляет корзины, которые к now уже пополнились бы до конца: их
+// состояние ничем не отличается от новой корзины
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.limit.Rate <= 0 {
+			continue
+		}
+		if b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Allow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 4, 25, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Rate: 0.5, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := s.Allow(ctx, "ip:1", limit)
+		require.NoError(t, err)
+		assert.True(t, ok, "запрос %d в пределах burst", i+1)
+	}
+
+	ok, retry, err := s.Allow(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2*time.Second, retry)
+
+	ok, _, _ = s.Allow(ctx, "ip:2", limit)
+	assert.True(t, ok, "у другого ключа своя корзина")
+
+	now = now.Add(2 * time.Second)
+	ok, _, _ = s.Allow(ctx, "ip:1", limit)
+	assert.True(t, ok, "токен пополнился")
+
+	now = now.Add(time.Hour)
+	s.Allow(ctx, "ip:3", limit)
+	assert.NotContains(t, s.buckets, "ip:2", "пополненные корзины удаляются")
+}
+
+func TestMemoryStore_SweepUsesBucketLimit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 4, 25, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	slow := Limit{Rate: 0.1, Burst: 10}
+	fast := Limit{Rate: 20, Burst: 40}
+
+	for i := 0; i < 10; i++ {
+		s.Allow(ctx, "auth:ip:1", slow)
+	}
+
+	// за минуту быстрая корзина пополнилась бы, медленная - нет
+	now = now.Add(sweepInterval + time.Second)
+	s.Allow(ctx, "api:user:1", fast)
+	assert.Contains(t, s.buckets, "auth:ip:1", "корзина с медленным лимитом еще не пополнилась")
+
+	ok, _, _ := s.Allow(ctx, "auth:ip:1", slow)
+	assert.True(t, ok)
+	assert.InDelta(t, 5.1, s.buckets["auth:ip:1"].tokens, 0.001, "6.1 токена за 61 с минус списанный")
+}
@@ -0,0 +1,52 @@
+// Package token - одноразовые секреты для ссылок (приглашения, сброс пароля).
+// В базе хранится только хэш, сам токен показывается один раз.
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+)
+
+// Generate возвращает случайный токен и его хэш для хранения
+func Generate() (string, string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", "", err
+	}
+
+	plain := base64.RawURLEncoding.EncodeToString(b[:])
+	return plain, Hash(plain), nil
+}
+
+// GenerateCode возвращает случайный код из digits цифр. Код короткий, чтобы
+// его можно было продиктовать, поэтому хранить его нужно как HMAC с ключом,
+// а проверять - с ограничением числа попыток.
+func GenerateCode(digits int) (string, error) {
+	code := make([]byte, digits)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+
+	return string(code), nil
+}
+
+func Hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// HMAC - хэш с серверным ключом для секретов с малым числом вариантов:
+// без ключа такой хэш из базы перебирается за миллисекунды
+func HMAC(key []byte, plain string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(plain))
+	return hex.EncodeToString(mac.Sum(nil))
+}
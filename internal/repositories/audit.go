@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// auditLockKey - ключ advisory lock, сериализующего запись в цепочку хэшей
+const auditLockKey int64 = 20250420
+
+type AuditRepository struct {
+	db   *pgxpool.Pool
+	psql sq.StatementBuilderType
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+}
+
+var auditColumns = []string{
+	"id", "created_at", "actor_id", "actor_email", "actor_role", "action", "target_type", "target_id",
+	"before", "after", "request_id", "ip", "prev_hash", "hash",
+}
+
+// Append дописывает запись в цепочку. Если ctx несет транзакцию, запись
+// становится ее частью (через savepoint): блокировка цепочки держится до
+// фиксации, а откат транзакции убирает и запись.
+func (r *AuditRepository) Append(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", auditLockKey); err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	query, args, err := r.psql.
+		Select("hash").
+		From("audit_log").
+		OrderBy("id DESC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	entry.PrevHash = ""
+	err = tx.QueryRow(ctx, query, args...).Scan(&entry.PrevHash)
+	if err != nil && err != pgx.ErrNoRows {
+		return models.AuditEntry{}, err
+	}
+
+	// postgres хранит микросекунды, хэш должен совпасть после чтения из базы
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+
+	query, args, err = r.psql.
+		Insert("audit_log").
+		Columns(auditColumns[1:]...).
+		Values(entry.CreatedAt, entry.ActorID, entry.ActorEmail, entry.ActorRole, entry.Action, entry.TargetType,
+			entry.TargetID, entry.Before, entry.After, entry.RequestID, entry.IP, entry.PrevHash, entry.Hash).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	if err := tx.QueryRow(ctx, query, args...).Scan(&entry.ID); err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query := r.psql.
+		Select(auditColumns...).
+		From("audit_log")
+
+	if filter.ActorID != "" {
+		query = query.Where(sq.Eq{"actor_id": filter.ActorID})
+	}
+	if filter.Action != "" {
+		query = query.Where(sq.Eq{"action": filter.Action})
+	}
+	if filter.TargetType != "" {
+		query = query.Where(sq.Eq{"target_type": filter.TargetType})
+	}
+	if filter.TargetID != "" {
+		query = query.Where(sq.Eq{"target_id": filter.TargetID})
+	}
+	if filter.RequestID != "" {
+		query = query.Where(sq.Eq{"request_id": filter.RequestID})
+	}
+	if !filter.From.IsZero() {
+		query = query.Where(sq.GtOrEq{"created_at": filter.From})
+	}
+	if !filter.To.IsZero() {
+		query = query.Where(sq.LtOrEq{"created_at": filter.To})
+	}
+
+	query = query.OrderBy("id DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(uint64(filter.Limit)).Offset(uint64(filter.Offset))
+	}
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.query(ctx, sqlStr, args...)
+}
+
+// ListAfter возвращает записи с id > afterID по возрастанию, для проверки цепочки
+func (r *AuditRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error) {
+	sqlStr, args, err := r.psql.
+		Select(auditColumns...).
+		From("audit_log").
+		Where(sq.Gt{"id": afterID}).
+		OrderBy("id ASC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.query(ctx, sqlStr, args...)
+}
+
+func (r *AuditRepository) query(ctx context.Context, sqlStr string, args ...interface{}) ([]models.AuditEntry, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.ActorID, &e.ActorEmail, &e.ActorRole, &e.Action, &e.TargetType,
+			&e.TargetID, &e.Before, &e.After, &e.RequestID, &e.IP, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"pvz-service/internal/logger"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CacheInvalidationChannel - канал LISTEN/NOTIFY для инвалидации кэша
+const CacheInvalidationChannel = "pvz_cache"
+
+// CacheNotifier рассылает инвалидацию через NOTIFY. Уведомление, отправленное
+// внутри транзакции, Postgres доставит только после ее коммита.
+type CacheNotifier struct {
+	db *pgxpool.Pool
+}
+
+func NewCacheNotifier(db *pgxpool.Pool) *CacheNotifier {
+	return &CacheNotifier{db: db}
+}
+
+func (n *CacheNotifier) Notify(ctx context.Context, key string) error {
+	_, err := conn(ctx, n.db).Exec(ctx, "SELECT pg_notify($1, $2)", CacheInvalidationChannel, key)
+	return err
+}
+
+// Listen занимает одно соединение пула на все время работы. При разрыве
+// соединения переподключается с нарастающей задержкой.
+func (n *CacheNotifier) Listen(ctx context.Context, evict func(ctx context.Context, key string), reset func(ctx context.Context)) {
+	delay := time.Second
+	for {
+		started := time.Now()
+		err := n.listen(ctx, evict, reset)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			delay = time.Second
+		}
+		logger.FromContext(ctx).Warnf("Потеряна подписка на инвалидацию кэша, повтор через %s: %v", delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+func (n *CacheNotifier) listen(ctx context.Context, evict func(ctx context.Context, key string), reset func(ctx context.Context)) error {
+	c, err := n.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// соединение с подпиской не должно вернуться в пул
+		c.Conn().Close(context.Background())
+		c.Release()
+	}()
+
+	if _, err := c.Exec(ctx, "LISTEN "+pgx.Identifier{CacheInvalidationChannel}.Sanitize()); err != nil {
+		return err
+	}
+	// пока подписки не было, уведомления могли быть пропущены
+	reset(ctx)
+
+	for {
+		notification, err := c.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		evict(ctx, notification.Payload)
+	}
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"time"
+)
+
+// Интерфейсы хранилищ. Реализации на postgres лежат в этом пакете,
+// in-memory реализация - в пакете memory. Общий контракт проверяет repotest.
+// Методы ...ForUpdate блокируют строку до конца транзакции TxManager.
+
+type UserRepositoryInterface interface {
+	CreateUser(ctx context.Context, user models.User) (models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	GetUserByID(ctx context.Context, id string) (models.User, error)
+	// IncrementFailedLogins атомарно увеличивает счетчик и возвращает новое значение
+	IncrementFailedLogins(ctx context.Context, id string) (int, error)
+	LockUser(ctx context.Context, id string, until time.Time) error
+	// ResetFailedLogins обнуляет счетчик и снимает блокировку
+	ResetFailedLogins(ctx context.Context, id string) error
+	// UpdatePassword меняет пароль и запоминает время смены at
+	UpdatePassword(ctx context.Context, id, passwordHash string, at time.Time) error
+	MarkEmailVerified(ctx context.Context, id string, at time.Time) error
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)
+	UpdateRole(ctx context.Context, id, role string) error
+	UpdateEmail(ctx context.Context, id, email string) error
+	// SetDeactivated отключает учетную запись или, при at == nil, включает ее
+	SetDeactivated(ctx context.Context, id string, at *time.Time) error
+}
+
+// UserTokenRepositoryInterface хранит одноразовые токены из писем
+// (сброс пароля, подтверждение email)
+type UserTokenRepositoryInterface interface {
+	Create(ctx context.Context, token models.UserToken) (models.UserToken, error)
+	GetByHashForUpdate(ctx context.Context, purpose, tokenHash string) (models.UserToken, error)
+	MarkUsed(ctx context.Context, id string, at time.Time) error
+	// InvalidateUser помечает использованными все активные токены пользователя
+	// с этим назначением, чтобы работала только последняя ссылка
+	InvalidateUser(ctx context.Context, userID, purpose string, at time.Time) error
+}
+
+type PVZRepositoryInterface interface {
+	GetAll(ctx context.Context, filter models.PVZFilter) ([]models.FullPVZ, error)
+	CreatePVZ(ctx context.Context, pvz models.PVZ) (models.PVZ, error)
+	CreatePVZBatch(ctx context.Context, pvzs []models.PVZ) ([]models.PVZ, error)
+	GetPVZByID(ctx context.Context, id string) (models.PVZ, error)
+	GetPVZByIDForUpdate(ctx context.Context, id string) (models.PVZ, error)
+	List(ctx context.Context, limit, offset int) ([]models.PVZ, error)
+	// Nearby возвращает ПВЗ с координатами в радиусе от точки, ближайшие первыми
+	Nearby(ctx context.Context, q models.NearbyQuery) ([]models.NearbyPVZ, error)
+	UpdateTimeZone(ctx context.Context, id, timeZone string) error
+	DeletePVZ(ctx context.Context, id string) error
+}
+
+// ScheduleRepositoryInterface хранит часы работы ПВЗ и исключения из них
+type ScheduleRepositoryInterface interface {
+	// GetSchedules возвращает расписание для каждого из pvzIDs, в том числе
+	// пустое. Исключения, закончившиеся раньше вчерашнего дня по UTC, не возвращаются.
+	GetSchedules(ctx context.Context, pvzIDs []string) (map[string]models.PVZSchedule, error)
+	ReplaceWeek(ctx context.Context, pvzID string, week []models.WorkingHours) error
+	CreateException(ctx context.Context, e models.ScheduleException) (models.ScheduleException, error)
+	DeleteException(ctx context.Context, pvzID, id string) (models.ScheduleException, error)
+}
+
+type ProductRepositoryInterface interface {
+	AddProduct(ctx context.Context, product models.Product) (models.Product, error)
+	DeleteLastProduct(ctx context.Context, receptionId string) (models.Product, error)
+	GetByReceptionID(ctx context.Context, receptionID string) ([]models.Product, error)
+	ListByReceptionID(ctx context.Context, receptionID string, limit, offset int) ([]models.Product, error)
+	// GetByIDForUpdate блокирует товар до конца транзакции
+	GetByIDForUpdate(ctx context.Context, id string) (models.Product, error)
+	UpdatePickup(ctx context.Context, product models.Product) error
+	// MarkReadyForPickup переводит принятые товары приемки в готовые к выдаче
+	MarkReadyForPickup(ctx context.Context, receptionID string) error
+}
+
+type ReceptionRepositoryInterface interface {
+	CreateReception(ctx context.Context, reception models.Reception) (models.Reception, error)
+	GetActiveReceptionByPVZID(ctx context.Context, pvzID string) (*models.Reception, error)
+	GetActiveReceptionByPVZIDForUpdate(ctx context.Context, pvzID string) (*models.Reception, error)
+	CloseReception(ctx context.Context, receptionId string) error
+	GetByID(ctx context.Context, id string) (models.Reception, error)
+	List(ctx context.Context, filter models.ReceptionFilter) ([]models.Reception, error)
+	// ListStale возвращает открытые приемки, последняя активность в которых
+	// (создание или добавление товара) была раньше idleSince
+	ListStale(ctx context.Context, idleSince time.Time) ([]models.Reception, error)
+}
+
+type InvitationRepositoryInterface interface {
+	Create(ctx context.Context, invitation models.Invitation) (models.Invitation, error)
+	// GetByTokenHashForUpdate блокирует приглашение, чтобы его нельзя было
+	// использовать дважды параллельными запросами
+	GetByTokenHashForUpdate(ctx context.Context, tokenHash string) (models.Invitation, error)
+	MarkUsed(ctx context.Context, id, userID string, at time.Time) error
+	List(ctx context.Context, limit, offset int) ([]models.Invitation, error)
+	// Delete отзывает неиспользованное приглашение
+	Delete(ctx context.Context, id string) error
+}
+
+// JobLockerInterface не дает нескольким репликам одновременно выполнять
+// одну фоновую задачу. ok=false означает, что блокировка занята.
+type JobLockerInterface interface {
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
+}
+
+// CacheNotifierInterface рассылает ключи устаревших записей кэша всем репликам.
+// Listen блокируется до отмены ctx и вызывает evict для каждого полученного ключа,
+// а reset - после каждого (пере)подключения, когда уведомления могли потеряться.
+type CacheNotifierInterface interface {
+	Notify(ctx context.Context, key string) error
+	Listen(ctx context.Context, evict func(ctx context.Context, key string), reset func(ctx context.Context))
+}
+
+type AuditRepositoryInterface interface {
+	Append(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error)
+	List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error)
+}
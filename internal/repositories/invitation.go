@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var invitationColumns = []string{
+	"id", "token_hash", "email", "role", "pvz_id", "created_by", "created_at", "expires_at", "used_at", "used_by",
+}
+
+type InvitationRepository struct {
+	db   *pgxpool.Pool
+	psql sq.StatementBuilderType
+}
+
+func NewInvitationRepository(db *pgxpool.Pool) *InvitationRepository {
+	return &InvitationRepository{db: db, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+}
+
+func (r *InvitationRepository) Create(ctx context.Context, inv models.Invitation) (models.Invitation, error) {
+	query, args, err := r.psql.
+		Insert("invitations").
+		Columns("token_hash", "email", "role", "pvz_id", "created_by", "expires_at").
+		Values(inv.TokenHash, inv.Email, inv.Role, inv.PvzID, inv.CreatedBy, inv.ExpiresAt).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return models.Invitation{}, err
+	}
+
+	err = conn(ctx, r.db).QueryRow(ctx, query, args...).Scan(&inv.ID, &inv.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return models.Invitation{}, errors.ErrNotFound
+	}
+	if err != nil {
+		return models.Invitation{}, err
+	}
+
+	return inv, nil
+}
+
+func (r *InvitationRepository) GetByTokenHashForUpdate(ctx context.Context, tokenHash string) (models.Invitation, error) {
+	query, args, err := r.psql.
+		Select(invitationColumns...).
+		From("invitations").
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return models.Invitation{}, err
+	}
+
+	inv, err := scanInvitation(conn(ctx, r.db).QueryRow(ctx, query, args...))
+	if err == pgx.ErrNoRows {
+		return models.Invitation{}, errors.ErrNotFound
+	}
+
+	return inv, err
+}
+
+func (r *InvitationRepository) MarkUsed(ctx context.Context, id, userID string, at time.Time) error {
+	query, args, err := r.psql.
+		Update("invitations").
+		Set("used_at", at).
+		Set("used_by", userID).
+		Where(sq.Eq{"id": id, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *InvitationRepository) List(ctx context.Context, limit, offset int) ([]models.Invitation, error) {
+	query, args, err := r.psql.
+		Select(invitationColumns...).
+		From("invitations").
+		OrderBy("created_at DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := make([]models.Invitation, 0)
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, rows.Err()
+}
+
+func (r *InvitationRepository) Delete(ctx context.Context, id string) error {
+	query, args, err := r.psql.
+		Delete("invitations").
+		Where(sq.Eq{"id": id, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanInvitation(row pgx.Row) (models.Invitation, error) {
+	var inv models.Invitation
+	err := row.Scan(&inv.ID, &inv.TokenHash, &inv.Email, &inv.Role, &inv.PvzID, &inv.CreatedBy,
+		&inv.CreatedAt, &inv.ExpiresAt, &inv.UsedAt, &inv.UsedBy)
+	return inv, err
+}
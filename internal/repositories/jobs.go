@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"hash/fnv"
+	"pvz-service/internal/logger"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JobLocker держит сессионную advisory-блокировку на отдельном соединении
+// пула, пока задача выполняется.
+type JobLocker struct {
+	db *pgxpool.Pool
+}
+
+func NewJobLocker(db *pgxpool.Pool) *JobLocker {
+	return &JobLocker{db: db}
+}
+
+func (l *JobLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	c, err := l.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := jobLockKey(name)
+	var ok bool
+	if err := c.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil {
+		c.Release()
+		return nil, false, err
+	}
+	if !ok {
+		c.Release()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// контекст задачи к этому моменту может быть уже отменен
+		if _, err := c.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			logger.FromContext(ctx).Errorf("не удалось снять блокировку задачи %s: %v", name, err)
+			// закрытое соединение освобождает все сессионные блокировки
+			c.Conn().Close(context.Background())
+		}
+		c.Release()
+	}
+
+	return unlock, true, nil
+}
+
+func jobLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("pvz-service:job:" + name))
+	return int64(h.Sum64())
+}
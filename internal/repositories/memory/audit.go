@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"time"
+)
+
+type AuditRepository struct {
+	store *Store
+}
+
+func (r *AuditRepository) Append(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	defer r.store.lock(ctx)()
+
+	entry.PrevHash = ""
+	if n := len(r.store.audit); n > 0 {
+		entry.PrevHash = r.store.audit[n-1].Hash
+	}
+
+	entry.ID = int64(len(r.store.audit) + 1)
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+	r.store.audit = append(r.store.audit, entry)
+
+	return entry, nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entries := make([]models.AuditEntry, 0)
+	for i := len(r.store.audit) - 1; i >= 0; i-- {
+		e := r.store.audit[i]
+		if !matchAudit(e, filter) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	return append(make([]models.AuditEntry, 0), page(entries, filter.Limit, filter.Offset)...), nil
+}
+
+func (r *AuditRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entries := make([]models.AuditEntry, 0)
+	for _, e := range r.store.audit {
+		if e.ID > afterID {
+			entries = append(entries, e)
+		}
+	}
+
+	return append(make([]models.AuditEntry, 0), page(entries, limit, 0)...), nil
+}
+
+func matchAudit(e models.AuditEntry, f models.AuditFilter) bool {
+	return (f.ActorID == "" || e.ActorID == f.ActorID) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.TargetType == "" || e.TargetType == f.TargetType) &&
+		(f.TargetID == "" || e.TargetID == f.TargetID) &&
+		(f.RequestID == "" || e.RequestID == f.RequestID) &&
+		(f.From.IsZero() || !e.CreatedAt.Before(f.From)) &&
+		(f.To.IsZero() || !e.CreatedAt.After(f.To))
+}
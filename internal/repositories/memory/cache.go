@@ -0,0 +1,15 @@
+package memory
+
+import "context"
+
+// CacheNotifier ничего не рассылает: in-memory хранилище не разделяется
+// между репликами, а свой кэш сервис сбрасывает сам.
+type CacheNotifier struct{}
+
+func (CacheNotifier) Notify(ctx context.Context, key string) error {
+	return nil
+}
+
+func (CacheNotifier) Listen(ctx context.Context, evict func(ctx context.Context, key string), reset func(ctx context.Context)) {
+	<-ctx.Done()
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"sort"
+	"time"
+)
+
+type InvitationRepository struct {
+	store *Store
+}
+
+func (r *InvitationRepository) Create(ctx context.Context, inv models.Invitation) (models.Invitation, error) {
+	defer r.store.lock(ctx)()
+
+	if inv.PvzID != nil {
+		if _, ok := r.store.pvzs[*inv.PvzID]; !ok {
+			return models.Invitation{}, errors.ErrNotFound
+		}
+	}
+	for _, existing := range r.store.invitations {
+		if existing.TokenHash == inv.TokenHash {
+			return models.Invitation{}, errors.ErrAlreadyExists
+		}
+	}
+
+	inv.ID = newID()
+	inv.CreatedAt = time.Now()
+	r.store.invitations[inv.ID] = inv
+
+	return inv, nil
+}
+
+func (r *InvitationRepository) GetByTokenHashForUpdate(ctx context.Context, tokenHash string) (models.Invitation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, inv := range r.store.invitations {
+		if inv.TokenHash == tokenHash {
+			return inv, nil
+		}
+	}
+
+	return models.Invitation{}, errors.ErrNotFound
+}
+
+func (r *InvitationRepository) MarkUsed(ctx context.Context, id, userID string, at time.Time) error {
+	defer r.store.lock(ctx)()
+
+	inv, ok := r.store.invitations[id]
+	if !ok || inv.UsedAt != nil {
+		return errors.ErrNotFound
+	}
+
+	inv.UsedAt = &at
+	inv.UsedBy = &userID
+	r.store.invitations[id] = inv
+
+	return nil
+}
+
+func (r *InvitationRepository) List(ctx context.Context, limit, offset int) ([]models.Invitation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	invitations := make([]models.Invitation, 0, len(r.store.invitations))
+	for _, inv := range r.store.invitations {
+		invitations = append(invitations, inv)
+	}
+
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].CreatedAt.After(invitations[j].CreatedAt)
+	})
+
+	if invitations = page(invitations, limit, offset); invitations == nil {
+		invitations = make([]models.Invitation, 0)
+	}
+
+	return invitations, nil
+}
+
+func (r *InvitationRepository) Delete(ctx context.Context, id string) error {
+	defer r.store.lock(ctx)()
+
+	inv, ok := r.store.invitations[id]
+	if !ok || inv.UsedAt != nil {
+		return errors.ErrNotFound
+	}
+
+	delete(r.store.invitations, id)
+	return nil
+}
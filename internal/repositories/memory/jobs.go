@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+// JobLocker работает в пределах процесса: in-memory хранилище не
+// разделяется между репликами.
+type JobLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (l *JobLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held[name] {
+		return nil, false, nil
+	}
+	l.held[name] = true
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+	}, true, nil
+}
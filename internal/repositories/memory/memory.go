@@ -0,0 +1,73 @@
+// Package memory - потокобезопасная in-memory реализация хранилищ для
+// локальной разработки и демо (STORAGE=memory). Данные живут до перезапуска.
+package memory
+
+import (
+	"crypto/rand"
+	"fmt"
+	"pvz-service/config"
+	"pvz-service/internal/models"
+	"pvz-service/internal/repositories"
+	"sync"
+)
+
+// Store хранит все сущности под одной блокировкой, чтобы каскадное удаление
+// и проверки ссылочной целостности видели согласованное состояние.
+type Store struct {
+	txMu        sync.Mutex
+	mu          sync.RWMutex
+	users       map[string]models.User
+	pvzs        map[string]models.PVZ
+	receptions  map[string]models.Reception
+	products    map[string]models.Product
+	audit       []models.AuditEntry
+	invitations map[string]models.Invitation
+	userTokens  map[string]models.UserToken
+	// workingHours - недельные расписания по id ПВЗ
+	workingHours map[string][]models.WorkingHours
+	exceptions   map[string]models.ScheduleException
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:        make(map[string]models.User),
+		pvzs:         make(map[string]models.PVZ),
+		receptions:   make(map[string]models.Reception),
+		products:     make(map[string]models.Product),
+		invitations:  make(map[string]models.Invitation),
+		userTokens:   make(map[string]models.UserToken),
+		workingHours: make(map[string][]models.WorkingHours),
+		exceptions:   make(map[string]models.ScheduleException),
+	}
+}
+
+func NewRepos(cfg *config.Config) *repositories.Repos {
+	store := NewStore()
+
+	return &repositories.Repos{
+		Cfg:            cfg,
+		AuthRepo:       &UserRepository{store: store},
+		PvzRepo:        &PVZRepository{store: store},
+		ProductRepo:    &ProductRepository{store: store},
+		ReceptionRepo:  &ReceptionRepository{store: store},
+		ScheduleRepo:   &ScheduleRepository{store: store},
+		AuditRepo:      &AuditRepository{store: store},
+		InvitationRepo: &InvitationRepository{store: store},
+		UserTokenRepo:  &UserTokenRepository{store: store},
+		TxManager:      &TxManager{store: store},
+		JobLocker:      &JobLocker{held: make(map[string]bool)},
+		CacheNotifier:  CacheNotifier{},
+	}
+}
+
+// newID генерирует UUID v4, как gen_random_uuid() в postgres
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
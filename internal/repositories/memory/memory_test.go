@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"pvz-service/config"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/repositories/repotest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repositories.Repos {
+		return NewRepos(&config.Config{})
+	})
+}
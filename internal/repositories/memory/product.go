@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"sort"
+)
+
+type ProductRepository struct {
+	store *Store
+}
+
+func (r *ProductRepository) AddProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.receptions[product.ReceptionId]; !ok {
+		return models.Product{}, errors.ErrNotFound
+	}
+
+	product.ID = newID()
+	if product.Status == "" {
+		product.Status = models.ProductAccepted
+	}
+	r.store.products[product.ID] = product
+
+	return product, nil
+}
+
+func (r *ProductRepository) DeleteLastProduct(ctx context.Context, receptionId string) (models.Product, error) {
+	defer r.store.lock(ctx)()
+
+	products := r.byReception(receptionId)
+	if len(products) == 0 {
+		return models.Product{}, errors.ErrNotFound
+	}
+
+	last := products[0]
+	if last.Status != models.ProductAccepted {
+		return models.Product{}, errors.ErrProductStatus
+	}
+	delete(r.store.products, last.ID)
+
+	return last, nil
+}
+
+func (r *ProductRepository) GetByReceptionID(ctx context.Context, receptionID string) ([]models.Product, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	products := r.byReception(receptionID)
+	for i := range products {
+		products[i].ReceptionId = ""
+		products[i].PickupCodeHash = ""
+		products[i].PickupAttempts = 0
+	}
+
+	return products, nil
+}
+
+// byReception возвращает товары приемки от последнего к первому.
+// Вызывается под блокировкой store.
+func (r *ProductRepository) byReception(receptionID string) []models.Product {
+	var products []models.Product
+	for _, p := range r.store.products {
+		if p.ReceptionId == receptionID {
+			products = append(products, p)
+		}
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		if !products[i].DateTime.Equal(products[j].DateTime) {
+			return products[i].DateTime.After(products[j].DateTime)
+		}
+		return products[i].ID < products[j].ID
+	})
+
+	return products
+}
+
+func (r *ProductRepository) ListByReceptionID(ctx context.Context, receptionID string, limit, offset int) ([]models.Product, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return append(make([]models.Product, 0), page(r.byReception(receptionID), limit, offset)...), nil
+}
+
+// GetByIDForUpdate не блокирует отдельный товар: транзакции в памяти и так
+// выполняются по одной
+func (r *ProductRepository) GetByIDForUpdate(ctx context.Context, id string) (models.Product, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	product, ok := r.store.products[id]
+	if !ok {
+		return models.Product{}, errors.ErrNotFound
+	}
+
+	return product, nil
+}
+
+func (r *ProductRepository) UpdatePickup(ctx context.Context, product models.Product) error {
+	defer r.store.lock(ctx)()
+
+	stored, ok := r.store.products[product.ID]
+	if !ok {
+		return errors.ErrNotFound
+	}
+
+	stored.Status = product.Status
+	stored.RecipientEmail = product.RecipientEmail
+	stored.PickupCodeHash = product.PickupCodeHash
+	stored.PickupAttempts = product.PickupAttempts
+	stored.IssuedAt = product.IssuedAt
+	r.store.products[product.ID] = stored
+
+	return nil
+}
+
+func (r *ProductRepository) MarkReadyForPickup(ctx context.Context, receptionID string) error {
+	defer r.store.lock(ctx)()
+
+	for id, product := range r.store.products {
+		if product.ReceptionId == receptionID && product.Status == models.ProductAccepted {
+			product.Status = models.ProductReadyForPickup
+			r.store.products[id] = product
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/geo"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PVZRepository struct {
+	store *Store
+}
+
+func (r *PVZRepository) GetAll(ctx context.Context, filter models.PVZFilter) ([]models.FullPVZ, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	inDays := func(period models.DayPeriod, t time.Time, zone string) bool {
+		if filter.Period.TimeZone != "" {
+			zone = filter.Period.TimeZone
+		}
+		from, to := period.Bounds(models.Location(zone))
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+	}
+
+	receptionsByPVZ := make(map[string][]models.Reception)
+	lastReception := make(map[string]time.Time)
+	hasOpen := make(map[string]bool)
+	for _, reception := range r.store.receptions {
+		if reception.DateTime.After(lastReception[reception.PvzId]) {
+			lastReception[reception.PvzId] = reception.DateTime
+		}
+		if reception.Status == "in_progress" {
+			hasOpen[reception.PvzId] = true
+		}
+		if inDays(filter.Period, reception.DateTime, r.store.pvzs[reception.PvzId].TimeZone) {
+			receptionsByPVZ[reception.PvzId] = append(receptionsByPVZ[reception.PvzId], reception)
+		}
+	}
+
+	productsByReception := make(map[string]int)
+	for _, product := range r.store.products {
+		productsByReception[product.ReceptionId]++
+	}
+
+	pvzs := make([]models.PVZ, 0, len(r.store.pvzs))
+	for _, pvz := range r.store.pvzs {
+		if len(receptionsByPVZ[pvz.ID]) == 0 {
+			continue
+		}
+		if filter.City != "" && pvz.City != filter.City {
+			continue
+		}
+		if !inDays(filter.Registered, pvz.RegistrationDate, pvz.TimeZone) {
+			continue
+		}
+		if filter.HasOpenReception != nil && *filter.HasOpenReception != hasOpen[pvz.ID] {
+			continue
+		}
+		if filter.MinProducts > 0 {
+			products := 0
+			for _, reception := range receptionsByPVZ[pvz.ID] {
+				products += productsByReception[reception.ID]
+			}
+			if products < filter.MinProducts {
+				continue
+			}
+		}
+		pvzs = append(pvzs, pvz)
+	}
+
+	compare, ok := pvzCompare(filter.Sort, lastReception)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort field %q", errors.ErrInvalidInput, filter.Sort)
+	}
+	sort.Slice(pvzs, func(i, j int) bool {
+		a, b := pvzs[i], pvzs[j]
+		// ПВЗ без приемок в конце при любом направлении, как NULLS LAST
+		if filter.Sort == models.PVZSortLastReception {
+			if noA, noB := lastReception[a.ID].IsZero(), lastReception[b.ID].IsZero(); noA != noB {
+				return noB
+			}
+		}
+		if c := compare(a, b); c != 0 {
+			return (c < 0) != (filter.Order == models.SortDesc)
+		}
+		return a.ID < b.ID
+	})
+
+	result := make([]models.FullPVZ, 0)
+	for _, pvz := range page(pvzs, filter.Limit, filter.Offset) {
+		full := models.FullPVZ{
+			ID:               pvz.ID,
+			RegistrationDate: pvz.RegistrationDate,
+			City:             pvz.City,
+			Address:          pvz.Address,
+			Latitude:         pvz.Latitude,
+			Longitude:        pvz.Longitude,
+			TimeZone:         pvz.TimeZone,
+			Receptions:       make(map[string]models.FullReception),
+		}
+		for _, reception := range receptionsByPVZ[pvz.ID] {
+			full.Receptions[reception.ID] = models.FullReception{
+				ID:       reception.ID,
+				PvzId:    reception.PvzId,
+				Status:   reception.Status,
+				DateTime: reception.DateTime,
+				Products: make([]models.Product, 0),
+			}
+		}
+		result = append(result, full)
+	}
+
+	return result, nil
+}
+
+func (r *PVZRepository) CreatePVZ(ctx context.Context, pvz models.PVZ) (models.PVZ, error) {
+	if !models.PVZCities[pvz.City] {
+		return models.PVZ{}, errors.ErrCityNotAllowed
+	}
+
+	defer r.store.lock(ctx)()
+
+	pvz.ID = newID()
+	pvz.RegistrationDate = time.Now().UTC()
+	r.store.pvzs[pvz.ID] = pvz
+
+	return pvz, nil
+}
+
+func (r *PVZRepository) CreatePVZBatch(ctx context.Context, pvzs []models.PVZ) ([]models.PVZ, error) {
+	defer r.store.lock(ctx)()
+
+	now := time.Now().UTC()
+	created := make([]models.PVZ, 0, len(pvzs))
+	for i, pvz := range pvzs {
+		if !models.PVZCities[pvz.City] {
+			return nil, &errors.BatchError{Index: i, Err: errors.ErrCityNotAllowed}
+		}
+		if pvz.RegistrationDate.IsZero() {
+			pvz.RegistrationDate = now
+		}
+		pvz.ID = newID()
+		created = append(created, pvz)
+	}
+
+	for _, pvz := range created {
+		r.store.pvzs[pvz.ID] = pvz
+	}
+
+	return created, nil
+}
+
+func (r *PVZRepository) GetPVZByID(ctx context.Context, id string) (models.PVZ, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	pvz, ok := r.store.pvzs[id]
+	if !ok {
+		return models.PVZ{}, errors.ErrNotFound
+	}
+
+	return pvz, nil
+}
+
+func (r *PVZRepository) GetPVZByIDForUpdate(ctx context.Context, id string) (models.PVZ, error) {
+	return r.GetPVZByID(ctx, id)
+}
+
+func (r *PVZRepository) List(ctx context.Context, limit, offset int) ([]models.PVZ, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	pvzs := make([]models.PVZ, 0, len(r.store.pvzs))
+	for _, pvz := range r.store.pvzs {
+		pvzs = append(pvzs, pvz)
+	}
+	sortPVZ(pvzs)
+
+	return append(make([]models.PVZ, 0), page(pvzs, limit, offset)...), nil
+}
+
+func (r *PVZRepository) Nearby(ctx context.Context, q models.NearbyQuery) ([]models.NearbyPVZ, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	result := make([]models.NearbyPVZ, 0)
+	for _, pvz := range r.store.pvzs {
+		if pvz.Latitude == nil || pvz.Longitude == nil {
+			continue
+		}
+		distance := geo.Distance(q.Lat, q.Lon, *pvz.Latitude, *pvz.Longitude)
+		if distance > q.Radius {
+			continue
+		}
+		result = append(result, models.NearbyPVZ{PVZ: pvz, Distance: distance})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Distance != result[j].Distance {
+			return result[i].Distance < result[j].Distance
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return append(make([]models.NearbyPVZ, 0), page(result, q.Limit, q.Offset)...), nil
+}
+
+func (r *PVZRepository) UpdateTimeZone(ctx context.Context, id, timeZone string) error {
+	defer r.store.lock(ctx)()
+
+	pvz, ok := r.store.pvzs[id]
+	if !ok {
+		return errors.ErrNotFound
+	}
+	pvz.TimeZone = timeZone
+	r.store.pvzs[id] = pvz
+
+	return nil
+}
+
+// DeletePVZ удаляет ПВЗ вместе с приемками, товарами, расписанием и приглашениями и
+// отвязывает сотрудников, как внешние ключи в postgres
+func (r *PVZRepository) DeletePVZ(ctx context.Context, id string) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.pvzs[id]; !ok {
+		return errors.ErrNotFound
+	}
+
+	delete(r.store.pvzs, id)
+	delete(r.store.workingHours, id)
+	for exceptionID, e := range r.store.exceptions {
+		if e.PvzID == id {
+			delete(r.store.exceptions, exceptionID)
+		}
+	}
+	for receptionID, reception := range r.store.receptions {
+		if reception.PvzId != id {
+			continue
+		}
+		delete(r.store.receptions, receptionID)
+		for productID, product := range r.store.products {
+			if product.ReceptionId == receptionID {
+				delete(r.store.products, productID)
+			}
+		}
+	}
+	for invID, inv := range r.store.invitations {
+		if inv.PvzID != nil && *inv.PvzID == id {
+			delete(r.store.invitations, invID)
+		}
+	}
+	for userID, user := range r.store.users {
+		if user.PvzID != nil && *user.PvzID == id {
+			user.PvzID = nil
+			r.store.users[userID] = user
+		}
+	}
+
+	return nil
+}
+
+// pvzCompare возвращает сравнение ПВЗ по полю сортировки
+func pvzCompare(field string, lastReception map[string]time.Time) (func(a, b models.PVZ) int, bool) {
+	switch field {
+	case models.PVZSortRegistrationDate:
+		return func(a, b models.PVZ) int { return a.RegistrationDate.Compare(b.RegistrationDate) }, true
+	case models.PVZSortCity:
+		return func(a, b models.PVZ) int { return strings.Compare(a.City, b.City) }, true
+	case models.PVZSortLastReception:
+		return func(a, b models.PVZ) int { return lastReception[a.ID].Compare(lastReception[b.ID]) }, true
+	}
+	return nil, false
+}
+
+func sortPVZ(pvzs []models.PVZ) {
+	sort.Slice(pvzs, func(i, j int) bool {
+		if !pvzs[i].RegistrationDate.Equal(pvzs[j].RegistrationDate) {
+			return pvzs[i].RegistrationDate.After(pvzs[j].RegistrationDate)
+		}
+		return pvzs[i].ID < pvzs[j].ID
+	})
+}
+
+func page[T any](items []T, limit, offset int) []T {
+	offset = max(offset, 0)
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
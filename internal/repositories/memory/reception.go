@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"sort"
+	"time"
+)
+
+type ReceptionRepository struct {
+	store *Store
+}
+
+func (r *ReceptionRepository) CreateReception(ctx context.Context, reception models.Reception) (models.Reception, error) {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.pvzs[reception.PvzId]; !ok {
+		return models.Reception{}, errors.ErrNotFound
+	}
+	// как частичный уникальный индекс reception_single_active в postgres
+	for _, existing := range r.store.receptions {
+		if existing.PvzId == reception.PvzId && existing.Status == "in_progress" {
+			return models.Reception{}, errors.ErrInvalidInput
+		}
+	}
+
+	reception.ID = newID()
+	reception.Status = "in_progress"
+	if reception.DateTime.IsZero() {
+		reception.DateTime = time.Now().UTC()
+	}
+	r.store.receptions[reception.ID] = reception
+
+	return reception, nil
+}
+
+func (r *ReceptionRepository) GetActiveReceptionByPVZID(ctx context.Context, pvzID string) (*models.Reception, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, reception := range r.store.receptions {
+		if reception.PvzId == pvzID && reception.Status == "in_progress" {
+			return &reception, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetActiveReceptionByPVZIDForUpdate не блокирует отдельную строку:
+// транзакции в памяти и так выполняются по одной.
+func (r *ReceptionRepository) GetActiveReceptionByPVZIDForUpdate(ctx context.Context, pvzID string) (*models.Reception, error) {
+	return r.GetActiveReceptionByPVZID(ctx, pvzID)
+}
+
+func (r *ReceptionRepository) ListStale(ctx context.Context, idleSince time.Time) ([]models.Reception, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	lastActivity := make(map[string]time.Time)
+	for _, p := range r.store.products {
+		if p.DateTime.After(lastActivity[p.ReceptionId]) {
+			lastActivity[p.ReceptionId] = p.DateTime
+		}
+	}
+
+	var receptions []models.Reception
+	for _, reception := range r.store.receptions {
+		if reception.Status != "in_progress" || !reception.DateTime.Before(idleSince) {
+			continue
+		}
+		if lastActivity[reception.ID].Before(idleSince) {
+			receptions = append(receptions, reception)
+		}
+	}
+
+	sort.Slice(receptions, func(i, j int) bool {
+		return receptions[i].DateTime.Before(receptions[j].DateTime)
+	})
+
+	return receptions, nil
+}
+
+func (r *ReceptionRepository) CloseReception(ctx context.Context, receptionId string) error {
+	defer r.store.lock(ctx)()
+
+	reception, ok := r.store.receptions[receptionId]
+	if !ok {
+		return errors.ErrNotFound
+	}
+
+	reception.Status = "close"
+	r.store.receptions[receptionId] = reception
+
+	return nil
+}
+
+func (r *ReceptionRepository) GetByID(ctx context.Context, id string) (models.Reception, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	reception, ok := r.store.receptions[id]
+	if !ok {
+		return models.Reception{}, errors.ErrNotFound
+	}
+
+	return reception, nil
+}
+
+func (r *ReceptionRepository) List(ctx context.Context, filter models.ReceptionFilter) ([]models.Reception, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	zone := filter.Period.TimeZone
+	if zone == "" {
+		zone = r.store.pvzs[filter.PvzID].TimeZone
+	}
+	from, to := filter.Period.Bounds(models.Location(zone))
+
+	receptions := make([]models.Reception, 0)
+	for _, reception := range r.store.receptions {
+		if reception.PvzId != filter.PvzID || (filter.Status != "" && reception.Status != filter.Status) {
+			continue
+		}
+		if (!from.IsZero() && reception.DateTime.Before(from)) || (!to.IsZero() && !reception.DateTime.Before(to)) {
+			continue
+		}
+		receptions = append(receptions, reception)
+	}
+
+	sort.Slice(receptions, func(i, j int) bool {
+		if !receptions[i].DateTime.Equal(receptions[j].DateTime) {
+			return receptions[i].DateTime.After(receptions[j].DateTime)
+		}
+		return receptions[i].ID < receptions[j].ID
+	})
+
+	return append(make([]models.Reception, 0), page(receptions, filter.Limit, filter.Offset)...), nil
+}
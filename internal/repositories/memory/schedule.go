@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+type ScheduleRepository struct {
+	store *Store
+}
+
+func (r *ScheduleRepository) GetSchedules(ctx context.Context, pvzIDs []string) (map[string]models.PVZSchedule, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	since := time.Now().UTC().AddDate(0, 0, -1).Format(models.DateLayout)
+
+	result := make(map[string]models.PVZSchedule, len(pvzIDs))
+	for _, id := range pvzIDs {
+		result[id] = models.PVZSchedule{
+			PvzID:      id,
+			Week:       append(make([]models.WorkingHours, 0), r.store.workingHours[id]...),
+			Exceptions: make([]models.ScheduleException, 0),
+		}
+	}
+
+	for _, e := range r.store.exceptions {
+		schedule, ok := result[e.PvzID]
+		if !ok || e.EndDate < since {
+			continue
+		}
+		schedule.Exceptions = append(schedule.Exceptions, e)
+		result[e.PvzID] = schedule
+	}
+
+	for _, schedule := range result {
+		sort.Slice(schedule.Exceptions, func(i, j int) bool {
+			return schedule.Exceptions[i].StartDate < schedule.Exceptions[j].StartDate
+		})
+	}
+
+	return result, nil
+}
+
+func (r *ScheduleRepository) ReplaceWeek(ctx context.Context, pvzID string, week []models.WorkingHours) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.pvzs[pvzID]; !ok {
+		return errors.ErrNotFound
+	}
+
+	week = slices.Clone(week)
+	sort.Slice(week, func(i, j int) bool { return week[i].Weekday < week[j].Weekday })
+	r.store.workingHours[pvzID] = week
+
+	return nil
+}
+
+func (r *ScheduleRepository) CreateException(ctx context.Context, e models.ScheduleException) (models.ScheduleException, error) {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.pvzs[e.PvzID]; !ok {
+		return models.ScheduleException{}, errors.ErrNotFound
+	}
+
+	e.ID = newID()
+	r.store.exceptions[e.ID] = e
+
+	return e, nil
+}
+
+func (r *ScheduleRepository) DeleteException(ctx context.Context, pvzID, id string) (models.ScheduleException, error) {
+	defer r.store.lock(ctx)()
+
+	e, ok := r.store.exceptions[id]
+	if !ok || e.PvzID != pvzID {
+		return models.ScheduleException{}, errors.ErrNotFound
+	}
+	delete(r.store.exceptions, id)
+
+	return e, nil
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"pvz-service/internal/models"
+	"slices"
+)
+
+type txKey struct{}
+
+// TxManager сериализует транзакции и изменяющие вызовы вне транзакций через
+// Store.txMu. При ошибке fn состояние хранилища восстанавливается из снимка.
+// Чтение не блокируется и может видеть незафиксированные изменения.
+type TxManager struct {
+	store *Store
+}
+
+func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if inTx(ctx) {
+		return fn(ctx)
+	}
+
+	m.store.txMu.Lock()
+	defer m.store.txMu.Unlock()
+
+	snap := m.store.snapshot()
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		m.store.restore(snap)
+		return err
+	}
+
+	return nil
+}
+
+func inTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(bool)
+	return ok
+}
+
+// lock захватывает хранилище на запись. Вне транзакции дополнительно ждет
+// завершения текущей транзакции, чтобы ее откат не затер чужие изменения.
+func (s *Store) lock(ctx context.Context) func() {
+	own := !inTx(ctx)
+	if own {
+		s.txMu.Lock()
+	}
+	s.mu.Lock()
+
+	return func() {
+		s.mu.Unlock()
+		if own {
+			s.txMu.Unlock()
+		}
+	}
+}
+
+type snapshot struct {
+	users       map[string]models.User
+	pvzs        map[string]models.PVZ
+	receptions  map[string]models.Reception
+	products    map[string]models.Product
+	audit       []models.AuditEntry
+	invitations map[string]models.Invitation
+	userTokens  map[string]models.UserToken
+	hours       map[string][]models.WorkingHours
+	exceptions  map[string]models.ScheduleException
+}
+
+func (s *Store) snapshot() snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return snapshot{
+		users:       maps.Clone(s.users),
+		pvzs:        maps.Clone(s.pvzs),
+		receptions:  maps.Clone(s.receptions),
+		products:    maps.Clone(s.products),
+		audit:       slices.Clone(s.audit),
+		invitations: maps.Clone(s.invitations),
+		userTokens:  maps.Clone(s.userTokens),
+		hours:       maps.Clone(s.workingHours),
+		exceptions:  maps.Clone(s.exceptions),
+	}
+}
+
+func (s *Store) restore(snap snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = snap.users
+	s.pvzs = snap.pvzs
+	s.receptions = snap.receptions
+	s.products = snap.products
+	s.audit = snap.audit
+	s.invitations = snap.invitations
+	s.userTokens = snap.userTokens
+	s.workingHours = snap.hours
+	s.exceptions = snap.exceptions
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+type UserRepository struct {
+	store *Store
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	defer r.store.lock(ctx)()
+
+	for _, u := range r.store.users {
+		if u.Email == user.Email {
+			return models.User{}, errors.ErrAlreadyExists
+		}
+	}
+
+	if user.PvzID != nil {
+		if _, ok := r.store.pvzs[*user.PvzID]; !ok {
+			return models.User{}, errors.ErrNotFound
+		}
+	}
+
+	user.ID = newID()
+	r.store.users[user.ID] = user
+
+	return user, nil
+}
+
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.store.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return models.User{}, errors.ErrNotFound
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id string) (models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return models.User{}, errors.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) IncrementFailedLogins(ctx context.Context, id string) (int, error) {
+	var failures int
+	err := r.update(ctx, id, func(u *models.User) {
+		u.FailedLogins++
+		failures = u.FailedLogins
+	})
+
+	return failures, err
+}
+
+func (r *UserRepository) LockUser(ctx context.Context, id string, until time.Time) error {
+	return r.update(ctx, id, func(u *models.User) {
+		u.LockedUntil = &until
+	})
+}
+
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	return r.update(ctx, id, func(u *models.User) {
+		u.FailedLogins = 0
+		u.LockedUntil = nil
+	})
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id, passwordHash string, at time.Time) error {
+	return r.update(ctx, id, func(u *models.User) {
+		u.Password = passwordHash
+		u.PasswordChangedAt = &at
+	})
+}
+
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id string, at time.Time) error {
+	return r.update(ctx, id, func(u *models.User) {
+		u.EmailVerifiedAt = &at
+	})
+}
+
+func (r *UserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	query := strings.ToLower(filter.Query)
+	users := make([]models.User, 0)
+	for _, u := range r.store.users {
+		if query != "" && !strings.Contains(strings.ToLower(u.Email), query) {
+			continue
+		}
+		if filter.Role != "" && u.Role != filter.Role {
+			continue
+		}
+		if filter.Status == models.UserStatusActive && u.DeactivatedAt != nil ||
+			filter.Status == models.UserStatusDeactivated && u.DeactivatedAt == nil {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Email < users[j].Email
+	})
+
+	if users = page(users, filter.Limit, filter.Offset); users == nil {
+		users = make([]models.User, 0)
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id, role string) error {
+	return r.update(ctx, id, func(u *models.User) {
+		u.Role = role
+	})
+}
+
+func (r *UserRepository) UpdateEmail(ctx context.Context, id, email string) error {
+	defer r.store.lock(ctx)()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return errors.ErrNotFound
+	}
+	for _, u := range r.store.users {
+		if u.Email == email && u.ID != id {
+			return errors.ErrAlreadyExists
+		}
+	}
+
+	user.Email = email
+	user.EmailVerifiedAt = nil
+	r.store.users[id] = user
+
+	return nil
+}
+
+func (r *UserRepository) SetDeactivated(ctx context.Context, id string, at *time.Time) error {
+	return r.update(ctx, id, func(u *models.User) {
+		u.DeactivatedAt = at
+	})
+}
+
+func (r *UserRepository) update(ctx context.Context, id string, fn func(u *models.User)) error {
+	defer r.store.lock(ctx)()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return errors.ErrNotFound
+	}
+
+	fn(&user)
+	r.store.users[id] = user
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"time"
+)
+
+type UserTokenRepository struct {
+	store *Store
+}
+
+func (r *UserTokenRepository) Create(ctx context.Context, token models.UserToken) (models.UserToken, error) {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.users[token.UserID]; !ok {
+		return models.UserToken{}, errors.ErrNotFound
+	}
+	for _, existing := range r.store.userTokens {
+		if existing.TokenHash == token.TokenHash {
+			return models.UserToken{}, errors.ErrAlreadyExists
+		}
+	}
+
+	token.ID = newID()
+	token.CreatedAt = time.Now()
+	r.store.userTokens[token.ID] = token
+
+	return token, nil
+}
+
+func (r *UserTokenRepository) GetByHashForUpdate(ctx context.Context, purpose, tokenHash string) (models.UserToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.userTokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return models.UserToken{}, errors.ErrNotFound
+}
+
+func (r *UserTokenRepository) MarkUsed(ctx context.Context, id string, at time.Time) error {
+	defer r.store.lock(ctx)()
+
+	token, ok := r.store.userTokens[id]
+	if !ok || token.UsedAt != nil {
+		return errors.ErrNotFound
+	}
+
+	token.UsedAt = &at
+	r.store.userTokens[id] = token
+
+	return nil
+}
+
+func (r *UserTokenRepository) InvalidateUser(ctx context.Context, userID, purpose string, at time.Time) error {
+	defer r.store.lock(ctx)()
+
+	for id, token := range r.store.userTokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &at
+			r.store.userTokens[id] = token
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func isUniqueViolation(err error) bool {
+	return pgErrorCode(err) == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	return pgErrorCode(err) == "23503"
+}
+
+// checkViolation возвращает имя нарушенного ограничения CHECK или ""
+func checkViolation(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// isInvalidText - значение не разбирается в тип колонки, например id не UUID
+func isInvalidText(err error) bool {
+	return pgErrorCode(err) == "22P02"
+}
@@ -0,0 +1,56 @@
+package repositories_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"pvz-service/config"
+	"pvz-service/internal/database"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/repositories/repotest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConformance прогоняет общий набор тестов на postgres. Нужна база в
+// TEST_DATABASE_URL; каждый подтест получает отдельную схему с миграциями.
+// Локально без базы тест пропускается, в CI (задана переменная CI) падает:
+// иначе расхождения memory и postgres проходят незамеченными. Запуск - make test-conformance.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_DATABASE_URL must be set in CI")
+		}
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	repotest.Run(t, func(t *testing.T) *repositories.Repos {
+		ctx := context.Background()
+		schema := fmt.Sprintf("repotest_%d", time.Now().UnixNano())
+
+		admin, err := pgxpool.New(ctx, dsn)
+		require.NoError(t, err)
+		_, err = admin.Exec(ctx, "CREATE SCHEMA "+schema)
+		require.NoError(t, err)
+
+		poolCfg, err := pgxpool.ParseConfig(dsn)
+		require.NoError(t, err)
+		poolCfg.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+		pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			pool.Close()
+			_, _ = admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+			admin.Close()
+		})
+
+		require.NoError(t, database.AutoMigrate(ctx, pool))
+
+		return repositories.NewRepos(&config.Config{}, pool, nil)
+	})
+}
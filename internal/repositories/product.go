@@ -3,96 +3,128 @@ package repositories
 import (
 	"context"
 	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/sirupsen/logrus"
 )
 
 type ProductRepository struct {
-	db   *pgxpool.Pool
-	psql sq.StatementBuilderType
+	db       *pgxpool.Pool
+	replicas ReadRouter
+	psql     sq.StatementBuilderType
 }
 
-func NewProductRepository(db *pgxpool.Pool) *ProductRepository {
-	return &ProductRepository{db: db, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+func NewProductRepository(db *pgxpool.Pool, replicas ReadRouter) *ProductRepository {
+	return &ProductRepository{db: db, replicas: replicas, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
 }
 
-func (r *ProductRepository) AddProduct(ctx context.Context, product models.Product) error {
-	tx, err := r.db.Begin(ctx)
+func (r *ProductRepository) AddProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
 	if err != nil {
-		return err
+		return models.Product{}, err
 	}
 	defer tx.Rollback(ctx)
 
+	if product.Status == "" {
+		product.Status = models.ProductAccepted
+	}
+
 	query, args, err := r.psql.Insert("products").
-		Columns("date_time", "type", "reception_id").
-		Values(product.DateTime, product.Type, product.ReceptionId).
+		Columns("date_time", "type", "reception_id", "status", "recipient_email", "pickup_code_hash").
+		Values(product.DateTime, product.Type, product.ReceptionId, product.Status, nullIfEmpty(product.RecipientEmail), nullIfEmpty(product.PickupCodeHash)).
+		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
-		return err
+		return models.Product{}, err
 	}
 
-	_, err = tx.Exec(ctx, query, args...)
+	err = tx.QueryRow(ctx, query, args...).Scan(&product.ID)
+	if isForeignKeyViolation(err) {
+		return models.Product{}, errors.ErrNotFound
+	}
 	if err != nil {
-		return err
+		return models.Product{}, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return err
+		return models.Product{}, err
 	}
 
-	return nil
+	return product, nil
 
 }
 
-func (r *ProductRepository) DeleteLastProduct(ctx context.Context, receptionId string) error {
-	tx, err := r.db.Begin(ctx)
+// DeleteLastProduct удаляет последний добавленный товар приемки и возвращает его.
+// Товар, который уже не просто принят (готов к выдаче, выдан или возвращен),
+// не удаляется: возвращается ErrProductStatus.
+func (r *ProductRepository) DeleteLastProduct(ctx context.Context, receptionId string) (models.Product, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
 	if err != nil {
-		return err
+		return models.Product{}, err
 	}
 	defer tx.Rollback(ctx)
 
-	subQuery := r.psql.
-		Select("id").
+	query, args, err := r.psql.
+		Select("id", "status").
 		From("products").
 		Where(sq.Eq{"reception_id": receptionId}).
-		OrderBy("date_time DESC").
-		Limit(1)
-
-	query, args, err := r.psql.Delete("products").
-		Where(sq.Expr("id = (?)", subQuery)).
+		OrderBy("date_time DESC", "id").
+		Limit(1).
+		Suffix("FOR UPDATE").
 		ToSql()
+	if err != nil {
+		return models.Product{}, err
+	}
 
-	logrus.Info(query)
+	var id, status string
+	err = tx.QueryRow(ctx, query, args...).Scan(&id, &status)
+	if err == pgx.ErrNoRows {
+		return models.Product{}, errors.ErrNotFound
+	}
 	if err != nil {
-		return err
+		return models.Product{}, err
+	}
+	if status != models.ProductAccepted {
+		return models.Product{}, errors.ErrProductStatus
 	}
 
-	_, err = tx.Exec(ctx, query, args...)
+	query, args, err = r.psql.Delete("products").
+		Where(sq.Eq{"id": id, "status": models.ProductAccepted}).
+		Suffix("RETURNING " + productColumns).
+		ToSql()
 	if err != nil {
-		return err
+		return models.Product{}, err
+	}
+
+	product, err := scanProduct(tx.QueryRow(ctx, query, args...))
+	if err == pgx.ErrNoRows {
+		return models.Product{}, errors.ErrNotFound
+	}
+	if err != nil {
+		return models.Product{}, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return err
+		return models.Product{}, err
 	}
 
-	return nil
+	return product, nil
 }
 
 func (r *ProductRepository) GetByReceptionID(ctx context.Context, receptionID string) ([]models.Product, error) {
 	query, args, err := r.psql.
-		Select("id", "date_time", "type").
+		Select("id", "date_time", "type", "status", "issued_at").
 		From("products").
 		Where(sq.Eq{"reception_id": receptionID}).
-		OrderBy("date_time DESC").
+		OrderBy("date_time DESC", "id").
 		ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := r.db.Query(ctx, query, args...)
+	rows, err := readConn(ctx, r.db, r.replicas).Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +133,7 @@ func (r *ProductRepository) GetByReceptionID(ctx context.Context, receptionID st
 	var products []models.Product
 	for rows.Next() {
 		var p models.Product
-		if err := rows.Scan(&p.ID, &p.DateTime, &p.Type); err != nil {
+		if err := rows.Scan(&p.ID, &p.DateTime, &p.Type, &p.Status, &p.IssuedAt); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
@@ -109,3 +141,116 @@ func (r *ProductRepository) GetByReceptionID(ctx context.Context, receptionID st
 
 	return products, nil
 }
+
+// ListByReceptionID возвращает страницу товаров приемки, последние добавленные первыми
+func (r *ProductRepository) ListByReceptionID(ctx context.Context, receptionID string, limit, offset int) ([]models.Product, error) {
+	query, args, err := r.psql.
+		Select(productColumns).
+		From("products").
+		Where(sq.Eq{"reception_id": receptionID}).
+		OrderBy("date_time DESC", "id").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := readConn(ctx, r.db, r.replicas).Query(ctx, query, args...)
+	if isInvalidText(err) {
+		return []models.Product{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]models.Product, 0)
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+
+	return products, rows.Err()
+}
+
+// GetByIDForUpdate блокирует товар до конца транзакции, поэтому должен
+// вызываться внутри TxManager.WithinTransaction
+func (r *ProductRepository) GetByIDForUpdate(ctx context.Context, id string) (models.Product, error) {
+	query, args, err := r.psql.
+		Select(productColumns).
+		From("products").
+		Where(sq.Eq{"id": id}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	product, err := scanProduct(conn(ctx, r.db).QueryRow(ctx, query, args...))
+	if err == pgx.ErrNoRows || isInvalidText(err) {
+		return models.Product{}, errors.ErrNotFound
+	}
+
+	return product, err
+}
+
+// UpdatePickup сохраняет статус, получателя, хэш кода получения, число неверных попыток и время выдачи
+func (r *ProductRepository) UpdatePickup(ctx context.Context, product models.Product) error {
+	query, args, err := r.psql.
+		Update("products").
+		Set("status", product.Status).
+		Set("recipient_email", nullIfEmpty(product.RecipientEmail)).
+		Set("pickup_code_hash", nullIfEmpty(product.PickupCodeHash)).
+		Set("pickup_attempts", product.PickupAttempts).
+		Set("issued_at", product.IssuedAt).
+		Where(sq.Eq{"id": product.ID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// MarkReadyForPickup переводит принятые товары приемки в готовые к выдаче
+func (r *ProductRepository) MarkReadyForPickup(ctx context.Context, receptionID string) error {
+	query, args, err := r.psql.
+		Update("products").
+		Set("status", models.ProductReadyForPickup).
+		Where(sq.Eq{"reception_id": receptionID, "status": models.ProductAccepted}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).Exec(ctx, query, args...)
+	return err
+}
+
+const productColumns = "id, date_time, type, reception_id, status, issued_at, coalesce(recipient_email, ''), coalesce(pickup_code_hash, ''), pickup_attempts"
+
+func scanProduct(row pgx.Row) (models.Product, error) {
+	var p models.Product
+	err := row.Scan(&p.ID, &p.DateTime, &p.Type, &p.ReceptionId, &p.Status, &p.IssuedAt, &p.RecipientEmail, &p.PickupCodeHash, &p.PickupAttempts)
+	return p, err
+}
+
+// nullIfEmpty пишет NULL вместо пустой строки: у товара может не быть кода
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
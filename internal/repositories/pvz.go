@@ -3,83 +3,217 @@ package repositories
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/geo"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/labstack/echo"
-	"github.com/sirupsen/logrus"
 )
 
+var pvzColumns = []string{"id", "city", "registration_date", "address", "latitude", "longitude", "time_zone"}
+
 type PVZRepository struct {
-	db   *pgxpool.Pool
-	psql sq.StatementBuilderType
+	db       *pgxpool.Pool
+	replicas ReadRouter
+	psql     sq.StatementBuilderType
+}
+
+func NewPVZRepository(db *pgxpool.Pool, replicas ReadRouter) *PVZRepository {
+	return &PVZRepository{db: db, replicas: replicas, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
 }
 
-func NewPVZRepository(db *pgxpool.Pool) *PVZRepository {
-	return &PVZRepository{db: db, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+// pvzSortColumns - выражения ORDER BY для разрешенных полей сортировки
+var pvzSortColumns = map[string]string{
+	models.PVZSortRegistrationDate: "pvz.registration_date",
+	models.PVZSortCity:             "pvz.city",
+	models.PVZSortLastReception:    "(SELECT max(reception.date_time) FROM reception WHERE reception.pvz_id = pvz.id)",
 }
 
-func (r *PVZRepository) GetAll(ctx context.Context, limit, offset int, from, to time.Time) ([]models.FullPVZ, error) {
+// GetAll возвращает страницу ПВЗ с их приемками. Как и раньше, ПВЗ без
+// приемок в выборку не попадают; если задан период - ПВЗ без приемок за этот
+// период, а из приемок возвращаются только попавшие в него.
+func (r *PVZRepository) GetAll(ctx context.Context, filter models.PVZFilter) ([]models.FullPVZ, error) {
+	sortColumn, ok := pvzSortColumns[filter.Sort]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort field %q", errors.ErrInvalidInput, filter.Sort)
+	}
+	direction := "ASC"
+	if filter.Order == models.SortDesc {
+		direction = "DESC"
+	}
+
+	receptionFilter := dayFilter("reception.date_time", filter.Period)
+
 	query := r.psql.
-		Select("DISTINCT pvz.id", "pvz.city", "pvz.registration_date, reception.id, reception.date_time, reception.pvz_id, reception.status").
-		From("pvz").
-		Join("reception ON pvz.id = reception.pvz_id")
+		Select("pvz.id", "pvz.city", "pvz.registration_date", "pvz.address", "pvz.latitude", "pvz.longitude", "pvz.time_zone").
+		From("pvz")
 
-	if !from.IsZero() {
-		query = query.Where(sq.GtOrEq{"reception.date_time": from})
+	if filter.City != "" {
+		query = query.Where(sq.Eq{"pvz.city": filter.City})
 	}
-	if !to.IsZero() {
-		query = query.Where(sq.LtOrEq{"reception.date_time": to})
+
+	registered := filter.Registered
+	registered.TimeZone = filter.Period.TimeZone
+	if registeredFilter := dayFilter("pvz.registration_date", registered); len(registeredFilter) > 0 {
+		query = query.Where(registeredFilter)
 	}
 
-	query = query.OrderBy("pvz.registration_date DESC").
-		Limit(uint64(limit)).
-		Offset(uint64(offset))
+	// подзапросы собираются с "?", номера $n расставляет внешний запрос
+	receptionExists := sq.
+		Select("1").
+		From("reception").
+		Where(sq.Expr("reception.pvz_id = pvz.id"))
+	if len(receptionFilter) > 0 {
+		receptionExists = receptionExists.Where(receptionFilter)
+	}
+	query = query.Where(sq.Expr("EXISTS (?)", receptionExists))
+
+	if filter.HasOpenReception != nil {
+		subQuery := sq.
+			Select("1").
+			From("reception").
+			Where(sq.Expr("reception.pvz_id = pvz.id")).
+			Where(sq.Eq{"reception.status": "in_progress"})
+		if *filter.HasOpenReception {
+			query = query.Where(sq.Expr("EXISTS (?)", subQuery))
+		} else {
+			query = query.Where(sq.Expr("NOT EXISTS (?)", subQuery))
+		}
+	}
+
+	if filter.MinProducts > 0 {
+		countQuery := sq.
+			Select("count(*)").
+			From("products").
+			Join("reception ON reception.id = products.reception_id").
+			Where(sq.Expr("reception.pvz_id = pvz.id"))
+		if len(receptionFilter) > 0 {
+			countQuery = countQuery.Where(receptionFilter)
+		}
+		query = query.Where(sq.Expr("(?) >= ?", countQuery, filter.MinProducts))
+	}
+
+	query = query.OrderBy(sortColumn+" "+direction+" NULLS LAST", "pvz.id").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset))
 
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
-	rows, err := r.db.Query(ctx, sqlStr, args...)
+	// обе выборки читаются из одного источника, чтобы приемки соответствовали ПВЗ
+	db := readConn(ctx, r.db, r.replicas)
+	rows, err := db.Query(ctx, sqlStr, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
-	var result []models.FullPVZ
+	result := make([]models.FullPVZ, 0)
+	index := make(map[string]int)
+	ids := make([]string, 0)
 	for rows.Next() {
 		var pvz models.FullPVZ
-		pvz.Receptions = make(map[string]models.FullReception)
-		var reseprion models.FullReception
-		if err := rows.Scan(&pvz.ID, &pvz.City, &pvz.RegistrationDate,
-			&reseprion.ID, &reseprion.DateTime, &reseprion.PvzId, &reseprion.Status); err != nil {
+		if err := rows.Scan(&pvz.ID, &pvz.City, &pvz.RegistrationDate, &pvz.Address, &pvz.Latitude, &pvz.Longitude, &pvz.TimeZone); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		pvz.Receptions[reseprion.ID] = reseprion
-		reseprion.Products = make([]models.Product, 0)
+		pvz.Receptions = make(map[string]models.FullReception)
+		index[pvz.ID] = len(result)
+		ids = append(ids, pvz.ID)
 		result = append(result, pvz)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	receptionQuery := r.psql.
+		Select("reception.id", "reception.date_time", "reception.pvz_id", "reception.status").
+		From("reception").
+		Where(sq.Eq{"reception.pvz_id": ids})
+	if len(receptionFilter) > 0 {
+		// границы дней считаются в поясе ПВЗ
+		receptionQuery = receptionQuery.
+			Join("pvz ON pvz.id = reception.pvz_id").
+			Where(receptionFilter)
+	}
+
+	sqlStr, args, err = receptionQuery.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	receptionRows, err := db.Query(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer receptionRows.Close()
+
+	for receptionRows.Next() {
+		var reception models.FullReception
+		if err := receptionRows.Scan(&reception.ID, &reception.DateTime, &reception.PvzId, &reception.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		reception.Products = make([]models.Product, 0)
+		result[index[reception.PvzId]].Receptions[reception.ID] = reception
+	}
 
-	return result, nil
+	return result, receptionRows.Err()
+}
+
+// dayFilter отбирает строки, у которых column попадает в местные дни
+// периода. Границы дней вычисляет postgres, поэтому в одном запросе у
+// каждого ПВЗ свой пояс.
+func dayFilter(column string, period models.DayPeriod) sq.And {
+	filter := sq.And{}
+
+	zone, zoneArgs := "pvz.time_zone", []any{}
+	if period.TimeZone != "" {
+		zone, zoneArgs = "?", []any{period.TimeZone}
+	}
+
+	if !period.From.IsZero() {
+		filter = append(filter, sq.Expr(
+			column+" >= (CAST(? AS date)::timestamp AT TIME ZONE "+zone+")",
+			append([]any{dateOnly(period.From)}, zoneArgs...)...,
+		))
+	}
+	if !period.To.IsZero() {
+		filter = append(filter, sq.Expr(
+			column+" < ((CAST(? AS date) + 1)::timestamp AT TIME ZONE "+zone+")",
+			append([]any{dateOnly(period.To)}, zoneArgs...)...,
+		))
+	}
+
+	return filter
+}
+
+// dateOnly отбрасывает время и пояс: дата передается в postgres как есть
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
 
 func (r *PVZRepository) CreatePVZ(ctx context.Context, pvz models.PVZ) (models.PVZ, error) {
-	tx, err := r.db.Begin(ctx)
+	tx, err := conn(ctx, r.db).Begin(ctx)
 	if err != nil {
 		return models.PVZ{}, err
 	}
 	defer tx.Rollback(ctx)
-	pvz.RegistrationDate = time.Now()
+	pvz.RegistrationDate = time.Now().UTC()
 
 	query, args, err := r.psql.
 		Insert("pvz").
-		Columns("city", "registration_date").
-		Values(pvz.City, pvz.RegistrationDate).
+		Columns("city", "registration_date", "address", "latitude", "longitude", "time_zone").
+		Values(pvz.City, pvz.RegistrationDate, pvz.Address, pvz.Latitude, pvz.Longitude, pvz.TimeZone).
 		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
@@ -88,6 +222,9 @@ func (r *PVZRepository) CreatePVZ(ctx context.Context, pvz models.PVZ) (models.P
 
 	var id string
 	err = tx.QueryRow(ctx, query, args...).Scan(&id)
+	if constraint := checkViolation(err); constraint != "" {
+		return models.PVZ{}, pvzCheckError(constraint)
+	}
 	if err != nil {
 		return models.PVZ{}, err
 	}
@@ -101,30 +238,197 @@ func (r *PVZRepository) CreatePVZ(ctx context.Context, pvz models.PVZ) (models.P
 	return pvz, nil
 }
 
+// CreatePVZBatch создает все ПВЗ в одной транзакции: либо все, либо ни одного
+func (r *PVZRepository) CreatePVZBatch(ctx context.Context, pvzs []models.PVZ) ([]models.PVZ, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	created := make([]models.PVZ, 0, len(pvzs))
+	for i, pvz := range pvzs {
+		if pvz.RegistrationDate.IsZero() {
+			pvz.RegistrationDate = now
+		}
+
+		query, args, err := r.psql.
+			Insert("pvz").
+			Columns("city", "registration_date", "address", "latitude", "longitude", "time_zone").
+			Values(pvz.City, pvz.RegistrationDate, pvz.Address, pvz.Latitude, pvz.Longitude, pvz.TimeZone).
+			Suffix("RETURNING id").
+			ToSql()
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.QueryRow(ctx, query, args...).Scan(&pvz.ID)
+		if constraint := checkViolation(err); constraint != "" {
+			return nil, &errors.BatchError{Index: i, Err: pvzCheckError(constraint)}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		created = append(created, pvz)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// pvzCheckError переводит нарушенный CHECK таблицы pvz в ошибку ввода
+func pvzCheckError(constraint string) error {
+	if constraint == "pvz_city_check" {
+		return errors.ErrCityNotAllowed
+	}
+	return fmt.Errorf("%w: нарушено ограничение %s", errors.ErrInvalidInput, constraint)
+}
+
 func (r *PVZRepository) GetPVZByID(ctx context.Context, id string) (models.PVZ, error) {
+	return r.getPVZ(ctx, readConn(ctx, r.db, r.replicas), id, "")
+}
+
+// GetPVZByIDForUpdate блокирует строку ПВЗ до конца транзакции: так
+// сериализуются операции над приемками одного ПВЗ.
+func (r *PVZRepository) GetPVZByIDForUpdate(ctx context.Context, id string) (models.PVZ, error) {
+	return r.getPVZ(ctx, conn(ctx, r.db), id, "FOR UPDATE")
+}
+
+func (r *PVZRepository) getPVZ(ctx context.Context, db querier, id string, suffix string) (models.PVZ, error) {
 	query, args, err := r.psql.
-		Select("id", "city", "registration_date").
+		Select(pvzColumns...).
 		From("pvz").
 		Where(sq.Eq{"id": id}).
+		Suffix(suffix).
 		ToSql()
 	if err != nil {
 		return models.PVZ{}, err
 	}
 
-	logrus.Debug(query)
-
-	row := r.db.QueryRow(ctx, query, args...)
+	row := db.QueryRow(ctx, query, args...)
 
 	var pvz models.PVZ
-	if err := row.Scan(&pvz.ID, &pvz.City, &pvz.RegistrationDate); err != nil {
+	err = row.Scan(&pvz.ID, &pvz.City, &pvz.RegistrationDate, &pvz.Address, &pvz.Latitude, &pvz.Longitude, &pvz.TimeZone)
+	if err == pgx.ErrNoRows || isInvalidText(err) {
+		return models.PVZ{}, errors.ErrNotFound
+	}
+	if err != nil {
 		return models.PVZ{}, err
 	}
 
 	return pvz, nil
 }
 
+// List возвращает ПВЗ без приемок, включая ПВЗ, в которых еще не было приемок
+func (r *PVZRepository) List(ctx context.Context, limit, offset int) ([]models.PVZ, error) {
+	query, args, err := r.psql.
+		Select(pvzColumns...).
+		From("pvz").
+		OrderBy("registration_date DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.PVZ, 0)
+	for rows.Next() {
+		var pvz models.PVZ
+		if err := rows.Scan(&pvz.ID, &pvz.City, &pvz.RegistrationDate, &pvz.Address, &pvz.Latitude, &pvz.Longitude, &pvz.TimeZone); err != nil {
+			return nil, err
+		}
+		result = append(result, pvz)
+	}
+
+	return result, rows.Err()
+}
+
+// distanceExpr - расстояние в метрах от точки (?, ?) по формуле гаверсинусов
+var distanceExpr = fmt.Sprintf(`2 * %.0f * asin(least(1, sqrt(
+	power(sin(radians(latitude - ?) / 2), 2) +
+	cos(radians(?)) * cos(radians(latitude)) * power(sin(radians(longitude - ?) / 2), 2)
+)))`, geo.EarthRadius)
+
+// Nearby возвращает ПВЗ в радиусе от точки по возрастанию расстояния.
+// Прямоугольник вокруг круга отсекает строки по индексу (latitude, longitude),
+// точное расстояние считается только для оставшихся.
+func (r *PVZRepository) Nearby(ctx context.Context, q models.NearbyQuery) ([]models.NearbyPVZ, error) {
+	box := geo.BoundingBox(q.Lat, q.Lon, q.Radius)
+
+	inner := r.psql.
+		Select(pvzColumns...).
+		Column(sq.Expr(distanceExpr+" AS distance", q.Lat, q.Lat, q.Lon)).
+		From("pvz").
+		Where(sq.Expr("latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat))
+	if !box.AllLon {
+		inner = inner.Where(sq.Expr("longitude BETWEEN ? AND ?", box.MinLon, box.MaxLon))
+	}
+
+	query, args, err := r.psql.
+		Select(append(pvzColumns, "distance")...).
+		FromSelect(inner, "nearby").
+		Where(sq.LtOrEq{"distance": q.Radius}).
+		OrderBy("distance", "id").
+		Limit(uint64(q.Limit)).
+		Offset(uint64(q.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := readConn(ctx, r.db, r.replicas).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]models.NearbyPVZ, 0)
+	for rows.Next() {
+		var pvz models.NearbyPVZ
+		if err := rows.Scan(&pvz.ID, &pvz.City, &pvz.RegistrationDate, &pvz.Address, &pvz.Latitude, &pvz.Longitude, &pvz.TimeZone, &pvz.Distance); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		result = append(result, pvz)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PVZRepository) UpdateTimeZone(ctx context.Context, id, timeZone string) error {
+	query, args, err := r.psql.
+		Update("pvz").
+		Set("time_zone", timeZone).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
 func (r *PVZRepository) DeletePVZ(ctx context.Context, id string) error {
-	tx, err := r.db.Begin(ctx)
+	tx, err := conn(ctx, r.db).Begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -144,7 +448,7 @@ func (r *PVZRepository) DeletePVZ(ctx context.Context, id string) error {
 	}
 
 	if result.RowsAffected() == 0 {
-		return echo.NewHTTPError(http.StatusNotFound, "PVZ not found")
+		return errors.ErrNotFound
 	}
 
 	if err := tx.Commit(ctx); err != nil {
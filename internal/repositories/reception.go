@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -11,54 +12,78 @@ import (
 )
 
 type ReceptionRepository struct {
-	db   *pgxpool.Pool
-	psql sq.StatementBuilderType
+	db       *pgxpool.Pool
+	replicas ReadRouter
+	psql     sq.StatementBuilderType
 }
 
-func NewReceptionRepository(db *pgxpool.Pool) *ReceptionRepository {
-	return &ReceptionRepository{db: db, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+func NewReceptionRepository(db *pgxpool.Pool, replicas ReadRouter) *ReceptionRepository {
+	return &ReceptionRepository{db: db, replicas: replicas, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
 }
 
-func (r *ReceptionRepository) CreateReception(ctx context.Context, Reception models.Reception) error {
-	tx, err := r.db.Begin(ctx)
+func (r *ReceptionRepository) CreateReception(ctx context.Context, Reception models.Reception) (models.Reception, error) {
+	if Reception.DateTime.IsZero() {
+		Reception.DateTime = time.Now().UTC()
+	}
+
+	tx, err := conn(ctx, r.db).Begin(ctx)
 	if err != nil {
-		return err
+		return models.Reception{}, err
 	}
 	defer tx.Rollback(ctx)
 
 	query, args, err := r.psql.
 		Insert("Reception").
 		Columns("pvz_id", "status", "date_time").
-		Values(Reception.PvzId, "in_progress", time.Now().Format(time.UnixDate)).
+		Values(Reception.PvzId, "in_progress", Reception.DateTime).
 		Suffix("RETURNING id, date_time, pvz_id, status").
 		ToSql()
 	if err != nil {
-		return err
+		return models.Reception{}, err
 	}
 
-	_, err = tx.Exec(ctx, query, args...)
+	var created models.Reception
+	err = tx.QueryRow(ctx, query, args...).Scan(&created.ID, &created.DateTime, &created.PvzId, &created.Status)
+	if isForeignKeyViolation(err) {
+		return models.Reception{}, errors.ErrNotFound
+	}
+	// reception_single_active: в ПВЗ уже есть открытая приемка
+	if isUniqueViolation(err) {
+		return models.Reception{}, errors.ErrInvalidInput
+	}
 	if err != nil {
-		return err
+		return models.Reception{}, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return err
+		return models.Reception{}, err
 	}
 
-	return nil
+	return created, nil
 }
 
 func (r *ReceptionRepository) GetActiveReceptionByPVZID(ctx context.Context, pvzID string) (*models.Reception, error) {
+	return r.getActiveReception(ctx, pvzID, "")
+}
+
+// GetActiveReceptionByPVZIDForUpdate блокирует строку открытой приемки до
+// конца транзакции, поэтому должен вызываться внутри TxManager.WithinTransaction.
+func (r *ReceptionRepository) GetActiveReceptionByPVZIDForUpdate(ctx context.Context, pvzID string) (*models.Reception, error) {
+	return r.getActiveReception(ctx, pvzID, "FOR UPDATE")
+}
+
+func (r *ReceptionRepository) getActiveReception(ctx context.Context, pvzID string, suffix string) (*models.Reception, error) {
 	query, args, err := r.psql.
 		Select("id", "date_time", "pvz_id", "status").
 		From("Reception").
 		Where(sq.Eq{"pvz_id": pvzID, "status": "in_progress"}).
+		Suffix(suffix).
 		ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	row := r.db.QueryRow(ctx, query, args...)
+	row := conn(ctx, r.db).QueryRow(ctx, query, args...)
 	if row == nil {
 		return nil, nil
 	}
@@ -76,24 +101,128 @@ func (r *ReceptionRepository) GetActiveReceptionByPVZID(ctx context.Context, pvz
 	return &Reception, nil
 }
 
+func (r *ReceptionRepository) GetByID(ctx context.Context, id string) (models.Reception, error) {
+	query, args, err := r.psql.
+		Select("id", "date_time", "pvz_id", "status").
+		From("reception").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return models.Reception{}, err
+	}
+
+	var reception models.Reception
+	err = readConn(ctx, r.db, r.replicas).QueryRow(ctx, query, args...).
+		Scan(&reception.ID, &reception.DateTime, &reception.PvzId, &reception.Status)
+	if err == pgx.ErrNoRows || isInvalidText(err) {
+		return models.Reception{}, errors.ErrNotFound
+	}
+	if err != nil {
+		return models.Reception{}, err
+	}
+
+	return reception, nil
+}
+
+// List возвращает страницу приемок ПВЗ, новые первыми
+func (r *ReceptionRepository) List(ctx context.Context, filter models.ReceptionFilter) ([]models.Reception, error) {
+	query := r.psql.
+		Select("reception.id", "reception.date_time", "reception.pvz_id", "reception.status").
+		From("reception").
+		Join("pvz ON pvz.id = reception.pvz_id").
+		Where(sq.Eq{"reception.pvz_id": filter.PvzID})
+	if filter.Status != "" {
+		query = query.Where(sq.Eq{"reception.status": filter.Status})
+	}
+	if periodFilter := dayFilter("reception.date_time", filter.Period); len(periodFilter) > 0 {
+		query = query.Where(periodFilter)
+	}
+
+	sqlStr, args, err := query.
+		OrderBy("reception.date_time DESC", "reception.id").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := readConn(ctx, r.db, r.replicas).Query(ctx, sqlStr, args...)
+	if isInvalidText(err) {
+		return []models.Reception{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receptions := make([]models.Reception, 0)
+	for rows.Next() {
+		var reception models.Reception
+		if err := rows.Scan(&reception.ID, &reception.DateTime, &reception.PvzId, &reception.Status); err != nil {
+			return nil, err
+		}
+		receptions = append(receptions, reception)
+	}
+
+	return receptions, rows.Err()
+}
+
+func (r *ReceptionRepository) ListStale(ctx context.Context, idleSince time.Time) ([]models.Reception, error) {
+	query, args, err := r.psql.
+		Select("r.id", "r.date_time", "r.pvz_id", "r.status").
+		From("reception r").
+		Where(sq.Eq{"r.status": "in_progress"}).
+		// GREATEST в postgres пропускает NULL, если товаров еще нет
+		Where("GREATEST(r.date_time, (SELECT max(p.date_time) FROM products p WHERE p.reception_id = r.id)) < ?", idleSince).
+		OrderBy("r.date_time").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receptions []models.Reception
+	for rows.Next() {
+		var reception models.Reception
+		if err := rows.Scan(&reception.ID, &reception.DateTime, &reception.PvzId, &reception.Status); err != nil {
+			return nil, err
+		}
+		receptions = append(receptions, reception)
+	}
+
+	return receptions, rows.Err()
+}
+
 func (r *ReceptionRepository) CloseReception(ctx context.Context, receptionId string) error {
-	tx, err := r.db.Begin(ctx)
+	tx, err := conn(ctx, r.db).Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
 
 	query, args, err := r.psql.Update("reception").
 		Set("status", "close").
+		Where(sq.Eq{"id": receptionId}).
 		ToSql()
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(ctx, query, args...)
+	result, err := tx.Exec(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 
+	if result.RowsAffected() == 0 {
+		return errors.ErrNotFound
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return err
 	}
@@ -1,11 +1,41 @@
 package repositories
 
-import "pvz-service/config"
+import (
+	"pvz-service/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
 
 type Repos struct {
-	AuthRepo      *UserRepository
-	ProductRepo   *ProductRepository
-	PvzRepo       *PVZRepository
-	ReceptionRepo *ReceptionRepository
-	Cfg           *config.Config
+	AuthRepo       UserRepositoryInterface
+	ProductRepo    ProductRepositoryInterface
+	PvzRepo        PVZRepositoryInterface
+	ReceptionRepo  ReceptionRepositoryInterface
+	ScheduleRepo   ScheduleRepositoryInterface
+	AuditRepo      AuditRepositoryInterface
+	InvitationRepo InvitationRepositoryInterface
+	UserTokenRepo  UserTokenRepositoryInterface
+	TxManager      TxManager
+	JobLocker      JobLockerInterface
+	CacheNotifier  CacheNotifierInterface
+	Cfg            *config.Config
+}
+
+// NewRepos создает репозитории Postgres. Списки ПВЗ и товары приемок читаются
+// через replicas, nil - все запросы идут в db.
+func NewRepos(cfg *config.Config, db *pgxpool.Pool, replicas ReadRouter) *Repos {
+	return &Repos{
+		Cfg:            cfg,
+		AuthRepo:       NewUserRepository(db),
+		PvzRepo:        NewPVZRepository(db, replicas),
+		ProductRepo:    NewProductRepository(db, replicas),
+		ReceptionRepo:  NewReceptionRepository(db, replicas),
+		ScheduleRepo:   NewScheduleRepository(db, replicas),
+		AuditRepo:      NewAuditRepository(db),
+		InvitationRepo: NewInvitationRepository(db),
+		UserTokenRepo:  NewUserTokenRepository(db),
+		TxManager:      NewTxManager(db),
+		JobLocker:      NewJobLocker(db),
+		CacheNotifier:  NewCacheNotifier(db),
+	}
 }
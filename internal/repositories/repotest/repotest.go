@@ -0,0 +1,1073 @@
+// Package repotest - общий набор тестов, которому должна соответствовать
+// каждая реализация хранилищ (postgres и memory).
+package repotest
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/repositories"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// missingID - валидный UUID, которого нет в хранилище
+const missingID = "00000000-0000-0000-0000-000000000000"
+
+// Run запускает все проверки. newRepos должен возвращать пустое хранилище
+// для каждого подтеста.
+func Run(t *testing.T, newRepos func(t *testing.T) *repositories.Repos) {
+	tests := map[string]func(t *testing.T, repos *repositories.Repos){
+		"Users":           testUsers,
+		"Lockout":         testUserLockout,
+		"Invitations":     testInvitations,
+		"UserTokens":      testUserTokens,
+		"UserAdmin":       testUserAdmin,
+		"PVZ":             testPVZ,
+		"GetAll":          testGetAll,
+		"GetAllLocalDays": testGetAllLocalDays,
+		"GetAllFilters":   testGetAllFilters,
+		"Nearby":          testNearby,
+		"Schedule":        testSchedule,
+		"Receptions":      testReceptions,
+		"ReceptionReads":  testReceptionReads,
+		"ProductPickup":   testProductPickup,
+		"Products":        testProducts,
+		"Concurrent":      testConcurrentProducts,
+		"Audit":           testAudit,
+		"Tx":              testTx,
+		"Stale":           testStaleReceptions,
+		"JobLocker":       testJobLocker,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepos(t))
+		})
+	}
+}
+
+func testUsers(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	created, err := repos.AuthRepo.CreateUser(ctx, models.User{Email: "user@example.com", Password: "hash", Role: "client"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+
+	found, err := repos.AuthRepo.GetUserByEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Equal(t, "hash", found.Password)
+	assert.Equal(t, "client", found.Role)
+
+	_, err = repos.AuthRepo.CreateUser(ctx, models.User{Email: "user@example.com", Password: "hash", Role: "client"})
+	assert.ErrorIs(t, err, errors.ErrAlreadyExists)
+
+	_, err = repos.AuthRepo.GetUserByEmail(ctx, "missing@example.com")
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	// id из URL может быть не UUID: это тоже "не найдено", а не ошибка базы
+	_, err = repos.AuthRepo.GetUserByID(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+	_, err = repos.AuthRepo.IncrementFailedLogins(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+	assert.ErrorIs(t, repos.AuthRepo.ResetFailedLogins(ctx, "not-a-uuid"), errors.ErrNotFound)
+	assert.ErrorIs(t, repos.AuthRepo.UpdateRole(ctx, "not-a-uuid", models.RoleModerator), errors.ErrNotFound)
+
+	byID, err := repos.AuthRepo.GetUserByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", byID.Email)
+	assert.Zero(t, byID.FailedLogins)
+	assert.Nil(t, byID.LockedUntil)
+
+	_, err = repos.AuthRepo.GetUserByID(ctx, missingID)
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+}
+
+func testUserLockout(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	user, err := repos.AuthRepo.CreateUser(ctx, models.User{Email: "locked@example.com", Password: "hash", Role: "client"})
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		failures, err := repos.AuthRepo.IncrementFailedLogins(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, i, failures)
+	}
+
+	until := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	require.NoError(t, repos.AuthRepo.LockUser(ctx, user.ID, until))
+
+	found, err := repos.AuthRepo.GetUserByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, 3, found.FailedLogins)
+	require.NotNil(t, found.LockedUntil)
+	assert.True(t, until.Equal(*found.LockedUntil))
+
+	require.NoError(t, repos.AuthRepo.ResetFailedLogins(ctx, user.ID))
+	found, err = repos.AuthRepo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, found.FailedLogins)
+	assert.Nil(t, found.LockedUntil)
+
+	_, err = repos.AuthRepo.IncrementFailedLogins(ctx, missingID)
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+	assert.ErrorIs(t, repos.AuthRepo.LockUser(ctx, missingID, until), errors.ErrNotFound)
+	assert.ErrorIs(t, repos.AuthRepo.ResetFailedLogins(ctx, missingID), errors.ErrNotFound)
+}
+
+func testPVZ(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	created, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.False(t, created.RegistrationDate.IsZero())
+
+	found, err := repos.PvzRepo.GetPVZByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "москва", found.City)
+
+	_, err = repos.PvzRepo.GetPVZByID(ctx, missingID)
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	// список городов совпадает с CHECK в базе, регистр важен
+	for _, city := range []string{"новосибирск", "санкт-Петербург", ""} {
+		_, err = repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: city})
+		assert.ErrorIs(t, err, errors.ErrCityNotAllowed, "город %q", city)
+	}
+	_, err = repos.PvzRepo.CreatePVZBatch(ctx, []models.PVZ{{City: "москва"}, {City: "новосибирск"}})
+	assert.ErrorIs(t, err, errors.ErrCityNotAllowed)
+	var batchErr *errors.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, 1, batchErr.Index, "ошибка указывает на элемент пакета")
+
+	registered := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	batch, err := repos.PvzRepo.CreatePVZBatch(ctx, []models.PVZ{
+		{City: "казань", RegistrationDate: registered},
+		{City: "москва"},
+	})
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.NotEmpty(t, batch[0].ID)
+	assert.True(t, batch[0].RegistrationDate.Equal(registered))
+	assert.False(t, batch[1].RegistrationDate.IsZero())
+
+	list, err := repos.PvzRepo.List(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, batch[0].ID, list[2].ID, "самый старый ПВЗ должен быть последним")
+
+	list, err = repos.PvzRepo.List(ctx, 2, 2)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	reception, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: created.ID})
+	require.NoError(t, err)
+	_, err = repos.ProductRepo.AddProduct(ctx, models.Product{Type: "обувь", DateTime: time.Now(), ReceptionId: reception.ID})
+	require.NoError(t, err)
+
+	require.NoError(t, repos.PvzRepo.DeletePVZ(ctx, created.ID))
+	_, err = repos.PvzRepo.GetPVZByID(ctx, created.ID)
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	active, err := repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Nil(t, active, "приемки удаляются вместе с ПВЗ")
+
+	products, err := repos.ProductRepo.GetByReceptionID(ctx, reception.ID)
+	require.NoError(t, err)
+	assert.Empty(t, products, "товары удаляются вместе с ПВЗ")
+
+	assert.ErrorIs(t, repos.PvzRepo.DeletePVZ(ctx, created.ID), errors.ErrNotFound)
+}
+
+func testGetAll(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	withReception, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+	withoutReception, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "казань"})
+	require.NoError(t, err)
+
+	earlier, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+
+	reception, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: withReception.ID})
+	require.NoError(t, err)
+	_, err = repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: earlier.ID, DateTime: reception.DateTime.AddDate(0, 0, -3)})
+	require.NoError(t, err)
+
+	all, err := repos.PvzRepo.GetAll(ctx, newestFirst(10, 0, models.DayPeriod{}))
+	require.NoError(t, err)
+	require.Len(t, all, 2, "ПВЗ без приемок не возвращаются")
+	assert.ElementsMatch(t, []string{withReception.ID, earlier.ID}, []string{all[0].ID, all[1].ID})
+	assert.NotContains(t, []string{all[0].ID, all[1].ID}, withoutReception.ID)
+
+	today := reception.DateTime.In(models.Location(withReception.TimeZone))
+	inRange, err := repos.PvzRepo.GetAll(ctx, newestFirst(10, 0, models.DayPeriod{From: today, To: today}))
+	require.NoError(t, err)
+	require.Len(t, inRange, 1, "период из одного дня включает весь день")
+	assert.Equal(t, withReception.ID, inRange[0].ID)
+	assert.Contains(t, inRange[0].Receptions, reception.ID)
+
+	outOfRange, err := repos.PvzRepo.GetAll(ctx, newestFirst(10, 0, models.DayPeriod{From: today.AddDate(0, 0, 1)}))
+	require.NoError(t, err)
+	assert.Empty(t, outOfRange)
+
+	paged, err := repos.PvzRepo.GetAll(ctx, newestFirst(1, 1, models.DayPeriod{}))
+	require.NoError(t, err)
+	require.Len(t, paged, 1)
+	assert.Equal(t, all[1].ID, paged[0].ID)
+}
+
+// testGetAllLocalDays проверяет, что границы дней считаются в поясе ПВЗ,
+// а не по UTC: 23:30 UTC 1 мая - это уже 2 мая в Токио.
+func testGetAllLocalDays(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	tokyo, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва", TimeZone: "Asia/Tokyo"})
+	require.NoError(t, err)
+	dateTime := time.Date(2025, time.May, 1, 23, 30, 0, 0, time.UTC)
+	reception, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: tokyo.ID, DateTime: dateTime})
+	require.NoError(t, err)
+	assert.True(t, dateTime.Equal(reception.DateTime), "время приемки сохраняется как передано")
+
+	may1 := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+	may2 := may1.AddDate(0, 0, 1)
+
+	local, err := repos.PvzRepo.GetAll(ctx, newestFirst(10, 0, models.DayPeriod{From: may2, To: may2}))
+	require.NoError(t, err)
+	require.Len(t, local, 1, "приемка относится к 2 мая по Токио")
+	assert.Contains(t, local[0].Receptions, reception.ID)
+
+	local, err = repos.PvzRepo.GetAll(ctx, newestFirst(10, 0, models.DayPeriod{From: may1, To: may1}))
+	require.NoError(t, err)
+	assert.Empty(t, local)
+
+	explicit, err := repos.PvzRepo.GetAll(ctx, newestFirst(10, 0, models.DayPeriod{From: may1, To: may1, TimeZone: "UTC"}))
+	require.NoError(t, err)
+	require.Len(t, explicit, 1, "явный пояс важнее пояса ПВЗ")
+	assert.Contains(t, explicit[0].Receptions, reception.ID)
+}
+
+// newestFirst - фильтр с сортировкой по умолчанию, как ее выставляет сервис
+func newestFirst(limit, offset int, period models.DayPeriod) models.PVZFilter {
+	return models.PVZFilter{
+		Limit:  limit,
+		Offset: offset,
+		Period: period,
+		Sort:   models.PVZSortRegistrationDate,
+		Order:  models.SortDesc,
+	}
+}
+
+func testGetAllFilters(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	registered := func(month time.Month) time.Time {
+		return time.Date(2025, month, 10, 12, 0, 0, 0, time.UTC)
+	}
+	pvzs, err := repos.PvzRepo.CreatePVZBatch(ctx, []models.PVZ{
+		{City: "москва", RegistrationDate: registered(time.January), TimeZone: "Europe/Moscow"},
+		{City: "казань", RegistrationDate: registered(time.February), TimeZone: "Europe/Moscow"},
+		{City: "санкт-петербург", RegistrationDate: registered(time.March), TimeZone: "Europe/Moscow"},
+	})
+	require.NoError(t, err)
+	moscow, kazan, spb := pvzs[0].ID, pvzs[1].ID, pvzs[2].ID
+
+	closed, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: moscow, DateTime: time.Date(2025, time.April, 1, 9, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	for range 2 {
+		_, err = repos.ProductRepo.AddProduct(ctx, models.Product{Type: "обувь", DateTime: time.Now(), ReceptionId: closed.ID})
+		require.NoError(t, err)
+	}
+	require.NoError(t, repos.ReceptionRepo.CloseReception(ctx, closed.ID))
+
+	empty, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: spb, DateTime: time.Date(2025, time.March, 20, 9, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.NoError(t, repos.ReceptionRepo.CloseReception(ctx, empty.ID))
+
+	open, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: kazan, DateTime: time.Date(2025, time.April, 5, 9, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	_, err = repos.ProductRepo.AddProduct(ctx, models.Product{Type: "одежда", DateTime: time.Now(), ReceptionId: open.ID})
+	require.NoError(t, err)
+
+	list := func(filter models.PVZFilter) []string {
+		t.Helper()
+		if filter.Sort == "" {
+			filter.Sort, filter.Order = models.PVZSortRegistrationDate, models.SortAsc
+		}
+		filter.Limit = 10
+		result, err := repos.PvzRepo.GetAll(ctx, filter)
+		require.NoError(t, err)
+		ids := make([]string, 0, len(result))
+		for _, pvz := range result {
+			ids = append(ids, pvz.ID)
+		}
+		return ids
+	}
+	yes, no := true, false
+
+	assert.Equal(t, []string{kazan}, list(models.PVZFilter{City: "казань"}))
+	assert.Equal(t, []string{kazan}, list(models.PVZFilter{HasOpenReception: &yes}))
+	assert.Equal(t, []string{moscow, spb}, list(models.PVZFilter{HasOpenReception: &no}))
+	assert.Equal(t, []string{kazan, spb}, list(models.PVZFilter{Registered: models.DayPeriod{
+		From: time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, time.March, 31, 0, 0, 0, 0, time.UTC),
+	}}))
+
+	assert.Equal(t, []string{moscow}, list(models.PVZFilter{MinProducts: 2}))
+	april5 := time.Date(2025, time.April, 5, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{kazan}, list(models.PVZFilter{MinProducts: 1, Period: models.DayPeriod{From: april5}}),
+		"товары считаются только в приемках за период")
+
+	assert.Equal(t, []string{kazan, moscow, spb}, list(models.PVZFilter{Sort: models.PVZSortCity, Order: models.SortAsc}))
+	assert.Equal(t, []string{spb, kazan, moscow}, list(models.PVZFilter{Sort: models.PVZSortRegistrationDate, Order: models.SortDesc}))
+	assert.Equal(t, []string{spb, moscow, kazan}, list(models.PVZFilter{Sort: models.PVZSortLastReception, Order: models.SortAsc}))
+	assert.Equal(t, []string{kazan, moscow, spb}, list(models.PVZFilter{Sort: models.PVZSortLastReception, Order: models.SortDesc}))
+
+	_, err = repos.PvzRepo.GetAll(ctx, models.PVZFilter{Limit: 10, Sort: "id; DROP TABLE pvz"})
+	assert.ErrorIs(t, err, errors.ErrInvalidInput)
+}
+
+func testNearby(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	point := func(lat, lon float64) (*float64, *float64) { return &lat, &lon }
+
+	// Красная площадь, Тверская и Казань
+	lat, lon := point(55.7539, 37.6208)
+	center, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва", Address: "Красная площадь, 1", Latitude: lat, Longitude: lon})
+	require.NoError(t, err)
+	lat, lon = point(55.7650, 37.6050)
+	tverskaya, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва", Latitude: lat, Longitude: lon})
+	require.NoError(t, err)
+	lat, lon = point(55.7963, 49.1088)
+	_, err = repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "казань", Latitude: lat, Longitude: lon})
+	require.NoError(t, err)
+	_, err = repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+
+	found, err := repos.PvzRepo.GetPVZByID(ctx, center.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Красная площадь, 1", found.Address)
+	require.NotNil(t, found.Latitude)
+	assert.Equal(t, 55.7539, *found.Latitude)
+
+	nearby, err := repos.PvzRepo.Nearby(ctx, models.NearbyQuery{Lat: 55.7540, Lon: 37.6210, Radius: 5000, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, nearby, 2, "Казань и ПВЗ без координат не попадают в радиус")
+	assert.Equal(t, center.ID, nearby[0].ID)
+	assert.Equal(t, tverskaya.ID, nearby[1].ID)
+	assert.Less(t, nearby[0].Distance, 50.0)
+	assert.InDelta(t, 1600, nearby[1].Distance, 100)
+
+	limited, err := repos.PvzRepo.Nearby(ctx, models.NearbyQuery{Lat: 55.7540, Lon: 37.6210, Radius: 5000, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, limited, 1)
+	assert.Equal(t, center.ID, limited[0].ID)
+
+	empty, err := repos.PvzRepo.Nearby(ctx, models.NearbyQuery{Lat: 0, Lon: 0, Radius: 5000, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func testSchedule(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	pvz, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "казань", TimeZone: "Europe/Moscow"})
+	require.NoError(t, err)
+	other, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва", TimeZone: "Europe/Moscow"})
+	require.NoError(t, err)
+
+	schedules, err := repos.ScheduleRepo.GetSchedules(ctx, []string{pvz.ID, other.ID})
+	require.NoError(t, err)
+	require.Len(t, schedules, 2, "пустое расписание возвращается для каждого ПВЗ")
+	assert.Empty(t, schedules[pvz.ID].Week)
+	assert.NotNil(t, schedules[pvz.ID].Exceptions)
+
+	require.NoError(t, repos.PvzRepo.UpdateTimeZone(ctx, pvz.ID, "Europe/Samara"))
+	found, err := repos.PvzRepo.GetPVZByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Samara", found.TimeZone)
+	assert.ErrorIs(t, repos.PvzRepo.UpdateTimeZone(ctx, missingID, "Europe/Samara"), errors.ErrNotFound)
+
+	week := []models.WorkingHours{
+		{Weekday: 6, Opens: "10:00", Closes: "24:00"},
+		{Weekday: 1, Opens: "09:00", Closes: "21:30"},
+	}
+	require.NoError(t, repos.ScheduleRepo.ReplaceWeek(ctx, pvz.ID, week))
+	require.NoError(t, repos.ScheduleRepo.ReplaceWeek(ctx, pvz.ID, week[1:]))
+	require.NoError(t, repos.ScheduleRepo.ReplaceWeek(ctx, pvz.ID, week))
+	assert.ErrorIs(t, repos.ScheduleRepo.ReplaceWeek(ctx, missingID, week), errors.ErrNotFound)
+
+	today := time.Now().UTC().Format(models.DateLayout)
+	nextWeek := time.Now().UTC().AddDate(0, 0, 7).Format(models.DateLayout)
+	closed, err := repos.ScheduleRepo.CreateException(ctx, models.ScheduleException{PvzID: pvz.ID, StartDate: nextWeek, EndDate: nextWeek, Reason: "инвентаризация"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, closed.ID)
+	short, err := repos.ScheduleRepo.CreateException(ctx, models.ScheduleException{PvzID: pvz.ID, StartDate: today, EndDate: today, Opens: "12:00", Closes: "15:00"})
+	require.NoError(t, err)
+	// граница считается по UTC независимо от часового пояса сессии базы
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(models.DateLayout)
+	recent, err := repos.ScheduleRepo.CreateException(ctx, models.ScheduleException{PvzID: pvz.ID, StartDate: yesterday, EndDate: yesterday})
+	require.NoError(t, err)
+	old := time.Now().UTC().AddDate(0, 0, -2).Format(models.DateLayout)
+	_, err = repos.ScheduleRepo.CreateException(ctx, models.ScheduleException{PvzID: pvz.ID, StartDate: old, EndDate: old})
+	require.NoError(t, err)
+	_, err = repos.ScheduleRepo.CreateException(ctx, models.ScheduleException{PvzID: missingID, StartDate: today, EndDate: today})
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	schedules, err = repos.ScheduleRepo.GetSchedules(ctx, []string{pvz.ID, other.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []models.WorkingHours{week[1], week[0]}, schedules[pvz.ID].Week, "дни недели по порядку")
+	assert.Equal(t, []models.ScheduleException{recent, short, closed}, schedules[pvz.ID].Exceptions, "прошедшие исключения не возвращаются")
+	assert.Empty(t, schedules[other.ID].Week)
+
+	_, err = repos.ScheduleRepo.DeleteException(ctx, other.ID, closed.ID)
+	assert.ErrorIs(t, err, errors.ErrNotFound, "исключение другого ПВЗ")
+	deleted, err := repos.ScheduleRepo.DeleteException(ctx, pvz.ID, closed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, closed, deleted)
+
+	require.NoError(t, repos.PvzRepo.DeletePVZ(ctx, pvz.ID))
+	schedules, err = repos.ScheduleRepo.GetSchedules(ctx, []string{pvz.ID})
+	require.NoError(t, err)
+	assert.Empty(t, schedules[pvz.ID].Week, "расписание удаляется вместе с ПВЗ")
+	assert.Empty(t, schedules[pvz.ID].Exceptions)
+}
+
+func testReceptions(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	pvz, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+
+	active, err := repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	created, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: pvz.ID})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "in_progress", created.Status)
+	assert.Equal(t, pvz.ID, created.PvzId)
+
+	active, err = repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, pvz.ID)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, created.ID, active.ID)
+
+	_, err = repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: pvz.ID})
+	assert.ErrorIs(t, err, errors.ErrInvalidInput, "вторая открытая приемка в том же ПВЗ")
+
+	require.NoError(t, repos.ReceptionRepo.CloseReception(ctx, created.ID))
+	active, err = repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	assert.ErrorIs(t, repos.ReceptionRepo.CloseReception(ctx, missingID), errors.ErrNotFound)
+
+	_, err = repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: missingID})
+	assert.Error(t, err)
+}
+
+func testReceptionReads(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	pvz, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва", TimeZone: "Europe/Moscow"})
+	require.NoError(t, err)
+	other, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "казань", TimeZone: "Europe/Moscow"})
+	require.NoError(t, err)
+
+	closed, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: pvz.ID, DateTime: time.Date(2025, time.April, 1, 9, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.NoError(t, repos.ReceptionRepo.CloseReception(ctx, closed.ID))
+	open, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: pvz.ID, DateTime: time.Date(2025, time.April, 5, 9, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+
+	found, err := repos.ReceptionRepo.GetByID(ctx, open.ID)
+	require.NoError(t, err)
+	assert.Equal(t, open.ID, found.ID)
+	assert.Equal(t, models.ReceptionInProgress, found.Status)
+	assert.True(t, open.DateTime.Equal(found.DateTime))
+	_, err = repos.ReceptionRepo.GetByID(ctx, missingID)
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	list := func(filter models.ReceptionFilter) []string {
+		t.Helper()
+		if filter.PvzID == "" {
+			filter.PvzID = pvz.ID
+		}
+		if filter.Limit == 0 {
+			filter.Limit = 10
+		}
+		receptions, err := repos.ReceptionRepo.List(ctx, filter)
+		require.NoError(t, err)
+		require.NotNil(t, receptions)
+		ids := make([]string, 0, len(receptions))
+		for _, reception := range receptions {
+			ids = append(ids, reception.ID)
+		}
+		return ids
+	}
+	april5 := time.Date(2025, time.April, 5, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []string{open.ID, closed.ID}, list(models.ReceptionFilter{}), "новые первыми")
+	assert.Equal(t, []string{closed.ID}, list(models.ReceptionFilter{Status: models.ReceptionClosed}))
+	assert.Equal(t, []string{open.ID}, list(models.ReceptionFilter{Period: models.DayPeriod{From: april5, To: april5}}))
+	assert.Equal(t, []string{closed.ID}, list(models.ReceptionFilter{Limit: 1, Offset: 1}))
+	assert.Empty(t, list(models.ReceptionFilter{PvzID: other.ID}))
+
+	var added []string
+	for i := range 3 {
+		product, err := repos.ProductRepo.AddProduct(ctx, models.Product{
+			Type:        "обувь",
+			DateTime:    time.Date(2025, time.April, 5, 10, i, 0, 0, time.UTC),
+			ReceptionId: open.ID,
+		})
+		require.NoError(t, err)
+		added = append(added, product.ID)
+	}
+
+	firstPage, err := repos.ProductRepo.ListByReceptionID(ctx, open.ID, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, firstPage, 2)
+	assert.Equal(t, added[2], firstPage[0].ID, "последний добавленный первым")
+	assert.Equal(t, open.ID, firstPage[0].ReceptionId)
+	secondPage, err := repos.ProductRepo.ListByReceptionID(ctx, open.ID, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, secondPage, 1)
+	assert.Equal(t, added[0], secondPage[0].ID)
+
+	empty, err := repos.ProductRepo.ListByReceptionID(ctx, closed.ID, 10, 0)
+	require.NoError(t, err)
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+}
+
+func testProductPickup(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	pvz, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва", TimeZone: "Europe/Moscow"})
+	require.NoError(t, err)
+	reception, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: pvz.ID})
+	require.NoError(t, err)
+
+	first, err := repos.ProductRepo.AddProduct(ctx, models.Product{Type: "обувь", DateTime: time.Now().Add(-time.Minute), ReceptionId: reception.ID, RecipientEmail: "buyer@example.com", PickupCodeHash: "hash-1"})
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductAccepted, first.Status, "новый товар принят")
+	withoutCode, err := repos.ProductRepo.AddProduct(ctx, models.Product{Type: "одежда", DateTime: time.Now(), ReceptionId: reception.ID})
+	require.NoError(t, err)
+
+	found, err := repos.ProductRepo.GetByIDForUpdate(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "hash-1", found.PickupCodeHash)
+	assert.Equal(t, "buyer@example.com", found.RecipientEmail)
+	assert.Equal(t, reception.ID, found.ReceptionId)
+	assert.Nil(t, found.IssuedAt)
+	_, err = repos.ProductRepo.GetByIDForUpdate(ctx, missingID)
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	require.NoError(t, repos.ProductRepo.MarkReadyForPickup(ctx, reception.ID))
+	found, err = repos.ProductRepo.GetByIDForUpdate(ctx, withoutCode.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductReadyForPickup, found.Status)
+	assert.Empty(t, found.PickupCodeHash)
+
+	_, err = repos.ProductRepo.DeleteLastProduct(ctx, reception.ID)
+	assert.ErrorIs(t, err, errors.ErrProductStatus, "готовый к выдаче товар не удаляется")
+
+	issuedAt := time.Now().UTC().Truncate(time.Microsecond)
+	found.Status = models.ProductIssued
+	found.IssuedAt = &issuedAt
+	found.PickupAttempts = 2
+	found.RecipientEmail = "other@example.com"
+	require.NoError(t, repos.ProductRepo.UpdatePickup(ctx, found))
+
+	issued, err := repos.ProductRepo.GetByIDForUpdate(ctx, withoutCode.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductIssued, issued.Status)
+	require.NotNil(t, issued.IssuedAt)
+	assert.True(t, issuedAt.Equal(*issued.IssuedAt))
+	assert.Equal(t, 2, issued.PickupAttempts)
+	assert.Equal(t, "other@example.com", issued.RecipientEmail)
+
+	_, err = repos.ProductRepo.DeleteLastProduct(ctx, reception.ID)
+	assert.ErrorIs(t, err, errors.ErrProductStatus, "выданный товар не удаляется")
+
+	products, err := repos.ProductRepo.GetByReceptionID(ctx, reception.ID)
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, models.ProductIssued, products[0].Status)
+	assert.Equal(t, models.ProductReadyForPickup, products[1].Status)
+
+	missing := found
+	missing.ID = missingID
+	assert.ErrorIs(t, repos.ProductRepo.UpdatePickup(ctx, missing), errors.ErrNotFound)
+}
+
+func testProducts(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	pvz, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+	reception, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: pvz.ID})
+	require.NoError(t, err)
+
+	_, err = repos.ProductRepo.DeleteLastProduct(ctx, reception.ID)
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	start := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+	var added []models.Product
+	for i, productType := range []string{"электроника", "одежда", "обувь"} {
+		p, err := repos.ProductRepo.AddProduct(ctx, models.Product{
+			Type:        productType,
+			DateTime:    start.Add(time.Duration(i) * time.Second),
+			ReceptionId: reception.ID,
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, p.ID)
+		added = append(added, p)
+	}
+
+	products, err := repos.ProductRepo.GetByReceptionID(ctx, reception.ID)
+	require.NoError(t, err)
+	require.Len(t, products, 3)
+	assert.Equal(t, added[2].ID, products[0].ID, "товары отсортированы от последнего")
+
+	deleted, err := repos.ProductRepo.DeleteLastProduct(ctx, reception.ID)
+	require.NoError(t, err)
+	assert.Equal(t, added[2].ID, deleted.ID)
+	assert.Equal(t, "обувь", deleted.Type)
+	assert.Equal(t, reception.ID, deleted.ReceptionId)
+
+	products, err = repos.ProductRepo.GetByReceptionID(ctx, reception.ID)
+	require.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	_, err = repos.ProductRepo.AddProduct(ctx, models.Product{Type: "обувь", DateTime: time.Now(), ReceptionId: missingID})
+	assert.Error(t, err)
+}
+
+func testConcurrentProducts(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	pvz, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+	reception, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: pvz.ID})
+	require.NoError(t, err)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repos.ProductRepo.AddProduct(ctx, models.Product{Type: "одежда", DateTime: time.Now(), ReceptionId: reception.ID})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	products, err := repos.ProductRepo.GetByReceptionID(ctx, reception.ID)
+	require.NoError(t, err)
+	assert.Len(t, products, workers)
+}
+
+func testAudit(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	var appended []models.AuditEntry
+	for i := 0; i < 3; i++ {
+		e, err := repos.AuditRepo.Append(ctx, models.AuditEntry{
+			ActorID:    fmt.Sprintf("actor-%d", i%2),
+			Action:     models.AuditPVZCreate,
+			TargetType: "pvz",
+			TargetID:   fmt.Sprintf("pvz-%d", i),
+			After:      []byte(fmt.Sprintf(`{"id":"pvz-%d"}`, i)),
+		})
+		require.NoError(t, err)
+		assert.NotZero(t, e.ID)
+		assert.NotEmpty(t, e.Hash)
+		appended = append(appended, e)
+	}
+
+	assert.Equal(t, "", appended[0].PrevHash)
+	assert.Equal(t, appended[0].Hash, appended[1].PrevHash)
+	assert.Equal(t, appended[1].Hash, appended[2].PrevHash)
+
+	entries, err := repos.AuditRepo.ListAfter(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, e := range entries {
+		assert.Equal(t, appended[i].ID, e.ID)
+		assert.Equal(t, e.Hash, e.ComputeHash(), "хэш должен сходиться после чтения из хранилища")
+	}
+
+	entries, err = repos.AuditRepo.ListAfter(ctx, appended[1].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, appended[2].ID, entries[0].ID)
+
+	filtered, err := repos.AuditRepo.List(ctx, models.AuditFilter{ActorID: "actor-0", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, filtered, 2)
+	assert.Equal(t, appended[2].ID, filtered[0].ID, "новые записи первыми")
+
+	filtered, err = repos.AuditRepo.List(ctx, models.AuditFilter{TargetID: "pvz-1"})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "actor-1", filtered[0].ActorID)
+}
+
+func testTx(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+	errRollback := fmt.Errorf("rollback")
+
+	var committed models.PVZ
+	err := repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		committed, err = repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+		if err != nil {
+			return err
+		}
+		_, err = repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: committed.ID})
+		return err
+	})
+	require.NoError(t, err)
+
+	active, err := repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, committed.ID)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+
+	var rolledBack models.PVZ
+	err = repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		rolledBack, err = repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "казань"})
+		if err != nil {
+			return err
+		}
+
+		locked, err := repos.ReceptionRepo.GetActiveReceptionByPVZIDForUpdate(ctx, committed.ID)
+		if err != nil {
+			return err
+		}
+		if err := repos.ReceptionRepo.CloseReception(ctx, locked.ID); err != nil {
+			return err
+		}
+
+		// вложенный вызов присоединяется к внешней транзакции
+		return repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+			if _, err := repos.PvzRepo.GetPVZByIDForUpdate(ctx, rolledBack.ID); err != nil {
+				return err
+			}
+			return errRollback
+		})
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	_, err = repos.PvzRepo.GetPVZByID(ctx, rolledBack.ID)
+	assert.ErrorIs(t, err, errors.ErrNotFound, "ПВЗ из откаченной транзакции")
+
+	active, err = repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, committed.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, active, "закрытие приемки откатилось")
+
+	// ошибка внутри репозитория не ломает внешнюю транзакцию
+	err = repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: committed.ID}); !stdErrors.Is(err, errors.ErrInvalidInput) {
+			return fmt.Errorf("ожидалась ErrInvalidInput, получено %v", err)
+		}
+		return repos.ReceptionRepo.CloseReception(ctx, active.ID)
+	})
+	require.NoError(t, err)
+
+	active, err = repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, committed.ID)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	// запись аудита - часть транзакции: фиксируется и откатывается вместе с ней
+	appendAudit := func(ctx context.Context) error {
+		_, err := repos.AuditRepo.Append(ctx, models.AuditEntry{Action: models.AuditPVZCreate, TargetType: "pvz", TargetID: committed.ID})
+		return err
+	}
+	require.NoError(t, repos.TxManager.WithinTransaction(ctx, appendAudit))
+
+	err = repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, appendAudit(ctx))
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	entries, err := repos.AuditRepo.List(ctx, models.AuditFilter{TargetID: committed.ID})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "запись откаченной транзакции не сохраняется")
+}
+
+func testStaleReceptions(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	pvz, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+	reception, err := repos.ReceptionRepo.CreateReception(ctx, models.Reception{PvzId: pvz.ID})
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	stale, err := repos.ReceptionRepo.ListStale(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, stale)
+
+	stale, err = repos.ReceptionRepo.ListStale(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, reception.ID, stale[0].ID)
+
+	// товар продлевает активность приемки
+	_, err = repos.ProductRepo.AddProduct(ctx, models.Product{Type: "обувь", DateTime: now.Add(2 * time.Hour), ReceptionId: reception.ID})
+	require.NoError(t, err)
+
+	stale, err = repos.ReceptionRepo.ListStale(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, stale)
+
+	require.NoError(t, repos.ReceptionRepo.CloseReception(ctx, reception.ID))
+	stale, err = repos.ReceptionRepo.ListStale(ctx, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, stale, "закрытые приемки не возвращаются")
+}
+
+func testJobLocker(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	unlock, ok, err := repos.JobLocker.TryLock(ctx, "test_job")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = repos.JobLocker.TryLock(ctx, "test_job")
+	require.NoError(t, err)
+	assert.False(t, ok, "блокировка уже занята")
+
+	other, ok, err := repos.JobLocker.TryLock(ctx, "other_job")
+	require.NoError(t, err)
+	require.True(t, ok)
+	other()
+
+	unlock()
+	unlock, ok, err = repos.JobLocker.TryLock(ctx, "test_job")
+	require.NoError(t, err)
+	require.True(t, ok)
+	unlock()
+}
+
+func testInvitations(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	pvz, err := repos.PvzRepo.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	created, err := repos.InvitationRepo.Create(ctx, models.Invitation{
+		TokenHash: "hash-1",
+		Email:     "employee@example.com",
+		Role:      models.RoleEmployee,
+		PvzID:     &pvz.ID,
+		CreatedBy: "moderator-1",
+		ExpiresAt: expires,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	found, err := repos.InvitationRepo.GetByTokenHashForUpdate(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Equal(t, "employee@example.com", found.Email)
+	require.NotNil(t, found.PvzID)
+	assert.Equal(t, pvz.ID, *found.PvzID)
+	assert.True(t, expires.Equal(found.ExpiresAt))
+	assert.Nil(t, found.UsedAt)
+
+	_, err = repos.InvitationRepo.GetByTokenHashForUpdate(ctx, "missing")
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	_, err = repos.InvitationRepo.Create(ctx, models.Invitation{TokenHash: "hash-2", Email: "x@example.com", Role: models.RoleEmployee, PvzID: stringPtr(missingID), CreatedBy: "m", ExpiresAt: expires})
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	user, err := repos.AuthRepo.CreateUser(ctx, models.User{Email: found.Email, Password: "hash", Role: found.Role, PvzID: found.PvzID})
+	require.NoError(t, err)
+	byID, err := repos.AuthRepo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, byID.PvzID)
+	assert.Equal(t, pvz.ID, *byID.PvzID)
+
+	require.NoError(t, repos.InvitationRepo.MarkUsed(ctx, created.ID, user.ID, time.Now()))
+	assert.ErrorIs(t, repos.InvitationRepo.MarkUsed(ctx, created.ID, user.ID, time.Now()), errors.ErrNotFound, "повторное использование")
+	assert.ErrorIs(t, repos.InvitationRepo.Delete(ctx, created.ID), errors.ErrNotFound, "использованное приглашение не отзывается")
+
+	found, err = repos.InvitationRepo.GetByTokenHashForUpdate(ctx, "hash-1")
+	require.NoError(t, err)
+	require.NotNil(t, found.UsedBy)
+	assert.Equal(t, user.ID, *found.UsedBy)
+
+	pending, err := repos.InvitationRepo.Create(ctx, models.Invitation{TokenHash: "hash-3", Email: "mod@example.com", Role: models.RoleModerator, CreatedBy: "m", ExpiresAt: expires})
+	require.NoError(t, err)
+
+	list, err := repos.InvitationRepo.List(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	require.NoError(t, repos.InvitationRepo.Delete(ctx, pending.ID))
+	list, err = repos.InvitationRepo.List(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func testUserTokens(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	user, err := repos.AuthRepo.CreateUser(ctx, models.User{Email: "token@example.com", Password: "hash", Role: models.RoleCustomer})
+	require.NoError(t, err)
+	assert.Nil(t, user.EmailVerifiedAt)
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	created, err := repos.UserTokenRepo.Create(ctx, models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPasswordReset,
+		TokenHash: "reset-1",
+		ExpiresAt: expires,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+
+	_, err = repos.UserTokenRepo.Create(ctx, models.UserToken{UserID: missingID, Purpose: models.TokenPasswordReset, TokenHash: "reset-x", ExpiresAt: expires})
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+
+	found, err := repos.UserTokenRepo.GetByHashForUpdate(ctx, models.TokenPasswordReset, "reset-1")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.UserID)
+	assert.True(t, expires.Equal(found.ExpiresAt))
+	assert.Nil(t, found.UsedAt)
+
+	_, err = repos.UserTokenRepo.GetByHashForUpdate(ctx, models.TokenEmailVerification, "reset-1")
+	assert.ErrorIs(t, err, errors.ErrNotFound, "токен другого назначения")
+
+	require.NoError(t, repos.UserTokenRepo.MarkUsed(ctx, created.ID, time.Now()))
+	assert.ErrorIs(t, repos.UserTokenRepo.MarkUsed(ctx, created.ID, time.Now()), errors.ErrNotFound, "повторное использование")
+
+	second, err := repos.UserTokenRepo.Create(ctx, models.UserToken{UserID: user.ID, Purpose: models.TokenPasswordReset, TokenHash: "reset-2", ExpiresAt: expires})
+	require.NoError(t, err)
+	verify, err := repos.UserTokenRepo.Create(ctx, models.UserToken{UserID: user.ID, Purpose: models.TokenEmailVerification, TokenHash: "verify-1", ExpiresAt: expires})
+	require.NoError(t, err)
+
+	require.NoError(t, repos.UserTokenRepo.InvalidateUser(ctx, user.ID, models.TokenPasswordReset, time.Now()))
+	assert.ErrorIs(t, repos.UserTokenRepo.MarkUsed(ctx, second.ID, time.Now()), errors.ErrNotFound)
+	require.NoError(t, repos.UserTokenRepo.MarkUsed(ctx, verify.ID, time.Now()), "токены другого назначения не затрагиваются")
+
+	changedAt := time.Now().Truncate(time.Microsecond)
+	require.NoError(t, repos.AuthRepo.UpdatePassword(ctx, user.ID, "new-hash", changedAt))
+	verifiedAt := time.Now().Truncate(time.Microsecond)
+	require.NoError(t, repos.AuthRepo.MarkEmailVerified(ctx, user.ID, verifiedAt))
+
+	updated, err := repos.AuthRepo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new-hash", updated.Password)
+	require.NotNil(t, updated.PasswordChangedAt)
+	assert.True(t, changedAt.Equal(*updated.PasswordChangedAt))
+	require.NotNil(t, updated.EmailVerifiedAt)
+	assert.True(t, verifiedAt.Equal(*updated.EmailVerifiedAt))
+
+	assert.ErrorIs(t, repos.AuthRepo.UpdatePassword(ctx, missingID, "x", changedAt), errors.ErrNotFound)
+}
+
+func testUserAdmin(t *testing.T, repos *repositories.Repos) {
+	ctx := context.Background()
+
+	var ids []string
+	for _, u := range []models.User{
+		{Email: "b-ivan@example.com", Password: "hash", Role: models.RoleEmployee},
+		{Email: "a-petr@example.com", Password: "hash", Role: models.RoleEmployee},
+		{Email: "c-anna@example.com", Password: "hash", Role: models.RoleModerator},
+	} {
+		created, err := repos.AuthRepo.CreateUser(ctx, u)
+		require.NoError(t, err)
+		ids = append(ids, created.ID)
+	}
+
+	all, err := repos.AuthRepo.ListUsers(ctx, models.UserFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "a-petr@example.com", all[0].Email, "сортировка по email")
+
+	employees, err := repos.AuthRepo.ListUsers(ctx, models.UserFilter{Role: models.RoleEmployee, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, employees, 2)
+
+	found, err := repos.AuthRepo.ListUsers(ctx, models.UserFilter{Query: "IVAN", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, ids[0], found[0].ID)
+
+	for _, q := range []string{"%", "_", `\`} {
+		literal, err := repos.AuthRepo.ListUsers(ctx, models.UserFilter{Query: q, Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, literal, "спецсимвол %q ищется буквально", q)
+	}
+
+	paged, err := repos.AuthRepo.ListUsers(ctx, models.UserFilter{Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	assert.Len(t, paged, 1)
+
+	require.NoError(t, repos.AuthRepo.UpdateRole(ctx, ids[0], models.RoleModerator))
+	now := time.Now().Truncate(time.Microsecond)
+	require.NoError(t, repos.AuthRepo.SetDeactivated(ctx, ids[0], &now))
+
+	user, err := repos.AuthRepo.GetUserByID(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, user.Role)
+	require.NotNil(t, user.DeactivatedAt)
+	assert.True(t, now.Equal(*user.DeactivatedAt))
+
+	deactivated, err := repos.AuthRepo.ListUsers(ctx, models.UserFilter{Status: models.UserStatusDeactivated, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, deactivated, 1)
+	assert.Equal(t, ids[0], deactivated[0].ID)
+
+	active, err := repos.AuthRepo.ListUsers(ctx, models.UserFilter{Status: models.UserStatusActive, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, active, 2)
+
+	require.NoError(t, repos.AuthRepo.SetDeactivated(ctx, ids[0], nil))
+	user, err = repos.AuthRepo.GetUserByID(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Nil(t, user.DeactivatedAt)
+
+	require.NoError(t, repos.AuthRepo.MarkEmailVerified(ctx, ids[1], now))
+	assert.ErrorIs(t, repos.AuthRepo.UpdateEmail(ctx, ids[1], "c-anna@example.com"), errors.ErrAlreadyExists)
+	require.NoError(t, repos.AuthRepo.UpdateEmail(ctx, ids[1], "d-petr@example.com"))
+	user, err = repos.AuthRepo.GetUserByID(ctx, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, "d-petr@example.com", user.Email)
+	assert.Nil(t, user.EmailVerifiedAt, "новый email нужно подтвердить")
+
+	assert.ErrorIs(t, repos.AuthRepo.UpdateRole(ctx, missingID, models.RoleEmployee), errors.ErrNotFound)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
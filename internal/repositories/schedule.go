@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ScheduleRepository struct {
+	db       *pgxpool.Pool
+	replicas ReadRouter
+	psql     sq.StatementBuilderType
+}
+
+func NewScheduleRepository(db *pgxpool.Pool, replicas ReadRouter) *ScheduleRepository {
+	return &ScheduleRepository{db: db, replicas: replicas, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+}
+
+// GetSchedules возвращает недельные расписания и актуальные исключения ПВЗ.
+// Часовой пояс хранится в самом ПВЗ и здесь не заполняется.
+func (r *ScheduleRepository) GetSchedules(ctx context.Context, pvzIDs []string) (map[string]models.PVZSchedule, error) {
+	result := make(map[string]models.PVZSchedule, len(pvzIDs))
+	for _, id := range pvzIDs {
+		result[id] = models.PVZSchedule{
+			PvzID:      id,
+			Week:       make([]models.WorkingHours, 0),
+			Exceptions: make([]models.ScheduleException, 0),
+		}
+	}
+	if len(pvzIDs) == 0 {
+		return result, nil
+	}
+
+	// расписание и исключения читаются из одного источника
+	db := readConn(ctx, r.db, r.replicas)
+
+	query, args, err := r.psql.
+		Select("pvz_id", "weekday", "opens_at", "closes_at").
+		From("pvz_working_hours").
+		Where(sq.Eq{"pvz_id": pvzIDs}).
+		OrderBy("pvz_id", "weekday").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pvzID string
+		var hours models.WorkingHours
+		var opens, closes pgtype.Time
+		if err := rows.Scan(&pvzID, &hours.Weekday, &opens, &closes); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		hours.Opens, hours.Closes = formatTime(opens), formatTime(closes)
+
+		schedule := result[pvzID]
+		schedule.Week = append(schedule.Week, hours)
+		result[pvzID] = schedule
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// вчерашняя дата по UTC: в любом часовом поясе местный день еще мог не закончиться.
+	// Дата считается здесь, а не через CURRENT_DATE, который зависит от TimeZone сессии
+	since := time.Now().UTC().AddDate(0, 0, -1).Truncate(24 * time.Hour)
+	query, args, err = r.psql.
+		Select("id", "pvz_id", "start_date", "end_date", "opens_at", "closes_at", "reason").
+		From("pvz_schedule_exceptions").
+		Where(sq.Eq{"pvz_id": pvzIDs}).
+		Where(sq.GtOrEq{"end_date": since}).
+		OrderBy("pvz_id", "start_date").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	exceptionRows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer exceptionRows.Close()
+
+	for exceptionRows.Next() {
+		e, err := scanException(exceptionRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		schedule := result[e.PvzID]
+		schedule.Exceptions = append(schedule.Exceptions, e)
+		result[e.PvzID] = schedule
+	}
+
+	return result, exceptionRows.Err()
+}
+
+// ReplaceWeek заменяет недельное расписание ПВЗ целиком
+func (r *ScheduleRepository) ReplaceWeek(ctx context.Context, pvzID string, week []models.WorkingHours) error {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query, args, err := r.psql.
+		Delete("pvz_working_hours").
+		Where(sq.Eq{"pvz_id": pvzID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return err
+	}
+
+	if len(week) > 0 {
+		insert := r.psql.
+			Insert("pvz_working_hours").
+			Columns("pvz_id", "weekday", "opens_at", "closes_at")
+		for _, hours := range week {
+			insert = insert.Values(pvzID, hours.Weekday, parseTime(hours.Opens), parseTime(hours.Closes))
+		}
+
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, query, args...)
+		if isForeignKeyViolation(err) {
+			return errors.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ScheduleRepository) CreateException(ctx context.Context, e models.ScheduleException) (models.ScheduleException, error) {
+	start, err := time.Parse(models.DateLayout, e.StartDate)
+	if err != nil {
+		return models.ScheduleException{}, errors.ErrInvalidInput
+	}
+	end, err := time.Parse(models.DateLayout, e.EndDate)
+	if err != nil {
+		return models.ScheduleException{}, errors.ErrInvalidInput
+	}
+
+	query, args, err := r.psql.
+		Insert("pvz_schedule_exceptions").
+		Columns("pvz_id", "start_date", "end_date", "opens_at", "closes_at", "reason").
+		Values(e.PvzID, start, end, parseTime(e.Opens), parseTime(e.Closes), e.Reason).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return models.ScheduleException{}, err
+	}
+
+	err = conn(ctx, r.db).QueryRow(ctx, query, args...).Scan(&e.ID)
+	if isForeignKeyViolation(err) {
+		return models.ScheduleException{}, errors.ErrNotFound
+	}
+	if err != nil {
+		return models.ScheduleException{}, err
+	}
+
+	return e, nil
+}
+
+func (r *ScheduleRepository) DeleteException(ctx context.Context, pvzID, id string) (models.ScheduleException, error) {
+	query, args, err := r.psql.
+		Delete("pvz_schedule_exceptions").
+		Where(sq.Eq{"id": id, "pvz_id": pvzID}).
+		Suffix("RETURNING id, pvz_id, start_date, end_date, opens_at, closes_at, reason").
+		ToSql()
+	if err != nil {
+		return models.ScheduleException{}, err
+	}
+
+	e, err := scanException(conn(ctx, r.db).QueryRow(ctx, query, args...))
+	if err == pgx.ErrNoRows {
+		return models.ScheduleException{}, errors.ErrNotFound
+	}
+
+	return e, err
+}
+
+func scanException(row pgx.Row) (models.ScheduleException, error) {
+	var e models.ScheduleException
+	var start, end time.Time
+	var opens, closes pgtype.Time
+	if err := row.Scan(&e.ID, &e.PvzID, &start, &end, &opens, &closes, &e.Reason); err != nil {
+		return models.ScheduleException{}, err
+	}
+
+	e.StartDate, e.EndDate = start.Format(models.DateLayout), end.Format(models.DateLayout)
+	e.Opens, e.Closes = formatTime(opens), formatTime(closes)
+	return e, nil
+}
+
+// parseTime переводит время 15:04 в TIME, пустая строка - NULL
+func parseTime(clock string) pgtype.Time {
+	minutes, err := models.ParseClock(clock)
+	if err != nil {
+		return pgtype.Time{}
+	}
+
+	return pgtype.Time{Microseconds: int64(minutes) * int64(time.Minute/time.Microsecond), Valid: true}
+}
+
+func formatTime(t pgtype.Time) string {
+	if !t.Valid {
+		return ""
+	}
+
+	return models.FormatClock(int(t.Microseconds / int64(time.Minute/time.Microsecond)))
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/tracing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TxManager выполняет несколько операций разных репозиториев в одной транзакции.
+// Репозитории, вызванные с ctx из fn, прозрачно используют эту транзакцию.
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// maxTxAttempts - сколько раз повторять транзакцию при serialization failure или deadlock
+const maxTxAttempts = 3
+
+type txKey struct{}
+
+// querier - общее подмножество pgxpool.Pool и pgx.Tx
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// conn возвращает транзакцию из контекста или пул. Begin на транзакции
+// создает savepoint, поэтому методы репозиториев со своей транзакцией
+// корректно вкладываются во внешнюю.
+func conn(ctx context.Context, db *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// ReadRouter выбирает пул для запросов только на чтение (database.Cluster)
+type ReadRouter interface {
+	Reader() *pgxpool.Pool
+}
+
+type primaryKey struct{}
+
+// WithPrimary направляет чтения с этим контекстом в primary. Нужен, когда
+// запрос должен увидеть только что сделанную запись, а реплика может отставать.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// readConn - conn для запросов только на чтение: вне транзакции и без
+// WithPrimary запрос уходит на реплику, выбранную replicas
+func readConn(ctx context.Context, db *pgxpool.Pool, replicas ReadRouter) querier {
+	_, inTx := ctx.Value(txKey{}).(pgx.Tx)
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	if inTx || primary || replicas == nil {
+		return conn(ctx, db)
+	}
+
+	return replicas.Reader()
+}
+
+type PgTxManager struct {
+	db *pgxpool.Pool
+}
+
+func NewTxManager(db *pgxpool.Pool) *PgTxManager {
+	return &PgTxManager{db: db}
+}
+
+func (m *PgTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// уже внутри транзакции - присоединяемся к ней
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = m.run(ctx, fn)
+		if !isRetryable(err) {
+			return err
+		}
+
+		logger.FromContext(ctx).Warnf("транзакция прервана (попытка %d): %v", attempt, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt*attempt) * 10 * time.Millisecond):
+		}
+	}
+
+	return err
+}
+
+func (m *PgTxManager) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	ctx, span := tracing.Start(ctx, "db transaction")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	// 40001 serialization_failure, 40P01 deadlock_detected
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
@@ -4,11 +4,30 @@ import (
 	"context"
 	"fmt"
 	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var userColumns = []string{
+	"id", "email", "password", "role", "pvz_id", "failed_logins", "locked_until", "email_verified_at", "deactivated_at",
+	"password_changed_at",
+}
+
+func scanUser(row pgx.Row) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.PvzID,
+		&user.FailedLogins, &user.LockedUntil, &user.EmailVerifiedAt, &user.DeactivatedAt, &user.PasswordChangedAt)
+	return user, err
+}
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы подстрока искалась буквально
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 type UserRepository struct {
 	db   *pgxpool.Pool
 	psql sq.StatementBuilderType
@@ -18,57 +37,187 @@ func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{db: db, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, user models.User) error {
-	tx, err := r.db.Begin(ctx)
+func (r *UserRepository) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
 	if err != nil {
-		return err
+		return models.User{}, err
 	}
 	defer tx.Rollback(ctx)
 
 	query, args, err := r.psql.
 		Insert("users").
-		Columns("email", "password", "role").
-		Values(user.Email, user.Password, user.Role).
+		Columns("email", "password", "role", "pvz_id", "email_verified_at").
+		Values(user.Email, user.Password, user.Role, user.PvzID, user.EmailVerifiedAt).
+		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
-		return err
+		return models.User{}, err
 	}
 
-	_, err = tx.Exec(ctx, query, args...)
+	err = tx.QueryRow(ctx, query, args...).Scan(&user.ID)
+	if isUniqueViolation(err) {
+		return models.User{}, errors.ErrAlreadyExists
+	}
+	if isForeignKeyViolation(err) {
+		return models.User{}, errors.ErrNotFound
+	}
 	if err != nil {
-		return err
+		return models.User{}, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("transaction commit failed: %w", err)
+		return models.User{}, fmt.Errorf("transaction commit failed: %w", err)
 	}
 
-	return nil
+	return user, nil
 }
 
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
-	conn, err := r.db.Acquire(ctx)
-	if err != nil {
-		return models.User{}, err
-	}
+	return r.getUser(ctx, sq.Eq{"email": email})
+}
 
-	defer conn.Release()
+func (r *UserRepository) GetUserByID(ctx context.Context, id string) (models.User, error) {
+	return r.getUser(ctx, sq.Eq{"id": id})
+}
 
+func (r *UserRepository) getUser(ctx context.Context, where sq.Eq) (models.User, error) {
 	query, args, err := r.psql.
-		Select("id", "email", "password", "role").
+		Select(userColumns...).
 		From("users").
-		Where(sq.Eq{"email": email}).
+		Where(where).
 		ToSql()
 	if err != nil {
 		return models.User{}, err
 	}
 
-	row := conn.QueryRow(ctx, query, args...)
-
-	var user models.User
-	err = row.Scan(&user.ID, &user.Email, &user.Password, &user.Role)
+	user, err := scanUser(conn(ctx, r.db).QueryRow(ctx, query, args...))
+	if err == pgx.ErrNoRows || isInvalidText(err) {
+		return models.User{}, errors.ErrNotFound
+	}
 	if err != nil {
 		return models.User{}, err
 	}
 	return user, nil
 }
+
+func (r *UserRepository) IncrementFailedLogins(ctx context.Context, id string) (int, error) {
+	query, args, err := r.psql.
+		Update("users").
+		Set("failed_logins", sq.Expr("failed_logins + 1")).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING failed_logins").
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var failures int
+	err = conn(ctx, r.db).QueryRow(ctx, query, args...).Scan(&failures)
+	if err == pgx.ErrNoRows || isInvalidText(err) {
+		return 0, errors.ErrNotFound
+	}
+
+	return failures, err
+}
+
+func (r *UserRepository) LockUser(ctx context.Context, id string, until time.Time) error {
+	return r.update(ctx, id, map[string]interface{}{"locked_until": until})
+}
+
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	return r.update(ctx, id, map[string]interface{}{"failed_logins": 0, "locked_until": nil})
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id, passwordHash string, at time.Time) error {
+	return r.update(ctx, id, map[string]interface{}{"password": passwordHash, "password_changed_at": at})
+}
+
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id string, at time.Time) error {
+	return r.update(ctx, id, map[string]interface{}{"email_verified_at": at})
+}
+
+func (r *UserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	builder := r.psql.
+		Select(userColumns...).
+		From("users").
+		OrderBy("email").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset))
+
+	if filter.Query != "" {
+		builder = builder.Where(sq.ILike{"email": "%" + likeEscaper.Replace(filter.Query) + "%"})
+	}
+	if filter.Role != "" {
+		builder = builder.Where(sq.Eq{"role": filter.Role})
+	}
+	switch filter.Status {
+	case models.UserStatusActive:
+		builder = builder.Where(sq.Eq{"deactivated_at": nil})
+	case models.UserStatusDeactivated:
+		builder = builder.Where(sq.NotEq{"deactivated_at": nil})
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id, role string) error {
+	return r.update(ctx, id, map[string]interface{}{"role": role})
+}
+
+// UpdateEmail меняет email и сбрасывает его подтверждение
+func (r *UserRepository) UpdateEmail(ctx context.Context, id, email string) error {
+	err := r.update(ctx, id, map[string]interface{}{"email": email, "email_verified_at": nil})
+	if isUniqueViolation(err) {
+		return errors.ErrAlreadyExists
+	}
+
+	return err
+}
+
+func (r *UserRepository) SetDeactivated(ctx context.Context, id string, at *time.Time) error {
+	return r.update(ctx, id, map[string]interface{}{"deactivated_at": at})
+}
+
+func (r *UserRepository) update(ctx context.Context, id string, values map[string]interface{}) error {
+	query, args, err := r.psql.
+		Update("users").
+		SetMap(values).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, args...)
+	if isInvalidText(err) {
+		return errors.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UserTokenRepository struct {
+	db   *pgxpool.Pool
+	psql sq.StatementBuilderType
+}
+
+func NewUserTokenRepository(db *pgxpool.Pool) *UserTokenRepository {
+	return &UserTokenRepository{db: db, psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+}
+
+func (r *UserTokenRepository) Create(ctx context.Context, token models.UserToken) (models.UserToken, error) {
+	query, args, err := r.psql.
+		Insert("user_tokens").
+		Columns("user_id", "purpose", "token_hash", "expires_at").
+		Values(token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return models.UserToken{}, err
+	}
+
+	err = conn(ctx, r.db).QueryRow(ctx, query, args...).Scan(&token.ID, &token.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return models.UserToken{}, errors.ErrNotFound
+	}
+	if err != nil {
+		return models.UserToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *UserTokenRepository) GetByHashForUpdate(ctx context.Context, purpose, tokenHash string) (models.UserToken, error) {
+	query, args, err := r.psql.
+		Select("id", "user_id", "purpose", "token_hash", "created_at", "expires_at", "used_at").
+		From("user_tokens").
+		Where(sq.Eq{"purpose": purpose, "token_hash": tokenHash}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return models.UserToken{}, err
+	}
+
+	var token models.UserToken
+	err = conn(ctx, r.db).QueryRow(ctx, query, args...).
+		Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt)
+	if err == pgx.ErrNoRows {
+		return models.UserToken{}, errors.ErrNotFound
+	}
+	if err != nil {
+		return models.UserToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *UserTokenRepository) MarkUsed(ctx context.Context, id string, at time.Time) error {
+	query, args, err := r.psql.
+		Update("user_tokens").
+		Set("used_at", at).
+		Where(sq.Eq{"id": id, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserTokenRepository) InvalidateUser(ctx context.Context, userID, purpose string, at time.Time) error {
+	query, args, err := r.psql.
+		Update("user_tokens").
+		Set("used_at", at).
+		Where(sq.Eq{"user_id": userID, "purpose": purpose, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).Exec(ctx, query, args...)
+	return err
+}
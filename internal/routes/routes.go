@@ -3,66 +3,136 @@ package routes
 import (
 	"pvz-service/config"
 	"pvz-service/internal/handlers"
+	"pvz-service/internal/health"
+	"pvz-service/internal/jobs"
+	"pvz-service/internal/logger"
 	"pvz-service/internal/middlewares"
-	"pvz-service/internal/repositories"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/ratelimit"
 	"pvz-service/internal/services"
+	"pvz-service/internal/tracing"
+	"strings"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
-func InitRoutes(e *echo.Echo, cfg *config.Config, db *pgxpool.Pool) {
-	authMiddleware := middlewares.NewAuthMiddleware(cfg)
+func InitRoutes(e *echo.Echo, cfg *config.Config, services *services.Services, scheduler *jobs.Scheduler, checker *health.Checker, dbStats handlers.DBStatsProvider) {
+	authMiddleware := middlewares.NewAuthMiddleware(cfg, services.UserService)
 
-	authRepo := repositories.NewUserRepository(db)
-	pvzRepo := repositories.NewPVZRepository(db)
-	productRepo := repositories.NewProductRepository(db)
-	receptionRepo := repositories.NewReceptionRepository(db)
+	dlHandler := handlers.NewDummyLoginHandler(services)
+	authHandler := handlers.NewAuthHandler(services)
+	pvzHandler := handlers.NewPVZHandler(services)
+	receptionHandler := handlers.NewReceptionHandler(services)
+	scheduleHandler := handlers.NewScheduleHandler(services)
+	productHandler := handlers.NewProductHandler(services)
+	auditHandler := handlers.NewAuditHandler(services)
+	jobHandler := handlers.NewJobHandler(scheduler)
+	userHandler := handlers.NewUserHandler(services)
+	invitationHandler := handlers.NewInvitationHandler(services)
+	healthHandler := handlers.NewHealthHandler(checker)
+	logLevelHandler := handlers.NewLogLevelHandler(logger.Levels{}, services.AuditService)
+	dbStatsHandler := handlers.NewDBStatsHandler(dbStats)
 
-	repos := repositories.Repos{
-		Cfg:           cfg,
-		AuthRepo:      authRepo,
-		PvzRepo:       pvzRepo,
-		ProductRepo:   productRepo,
-		ReceptionRepo: receptionRepo,
-	}
+	authLimit, apiLimit := rateLimiters(cfg)
 
-	userService := services.NewUserService(&repos)
-	productService := services.NewProductService(&repos)
-	pvzService := services.NewPVZService(&repos)
-	ReceptionService := services.NewReceptionService(&repos)
-
-	services := services.Services{
-		UserService:      userService,
-		ProductService:   productService,
-		PvzService:       pvzService,
-		ReceptionService: ReceptionService,
-		Cfg:              cfg,
-	}
+	e.Use(otelecho.Middleware(tracing.ServiceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		return strings.HasPrefix(c.Path(), "/swagger") || middlewares.IsProbe(c)
+	})))
+	e.Use(middlewares.RequestIDMiddleware())
+	e.Use(middlewares.LoggerMiddleware())
+	e.Use(middlewares.ActorMiddleware())
 
-	dlHandler := handlers.NewDummyLoginHandler(&services)
-	authHandler := handlers.NewAuthHandler(&services)
-	pvzHandler := handlers.NewPVZHandler(&services)
-	receptionHandler := handlers.NewReceptionHandler(&services)
-	productHandler := handlers.NewProductHandler(&services)
+	// пробы оркестратора: без авторизации и ограничения частоты
+	e.GET("/healthz", healthHandler.Healthz)
+	e.GET("/readyz", healthHandler.Readyz)
 
-	e.Use(middlewares.LoggerMiddleware())
+	// токены без пароля - только для локальной разработки
+	if cfg.Auth.DummyLogin {
+		e.POST("/dummyLogin", dlHandler.DummyLogin, authLimit)
+	}
 
-	e.POST("/dummyLogin", dlHandler.DummyLogin)
+	e.POST("/register", authHandler.Register, authLimit)
+	e.POST("/register/invite", authHandler.RegisterInvite, authLimit)
+	e.POST("/login", authHandler.Login, authLimit)
 
-	e.POST("/register", authHandler.Register)
-	e.POST("/login", authHandler.Login)
+	e.POST("/auth/password/forgot", authHandler.ForgotPassword, authLimit)
+	e.POST("/auth/password/reset", authHandler.ResetPassword, authLimit)
+	e.POST("/auth/email/verify", authHandler.VerifyEmail, authLimit)
+	e.POST("/auth/email/resend", authHandler.ResendVerification, authLimit)
 
 	g := e.Group("/pvz")
-	g.Use(authMiddleware.JWTMiddleware())
+	g.Use(authMiddleware.JWTMiddleware(), apiLimit)
 
 	g.POST("/", pvzHandler.Create, authMiddleware.RequireRole("moderator"))
-	g.GET("/", pvzHandler.GetAll)
-	g.GET("/:id", pvzHandler.GetByID)
+	g.POST("/import", pvzHandler.Import, authMiddleware.RequireRole("moderator"))
+	// покупателям доступны только поиск ближайших ПВЗ и расписание, приемки и товары видят сотрудники
+	g.GET("/", pvzHandler.GetAll, authMiddleware.RequireRole(models.RoleEmployee, models.RoleModerator))
+	g.GET("/nearby", pvzHandler.Nearby)
+	g.GET("/:id", pvzHandler.GetByID, authMiddleware.RequireRole(models.RoleEmployee, models.RoleModerator))
 	g.DELETE("/:id/delete_last_product", pvzHandler.DeleteLastProduct, authMiddleware.RequireRole("client"))
 	g.PUT("/:id/close_last_reception", pvzHandler.CloseLastReception, authMiddleware.RequireRole("client"))
+	g.GET("/:id/schedule", scheduleHandler.Get)
+	g.PUT("/:id/schedule", scheduleHandler.Update, authMiddleware.RequireRole("moderator"))
+	g.POST("/:id/schedule/exceptions", scheduleHandler.AddException, authMiddleware.RequireRole("moderator"))
+	g.DELETE("/:id/schedule/exceptions/:exceptionId", scheduleHandler.DeleteException, authMiddleware.RequireRole("moderator"))
+	g.GET("/:id/receptions", receptionHandler.ListByPVZ, authMiddleware.RequireRole("client", "moderator"))
+	g.GET("/:id/receptions/active", receptionHandler.GetActive, authMiddleware.RequireRole("client", "moderator"))
+
+	r := e.Group("/receptions", authMiddleware.JWTMiddleware(), apiLimit, authMiddleware.RequireRole("client", "moderator"))
+	r.POST("", receptionHandler.Create)
+	r.GET("/:id", receptionHandler.Get)
+	r.GET("/:id/products", receptionHandler.ListProducts)
+
+	e.POST("/product", productHandler.AddProduct, authMiddleware.JWTMiddleware(), apiLimit, authMiddleware.RequireRole("client"))
+
+	p := e.Group("/products", authMiddleware.JWTMiddleware(), apiLimit)
+	p.POST("/:id/issue", productHandler.Issue, authMiddleware.RequireRole("client"))
+	p.POST("/:id/return", productHandler.Return, authMiddleware.RequireRole("client"))
+	p.POST("/:id/pickup_code", productHandler.ResetPickupCode, authMiddleware.RequireRole("moderator"))
+
+	a := e.Group("/audit", authMiddleware.JWTMiddleware(), apiLimit, authMiddleware.RequireRole("moderator"))
+	a.GET("", auditHandler.List)
+	a.GET("/verify", auditHandler.Verify)
+
+	e.GET("/jobs", jobHandler.List, authMiddleware.JWTMiddleware(), apiLimit, authMiddleware.RequireRole("moderator"))
+
+	adm := e.Group("/admin", authMiddleware.JWTMiddleware(), apiLimit, authMiddleware.RequireRole("moderator"))
+	adm.GET("/log-level", logLevelHandler.Get)
+	adm.PUT("/log-level", logLevelHandler.Set)
+	adm.GET("/db/stats", dbStatsHandler.Get)
+
+	u := e.Group("/users", authMiddleware.JWTMiddleware(), apiLimit, authMiddleware.RequireRole("moderator"))
+	u.GET("", userHandler.List)
+	u.GET("/:id", userHandler.Get)
+	u.PATCH("/:id/role", userHandler.ChangeRole)
+	u.POST("/:id/deactivate", userHandler.Deactivate)
+	u.POST("/:id/reactivate", userHandler.Reactivate)
+	u.POST("/:id/unlock", userHandler.Unlock)
+
+	me := e.Group("/me", authMiddleware.JWTMiddleware(), apiLimit)
+	me.GET("", userHandler.Me)
+	me.PATCH("", userHandler.UpdateMe)
+
+	i := e.Group("/invitations", authMiddleware.JWTMiddleware(), apiLimit, authMiddleware.RequireRole("moderator"))
+	i.POST("", invitationHandler.Create)
+	i.GET("", invitationHandler.List)
+	i.DELETE("/:id", invitationHandler.Revoke)
+}
+
+// rateLimiters возвращает ограничители для входа (по IP) и для остального
+// API (по пользователю). При RATE_LIMIT_ENABLED=false оба пропускают все запросы.
+func rateLimiters(cfg *config.Config) (echo.MiddlewareFunc, echo.MiddlewareFunc) {
+	if !cfg.RateLimit.Enabled {
+		noop := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+		return noop, noop
+	}
 
-	e.POST("/receptions", receptionHandler.Create, authMiddleware.JWTMiddleware(), authMiddleware.RequireRole("client"))
+	store := ratelimit.NewMemoryStore()
+	authLimit := middlewares.RateLimitMiddleware(store, "auth",
+		ratelimit.Limit{Rate: cfg.RateLimit.AuthRate, Burst: cfg.RateLimit.AuthBurst}, middlewares.RateKeyByIP)
+	apiLimit := middlewares.RateLimitMiddleware(store, "api",
+		ratelimit.Limit{Rate: cfg.RateLimit.APIRate, Burst: cfg.RateLimit.APIBurst}, middlewares.RateKeyByUser)
 
-	e.POST("/product", productHandler.AddProduct, authMiddleware.JWTMiddleware(), authMiddleware.RequireRole("client"))
+	return authLimit, apiLimit
 }
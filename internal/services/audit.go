@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/tracing"
+)
+
+// verifyBatchSize - сколько записей журнала читается за раз при проверке цепочки
+const verifyBatchSize = 1000
+
+type AuditService struct {
+	repos *repositories.Repos
+}
+
+func NewAuditService(repos *repositories.Repos) *AuditService {
+	return &AuditService{repos: repos}
+}
+
+func (s *AuditService) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.List")
+	defer span.End()
+
+	return s.repos.AuditRepo.List(ctx, filter)
+}
+
+// Verify пересчитывает цепочку хэшей с начала журнала и возвращает
+// id первой записи, которая не сходится.
+func (s *AuditService) Verify(ctx context.Context) (models.AuditVerifyResult, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Verify")
+	defer span.End()
+
+	result := models.AuditVerifyResult{Valid: true}
+	prevHash := ""
+	var lastID int64
+
+	for {
+		entries, err := s.repos.AuditRepo.ListAfter(ctx, lastID, verifyBatchSize)
+		if err != nil {
+			return models.AuditVerifyResult{}, err
+		}
+
+		for _, e := range entries {
+			result.Checked++
+			if e.PrevHash != prevHash || e.ComputeHash() != e.Hash {
+				id := e.ID
+				result.Valid = false
+				result.BrokenAt = &id
+				return result, nil
+			}
+			prevHash = e.Hash
+			lastID = e.ID
+		}
+
+		if len(entries) < verifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+// RecordLogLevelChange фиксирует смену уровня логирования реплики. Целью
+// записи служит имя хоста, так как уровень меняется только на одной реплике.
+func (s *AuditService) RecordLogLevelChange(ctx context.Context, before, after string) error {
+	host, _ := os.Hostname()
+	return recordAudit(ctx, s.repos, models.AuditLogLevelChange, "log_level", host,
+		map[string]string{"level": before}, map[string]string{"level": after})
+}
+
+// recordAudit пишет изменение в журнал аудита от имени пользователя из контекста.
+// Запись идет в транзакции из ctx и фиксируется или откатывается вместе с
+// изменением; ошибка записи откатывает и само изменение. Блокировка цепочки
+// хэшей держится до конца транзакции, поэтому аудит пишется последним шагом.
+func recordAudit(ctx context.Context, repos *repositories.Repos, action, targetType, targetID string, before, after interface{}) error {
+	if repos.AuditRepo == nil {
+		return nil
+	}
+
+	a := actor.FromContext(ctx)
+	entry := models.AuditEntry{
+		ActorID:    a.UserID,
+		ActorEmail: a.Email,
+		ActorRole:  a.Role,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     auditSnapshot(ctx, before),
+		After:      auditSnapshot(ctx, after),
+		RequestID:  a.RequestID,
+		IP:         a.IP,
+	}
+
+	if _, err := repos.AuditRepo.Append(ctx, entry); err != nil {
+		return fmt.Errorf("запись аудита %s: %w", action, err)
+	}
+
+	return nil
+}
+
+func auditSnapshot(ctx context.Context, v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("не удалось сериализовать снимок для аудита: %v", err)
+		return nil
+	}
+
+	return data
+}
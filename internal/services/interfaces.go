@@ -2,14 +2,75 @@ package services
 
 import (
 	"context"
+	"io"
 	"pvz-service/internal/models"
+	"time"
 )
 
+type UserServiceInterface interface {
+	CreateUser(ctx context.Context, user models.User) error
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	Authenticate(ctx context.Context, email, password string) (models.User, error)
+	UnlockUser(ctx context.Context, id string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, passwordHash string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)
+	GetUser(ctx context.Context, id string) (models.User, error)
+	ChangeRole(ctx context.Context, id, role string) (models.User, error)
+	DeactivateUser(ctx context.Context, id string) (models.User, error)
+	ReactivateUser(ctx context.Context, id string) (models.User, error)
+	UpdateProfile(ctx context.Context, id string, update models.ProfileUpdate) (models.User, error)
+}
+
+type ProductServiceInterface interface {
+	AddProduct(ctx context.Context, product models.Product, pvzID string) (models.Product, error)
+	IssueProduct(ctx context.Context, id, code string) (models.Product, error)
+	ReturnProduct(ctx context.Context, id string) (models.Product, error)
+	ResetPickupCode(ctx context.Context, id, recipientEmail string) (models.Product, error)
+	DeleteLastProduct(ctx context.Context, pvzID string) error
+	GetByReceptionID(ctx context.Context, receptionID string) ([]models.Product, error)
+}
+
+type ReceptionServiceInterface interface {
+	CreateReception(ctx context.Context, reception models.Reception, override bool) (models.Reception, error)
+	GetActiveReceptionByPVZID(ctx context.Context, pvzID string) (*models.Reception, error)
+	GetReception(ctx context.Context, id string) (models.FullReception, error)
+	GetActiveReception(ctx context.Context, pvzID string) (models.FullReception, error)
+	ListReceptions(ctx context.Context, filter models.ReceptionFilter) ([]models.Reception, error)
+	ListProducts(ctx context.Context, receptionID string, limit, offset int) ([]models.Product, error)
+	ProcessStaleReceptions(ctx context.Context, idleFor time.Duration, action string) (int, error)
+}
+
+type ScheduleServiceInterface interface {
+	GetSchedule(ctx context.Context, pvzID string) (models.PVZSchedule, error)
+	UpdateSchedule(ctx context.Context, pvzID string, update models.PVZSchedule) (models.PVZSchedule, error)
+	AddException(ctx context.Context, e models.ScheduleException) (models.ScheduleException, error)
+	DeleteException(ctx context.Context, pvzID, id string) error
+}
+
 type PVZServiceInterface interface {
-	GetAll(ctx context.Context, page, limit, from, to string) ([]models.FullPVZ, error)
+	GetAll(ctx context.Context, page int, filter models.PVZFilter) ([]models.FullPVZ, error)
 	CreatePVZ(ctx context.Context, pvz models.PVZ) (models.PVZ, error)
 	GetPVZByID(ctx context.Context, id string) (models.PVZ, error)
+	ListPVZ(ctx context.Context, limit, offset int) ([]models.PVZ, error)
+	Nearby(ctx context.Context, q models.NearbyQuery) ([]models.NearbyPVZ, error)
 	DeletePVZ(ctx context.Context, id string) error
 	DeleteLastProduct(ctx context.Context, id string) error
 	CloseLastReception(ctx context.Context, id string) error
+	ImportPVZ(ctx context.Context, r io.Reader, dryRun bool) (models.PVZImportResult, error)
+}
+
+type InvitationServiceInterface interface {
+	CreateInvitation(ctx context.Context, email, role string, pvzID *string) (models.CreatedInvitation, error)
+	ListInvitations(ctx context.Context, limit, offset int) ([]models.Invitation, error)
+	RevokeInvitation(ctx context.Context, id string) error
+	RedeemInvitation(ctx context.Context, token, email, passwordHash string) (models.User, error)
+}
+
+type AuditServiceInterface interface {
+	List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	Verify(ctx context.Context) (models.AuditVerifyResult, error)
+	RecordLogLevelChange(ctx context.Context, before, after string) error
 }
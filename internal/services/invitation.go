@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	stdErrors "errors"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/mailer"
+	"pvz-service/internal/pkg/token"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/tracing"
+	"strings"
+	"time"
+)
+
+type InvitationService struct {
+	repos  *repositories.Repos
+	mailer mailer.Mailer
+}
+
+func NewInvitationService(repos *repositories.Repos, mail mailer.Mailer) *InvitationService {
+	return &InvitationService{repos: repos, mailer: mail}
+}
+
+// CreateInvitation выпускает приглашение для сотрудника или модератора.
+// Токен возвращается только здесь и уходит письмом на email приглашенного,
+// в базе хранится его хэш.
+func (s *InvitationService) CreateInvitation(ctx context.Context, email, role string, pvzID *string) (models.CreatedInvitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.CreateInvitation")
+	defer span.End()
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || (role != models.RoleEmployee && role != models.RoleModerator) {
+		return models.CreatedInvitation{}, errors.ErrInvalidInput
+	}
+	if pvzID != nil && *pvzID == "" {
+		pvzID = nil
+	}
+
+	plain, hash, err := token.Generate()
+	if err != nil {
+		return models.CreatedInvitation{}, err
+	}
+
+	var created models.Invitation
+	err = s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.repos.InvitationRepo.Create(ctx, models.Invitation{
+			TokenHash: hash,
+			Email:     email,
+			Role:      role,
+			PvzID:     pvzID,
+			CreatedBy: actor.FromContext(ctx).UserID,
+			ExpiresAt: time.Now().Add(s.repos.Cfg.Auth.InvitationTTL),
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditInvitationCreate, "invitation", created.ID, nil, created)
+	})
+	if err != nil {
+		return models.CreatedInvitation{}, err
+	}
+
+	msg := invitationMessage(s.repos.Cfg.HTTP.PublicURL, created, plain)
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		logger.FromContext(ctx).Errorf("не удалось отправить приглашение %s: %v", created.ID, err)
+	}
+
+	return models.CreatedInvitation{Invitation: created, Token: plain}, nil
+}
+
+func (s *InvitationService) ListInvitations(ctx context.Context, limit, offset int) ([]models.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.ListInvitations")
+	defer span.End()
+
+	return s.repos.InvitationRepo.List(ctx, limit, offset)
+}
+
+func (s *InvitationService) RevokeInvitation(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "InvitationService.RevokeInvitation")
+	defer span.End()
+
+	return s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repos.InvitationRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditInvitationRevoke, "invitation", id, nil, nil)
+	})
+}
+
+// RedeemInvitation создает пользователя по приглашению. email, если передан,
+// должен совпадать с адресом из приглашения.
+func (s *InvitationService) RedeemInvitation(ctx context.Context, plainToken, email, passwordHash string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.RedeemInvitation")
+	defer span.End()
+
+	var created models.User
+	err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		inv, err := s.repos.InvitationRepo.GetByTokenHashForUpdate(ctx, token.Hash(plainToken))
+		if stdErrors.Is(err, errors.ErrNotFound) {
+			return errors.ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if inv.UsedAt != nil || !now.Before(inv.ExpiresAt) {
+			return errors.ErrInvalidToken
+		}
+		if email != "" && !strings.EqualFold(strings.TrimSpace(email), inv.Email) {
+			return errors.ErrInvalidToken
+		}
+
+		// ссылка пришла на этот адрес, поэтому email считается подтвержденным
+		created, err = s.repos.AuthRepo.CreateUser(ctx, models.User{
+			Email:           inv.Email,
+			Password:        passwordHash,
+			Role:            inv.Role,
+			PvzID:           inv.PvzID,
+			EmailVerifiedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.repos.InvitationRepo.MarkUsed(ctx, inv.ID, created.ID, now); err != nil {
+			return err
+		}
+
+		if err := recordAudit(ctx, s.repos, models.AuditInvitationRedeem, "invitation", inv.ID, inv, created); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.repos, models.AuditUserRegister, "user", created.ID, nil, created)
+	})
+
+	return created, err
+}
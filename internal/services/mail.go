@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/mailer"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Background отправляет письма после ответа на запрос. При остановке сервиса
+// Wait дожидается начатых отправок, чтобы письма с кодами и ссылками не терялись.
+type Background struct {
+	wg sync.WaitGroup
+}
+
+// Go выполняет fn в отдельной горутине, не дольше timeout и без отмены
+// вместе с запросом. Ошибка только логируется.
+func (b *Background) Go(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	cancel := func() {}
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			logger.FromContext(ctx).Errorf("не удалось отправить письмо: %v", err)
+		}
+	}()
+}
+
+// Wait дожидается всех начатых отправок, каждая ограничена своим timeout
+func (b *Background) Wait() {
+	b.wg.Wait()
+}
+
+// link собирает ссылку для письма из PUBLIC_URL, пути и токена
+func link(publicURL, path, token string) string {
+	return strings.TrimRight(publicURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func verificationMessage(publicURL, email, token string) mailer.Message {
+	return mailer.Message{
+		To:      email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Чтобы подтвердить адрес, перейдите по ссылке:\n\n%s\n\n"+
+			"Если вы не регистрировались в сервисе ПВЗ, просто проигнорируйте это письмо.\n",
+			link(publicURL, "/verify-email", token)),
+	}
+}
+
+func passwordResetMessage(publicURL, email, token string) mailer.Message {
+	return mailer.Message{
+		To:      email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Для смены пароля перейдите по ссылке:\n\n%s\n\n"+
+			"Ссылка одноразовая. Если вы не запрашивали сброс, ничего делать не нужно.\n",
+			link(publicURL, "/reset-password", token)),
+	}
+}
+
+func pickupCodeMessage(email string, product models.Product, code string) mailer.Message {
+	return mailer.Message{
+		To:      email,
+		Subject: "Код получения заказа",
+		Body: fmt.Sprintf("Код получения вашего товара (%s): %s\n\n"+
+			"Назовите его сотруднику пункта выдачи, когда товар будет готов к выдаче, и никому больше не сообщайте. "+
+			"Если код выпущен повторно, прежний код не действует.\n\nНомер товара: %s\n",
+			product.Type, code, product.ID),
+	}
+}
+
+func invitationMessage(publicURL string, inv models.Invitation, token string) mailer.Message {
+	return mailer.Message{
+		To:      inv.Email,
+		Subject: "Приглашение в сервис ПВЗ",
+		Body: fmt.Sprintf("Вас пригласили в сервис ПВЗ с ролью %s.\n\nДля регистрации перейдите по ссылке:\n\n%s\n\n"+
+			"Ссылка действительна до %s.\n",
+			inv.Role, link(publicURL, "/invite", token), inv.ExpiresAt.Format("02.01.2006 15:04 MST")),
+	}
+}
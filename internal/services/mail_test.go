@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackground_Wait(t *testing.T) {
+	var b Background
+	var sent atomic.Int32
+	release := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for range 3 {
+		b.Go(ctx, time.Second, func(ctx context.Context) error {
+			<-release
+			sent.Add(1)
+			return ctx.Err()
+		})
+	}
+	// отправка не отменяется вместе с запросом
+	cancel()
+
+	waited := make(chan struct{})
+	go func() {
+		b.Wait()
+		close(waited)
+	}()
+
+	select {
+	case <-waited:
+		t.Fatal("Wait вернулся до завершения отправок")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-waited
+	assert.Equal(t, int32(3), sent.Load())
+}
+
+func TestBackground_Timeout(t *testing.T) {
+	var b Background
+	var err atomic.Value
+
+	b.Go(context.Background(), 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		err.Store(ctx.Err())
+		return ctx.Err()
+	})
+	b.Wait()
+
+	assert.ErrorIs(t, err.Load().(error), context.DeadlineExceeded, "зависшая отправка не держит остановку дольше timeout")
+}
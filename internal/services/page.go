@@ -0,0 +1,36 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"pvz-service/internal/pkg/errors"
+)
+
+// maxPageLimit - наибольший размер страницы списков
+const maxPageLimit = 100
+
+// maxPageOffset ограничивает смещение страницы, чтобы (page-1)*limit
+// не переполнялось и не уходило в OFFSET отрицательным
+const maxPageOffset = math.MaxInt32
+
+// PageOffset проверяет номер и размер страницы и возвращает размер и смещение.
+// Нулевые значения означают первую страницу и defaultLimit, размер больше
+// maxPageLimit уменьшается до него.
+func PageOffset(page, limit, defaultLimit int) (int, int, error) {
+	if page < 0 || limit < 0 {
+		return 0, 0, fmt.Errorf("%w: page и limit не могут быть отрицательными", errors.ErrInvalidInput)
+	}
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	limit = min(limit, maxPageLimit)
+
+	if page-1 > maxPageOffset/limit {
+		return 0, 0, fmt.Errorf("%w: слишком большой номер страницы", errors.ErrInvalidInput)
+	}
+
+	return limit, (page - 1) * limit, nil
+}
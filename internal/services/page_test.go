@@ -0,0 +1,37 @@
+package services
+
+import (
+	"math"
+	"pvz-service/internal/pkg/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageOffset(t *testing.T) {
+	limit, offset, err := PageOffset(0, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 10, limit, "размер по умолчанию")
+	assert.Zero(t, offset)
+
+	limit, offset, err = PageOffset(3, 20, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 20, limit)
+	assert.Equal(t, 40, offset)
+
+	limit, offset, err = PageOffset(2, 1000, 10)
+	require.NoError(t, err)
+	assert.Equal(t, maxPageLimit, limit, "размер ограничен сверху")
+	assert.Equal(t, maxPageLimit, offset)
+
+	for _, tc := range []struct{ page, limit int }{
+		{-1, 10},
+		{1, -1},
+		{math.MaxInt, 100},
+		{math.MaxInt32, 2},
+	} {
+		_, _, err := PageOffset(tc.page, tc.limit, 10)
+		assert.ErrorIs(t, err, errors.ErrInvalidInput, "page=%d limit=%d", tc.page, tc.limit)
+	}
+}
@@ -2,31 +2,41 @@ package services
 
 import (
 	"context"
+	"crypto/subtle"
+	stdErrors "errors"
+	"fmt"
+	"net/mail"
 	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
 	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/mailer"
+	"pvz-service/internal/pkg/token"
 	"pvz-service/internal/repositories"
+	"pvz-service/internal/tracing"
+	"strings"
 	"time"
 )
 
 type ProductService struct {
-	repos *repositories.Repos
+	repos  *repositories.Repos
+	cache  *PVZCache
+	mailer mailer.Mailer
+	tasks  *Background
 }
 
-func NewProductService(repos *repositories.Repos) *ProductService {
-	return &ProductService{repos: repos}
+func NewProductService(repos *repositories.Repos, cache *PVZCache, mail mailer.Mailer, tasks *Background) *ProductService {
+	return &ProductService{repos: repos, cache: cache, mailer: mail, tasks: tasks}
 }
 
-func (s *ProductService) AddProduct(ctx context.Context, product models.Product, pvzID string) error {
-	reception, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, pvzID)
-	if err != nil {
-		return err
-	}
-	if reception == nil {
-		return errors.ErrInvalidInput
-	}
+// pickupCodeDigits - длина кода получения, который называет получатель
+const pickupCodeDigits = 6
 
-	product.DateTime = time.Now()
-	product.ReceptionId = reception.ID
+// AddProduct блокирует открытую приемку до вставки товара, чтобы ее не
+// закрыли между проверкой и записью. Код получения уходит письмом на
+// product.RecipientEmail и в ответ не попадает, время приема - во времени ПВЗ.
+func (s *ProductService) AddProduct(ctx context.Context, product models.Product, pvzID string) (models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.AddProduct")
+	defer span.End()
 
 	allowedTypes := map[string]bool{
 		"электроника": true,
@@ -35,20 +45,300 @@ func (s *ProductService) AddProduct(ctx context.Context, product models.Product,
 	}
 
 	if _, ok := allowedTypes[product.Type]; !ok {
-		return errors.ErrCategoryNotAllowed
+		return models.Product{}, errors.ErrCategoryNotAllowed
+	}
+
+	recipient, err := normalizeRecipient(product.RecipientEmail)
+	if err != nil {
+		return models.Product{}, err
+	}
+	product.RecipientEmail = recipient
+
+	code, err := token.GenerateCode(pickupCodeDigits)
+	if err != nil {
+		return models.Product{}, err
 	}
 
-	return s.repos.ProductRepo.AddProduct(ctx, product)
+	var created models.Product
+	var loc *time.Location
+	err = s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		reception, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZIDForUpdate(ctx, pvzID)
+		if err != nil {
+			return err
+		}
+		if reception == nil {
+			return errors.ErrInvalidInput
+		}
+
+		pvz, err := s.repos.PvzRepo.GetPVZByID(ctx, pvzID)
+		if err != nil {
+			return err
+		}
+		loc = models.Location(pvz.TimeZone)
+
+		product.DateTime = time.Now().UTC()
+		product.ReceptionId = reception.ID
+		product.Status = models.ProductAccepted
+		product.PickupCodeHash = s.hashCode(code)
+
+		created, err = s.repos.ProductRepo.AddProduct(ctx, product)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditProductAdd, "product", created.ID, nil, created)
+	})
+	if err != nil {
+		return models.Product{}, err
+	}
+	s.cache.invalidate(ctx, pvzID)
+
+	s.sendCode(ctx, created, code)
+
+	created.DateTime = created.DateTime.In(loc)
+	return created, nil
 }
 
 func (s *ProductService) DeleteLastProduct(ctx context.Context, pvzID string) error {
-	reception, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, pvzID)
+	ctx, span := tracing.Start(ctx, "ProductService.DeleteLastProduct")
+	defer span.End()
+
+	err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		reception, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZIDForUpdate(ctx, pvzID)
+		if err != nil {
+			return err
+		}
+		if reception == nil {
+			return errors.ErrInvalidInput
+		}
+
+		deleted, err := s.repos.ProductRepo.DeleteLastProduct(ctx, reception.ID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditProductDeleteLast, "product", deleted.ID, deleted, nil)
+	})
 	if err != nil {
 		return err
 	}
-	if reception == nil {
-		return errors.ErrInvalidInput
+	s.cache.invalidate(ctx, pvzID)
+
+	return nil
+}
+
+func (s *ProductService) GetByReceptionID(ctx context.Context, receptionID string) ([]models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetByReceptionID")
+	defer span.End()
+
+	return s.repos.ProductRepo.GetByReceptionID(ctx, receptionID)
+}
+
+// IssueProduct выдает готовый к выдаче товар получателю, назвавшему код.
+// Выдать товар может только сотрудник ПВЗ, в который он принят. Неверная
+// попытка засчитывается и попадает в журнал аудита, даже если выдача не
+// состоялась; после MaxPickupAttempts ошибок код нужно перевыпустить.
+func (s *ProductService) IssueProduct(ctx context.Context, id, code string) (models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.IssueProduct")
+	defer span.End()
+
+	wrongCode := false
+	product, err := s.updatePickup(ctx, id, true, func(product *models.Product) (string, error) {
+		if product.Status != models.ProductReadyForPickup {
+			return "", errors.ErrProductStatus
+		}
+		if product.PickupCodeHash == "" || product.PickupAttempts >= models.MaxPickupAttempts {
+			return "", errors.ErrPickupLocked
+		}
+
+		if subtle.ConstantTimeCompare([]byte(s.hashCode(code)), []byte(product.PickupCodeHash)) != 1 {
+			// попытка сохраняется вместе с транзакцией, поэтому ошибка возвращается после нее
+			product.PickupAttempts++
+			wrongCode = true
+			return models.AuditProductIssueDenied, nil
+		}
+
+		now := time.Now().UTC()
+		product.Status = models.ProductIssued
+		product.IssuedAt = &now
+		product.PickupCodeHash = ""
+		product.PickupAttempts = 0
+		return models.AuditProductIssue, nil
+	})
+	if err != nil {
+		return models.Product{}, err
+	}
+	if wrongCode {
+		return models.Product{}, errors.ErrInvalidPickupCode
 	}
 
-	return s.repos.ProductRepo.DeleteLastProduct(ctx, reception.ID)
+	return product, nil
+}
+
+// ReturnProduct возвращает отправителю невостребованный товар или товар,
+// который получатель вернул после выдачи. Как и выдача, доступен только
+// сотруднику ПВЗ товара.
+func (s *ProductService) ReturnProduct(ctx context.Context, id string) (models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.ReturnProduct")
+	defer span.End()
+
+	return s.updatePickup(ctx, id, true, func(product *models.Product) (string, error) {
+		if product.Status != models.ProductReadyForPickup && product.Status != models.ProductIssued {
+			return "", errors.ErrProductStatus
+		}
+
+		product.Status = models.ProductReturned
+		product.PickupCodeHash = ""
+		return models.AuditProductReturn, nil
+	})
+}
+
+// ResetPickupCode выпускает новый код получения взамен утерянного или
+// заблокированного и отправляет его получателю. Непустой recipientEmail
+// заменяет адрес получателя, например если прежний был указан с ошибкой.
+func (s *ProductService) ResetPickupCode(ctx context.Context, id, recipientEmail string) (models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.ResetPickupCode")
+	defer span.End()
+
+	if recipientEmail != "" {
+		var err error
+		if recipientEmail, err = normalizeRecipient(recipientEmail); err != nil {
+			return models.Product{}, err
+		}
+	}
+
+	code, err := token.GenerateCode(pickupCodeDigits)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	product, err := s.updatePickup(ctx, id, false, func(product *models.Product) (string, error) {
+		if product.Status != models.ProductAccepted && product.Status != models.ProductReadyForPickup {
+			return "", errors.ErrProductStatus
+		}
+		if recipientEmail != "" {
+			product.RecipientEmail = recipientEmail
+		}
+		if product.RecipientEmail == "" {
+			return "", fmt.Errorf("%w: у товара не указан email получателя", errors.ErrInvalidInput)
+		}
+
+		product.PickupCodeHash = s.hashCode(code)
+		product.PickupAttempts = 0
+		return models.AuditProductPickupCode, nil
+	})
+	if err != nil {
+		return models.Product{}, err
+	}
+	s.sendCode(ctx, product, code)
+
+	return product, nil
+}
+
+// hashCode - HMAC кода получения с ключом PICKUP_CODE_SECRET: по хэшу из
+// базы без ключа код не подобрать
+func (s *ProductService) hashCode(code string) string {
+	return token.HMAC([]byte(s.repos.Cfg.Auth.PickupCodeSecret), code)
+}
+
+// sendCode отправляет код получения в фоне, после фиксации товара. Если
+// письмо не дошло, модератор перевыпускает код.
+func (s *ProductService) sendCode(ctx context.Context, product models.Product, code string) {
+	msg := pickupCodeMessage(product.RecipientEmail, product, code)
+	s.tasks.Go(ctx, s.repos.Cfg.Mail.SendTimeout, func(ctx context.Context) error {
+		return s.mailer.Send(ctx, msg)
+	})
+}
+
+// authorizePVZ пропускает только сотрудника, закрепленного за ПВЗ pvzID.
+// Сотруднику другого ПВЗ возвращается ErrWrongPVZ.
+func (s *ProductService) authorizePVZ(ctx context.Context, pvzID string) error {
+	userID := actor.FromContext(ctx).UserID
+	if userID == "" {
+		return errors.ErrForbidden
+	}
+
+	user, err := s.repos.AuthRepo.GetUserByID(ctx, userID)
+	if stdErrors.Is(err, errors.ErrNotFound) {
+		return errors.ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if user.Role != models.RoleEmployee || user.PvzID == nil {
+		return errors.ErrForbidden
+	}
+	if *user.PvzID != pvzID {
+		return errors.ErrWrongPVZ
+	}
+
+	return nil
+}
+
+// normalizeRecipient приводит email получателя к нижнему регистру и проверяет,
+// что это один адрес без имени
+func normalizeRecipient(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return "", fmt.Errorf("%w: некорректный email получателя", errors.ErrInvalidInput)
+	}
+
+	return email, nil
+}
+
+// updatePickup блокирует товар, применяет change и сохраняет результат.
+// change возвращает действие для журнала аудита. С employeePVZ товар меняет
+// только сотрудник его ПВЗ. Время в ответе и сброс кэша - по ПВЗ приемки товара.
+func (s *ProductService) updatePickup(ctx context.Context, id string, employeePVZ bool, change func(product *models.Product) (string, error)) (models.Product, error) {
+	var (
+		updated models.Product
+		pvzID   string
+		loc     *time.Location
+	)
+	err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		product, err := s.repos.ProductRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		reception, err := s.repos.ReceptionRepo.GetByID(ctx, product.ReceptionId)
+		if err != nil {
+			return err
+		}
+		pvz, err := s.repos.PvzRepo.GetPVZByID(ctx, reception.PvzId)
+		if err != nil {
+			return err
+		}
+		if employeePVZ {
+			if err := s.authorizePVZ(ctx, pvz.ID); err != nil {
+				return err
+			}
+		}
+		pvzID, loc = pvz.ID, models.Location(pvz.TimeZone)
+
+		before := product
+		action, err := change(&product)
+		if err != nil {
+			return err
+		}
+		if err := s.repos.ProductRepo.UpdatePickup(ctx, product); err != nil {
+			return err
+		}
+
+		if err := recordAudit(ctx, s.repos, action, "product", product.ID, before, product); err != nil {
+			return err
+		}
+		updated = product
+		return nil
+	})
+	if err != nil {
+		return models.Product{}, err
+	}
+	s.cache.invalidate(ctx, pvzID)
+
+	updated.DateTime = updated.DateTime.In(loc)
+	if updated.IssuedAt != nil {
+		issuedAt := updated.IssuedAt.In(loc)
+		updated.IssuedAt = &issuedAt
+	}
+	return updated, nil
 }
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"pvz-service/config"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/mailer"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/repositories/memory"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureMailer передает отправленные письма в канал
+type captureMailer chan mailer.Message
+
+func (m captureMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m <- msg
+	return nil
+}
+
+var pickupCodeRe = regexp.MustCompile(`\): (\d+)\n`)
+
+// receiveCode ждет письмо с кодом получения и возвращает код
+func (m captureMailer) receiveCode(t *testing.T, to string) string {
+	t.Helper()
+
+	select {
+	case msg := <-m:
+		assert.Equal(t, to, msg.To)
+		match := pickupCodeRe.FindStringSubmatch(msg.Body)
+		require.Len(t, match, 2, msg.Body)
+		return match[1]
+	case <-time.After(time.Second):
+		t.Fatal("письмо с кодом не отправлено")
+		return ""
+	}
+}
+
+type pickupEnv struct {
+	repos    *repositories.Repos
+	services *Services
+	mail     captureMailer
+	pvzID    string
+	// employee - контекст сотрудника ПВЗ pvzID, stranger - сотрудника другого ПВЗ
+	employee context.Context
+	stranger context.Context
+}
+
+func newPickupEnv(t *testing.T) pickupEnv {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Storage = "memory"
+	cfg.Mail.SendTimeout = time.Second
+	repos := memory.NewRepos(&cfg)
+	mail := make(captureMailer, 4)
+	services := NewServices(repos, mail)
+	ctx := context.Background()
+
+	pvz, err := services.PvzService.CreatePVZ(ctx, models.PVZ{City: "москва"})
+	require.NoError(t, err)
+	other, err := services.PvzService.CreatePVZ(ctx, models.PVZ{City: "казань"})
+	require.NoError(t, err)
+
+	employee, err := repos.AuthRepo.CreateUser(ctx, models.User{Email: "employee@example.com", Role: models.RoleEmployee, PvzID: &pvz.ID})
+	require.NoError(t, err)
+	stranger, err := repos.AuthRepo.CreateUser(ctx, models.User{Email: "stranger@example.com", Role: models.RoleEmployee, PvzID: &other.ID})
+	require.NoError(t, err)
+
+	return pickupEnv{
+		repos:    repos,
+		services: services,
+		mail:     mail,
+		pvzID:    pvz.ID,
+		employee: actor.WithActor(ctx, actor.Actor{UserID: employee.ID, Role: employee.Role}),
+		stranger: actor.WithActor(ctx, actor.Actor{UserID: stranger.ID, Role: stranger.Role}),
+	}
+}
+
+// addProduct открывает приемку и принимает в нее товар, возвращает товар и
+// код из письма получателю
+func (env pickupEnv) addProduct(t *testing.T) (models.Product, string) {
+	t.Helper()
+
+	_, err := env.services.ReceptionService.CreateReception(env.employee, models.Reception{PvzId: env.pvzID}, true)
+	require.NoError(t, err)
+
+	product, err := env.services.ProductService.AddProduct(env.employee,
+		models.Product{Type: "электроника", RecipientEmail: " Client@Example.com "}, env.pvzID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductAccepted, product.Status)
+	assert.Equal(t, "client@example.com", product.RecipientEmail)
+
+	code := env.mail.receiveCode(t, "client@example.com")
+	stored := env.product(t, product.ID)
+	assert.NotEmpty(t, stored.PickupCodeHash)
+	assert.NotEqual(t, code, stored.PickupCodeHash, "в базе хранится только HMAC кода")
+
+	return product, code
+}
+
+func (env pickupEnv) product(t *testing.T, id string) models.Product {
+	t.Helper()
+
+	product, err := env.repos.ProductRepo.GetByIDForUpdate(context.Background(), id)
+	require.NoError(t, err)
+	return product
+}
+
+// wrongCode возвращает код той же длины, отличный от code
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestProductService_IssueProduct(t *testing.T) {
+	env := newPickupEnv(t)
+	product, code := env.addProduct(t)
+	productService := env.services.ProductService
+
+	_, err := productService.IssueProduct(env.employee, product.ID, code)
+	assert.ErrorIs(t, err, errors.ErrProductStatus, "приемка еще открыта")
+
+	require.NoError(t, env.services.PvzService.CloseLastReception(env.employee, env.pvzID))
+	assert.Equal(t, models.ProductReadyForPickup, env.product(t, product.ID).Status)
+
+	_, err = productService.IssueProduct(env.stranger, product.ID, code)
+	assert.ErrorIs(t, err, errors.ErrWrongPVZ, "сотрудник другого ПВЗ")
+	assert.ErrorIs(t, err, errors.ErrForbidden)
+
+	// модератор с тем же pvz_id - не сотрудник ПВЗ
+	moderator, err := env.repos.AuthRepo.CreateUser(context.Background(),
+		models.User{Email: "moderator@example.com", Role: models.RoleModerator, PvzID: &env.pvzID})
+	require.NoError(t, err)
+	for name, ctx := range map[string]context.Context{
+		"без пользователя": context.Background(),
+		"модератор":        actor.WithActor(context.Background(), actor.Actor{UserID: moderator.ID, Role: moderator.Role}),
+	} {
+		_, err = productService.IssueProduct(ctx, product.ID, code)
+		assert.ErrorIs(t, err, errors.ErrForbidden, name)
+		assert.NotErrorIs(t, err, errors.ErrWrongPVZ, name)
+	}
+	assert.Zero(t, env.product(t, product.ID).PickupAttempts, "отказ в доступе не тратит попытку")
+
+	for i := 1; i <= models.MaxPickupAttempts; i++ {
+		_, err = productService.IssueProduct(env.employee, product.ID, wrongCode(code))
+		assert.ErrorIs(t, err, errors.ErrInvalidPickupCode)
+		assert.Equal(t, i, env.product(t, product.ID).PickupAttempts)
+	}
+
+	_, err = productService.IssueProduct(env.employee, product.ID, code)
+	assert.ErrorIs(t, err, errors.ErrPickupLocked, "верный код после блокировки не принимается")
+
+	_, err = productService.ResetPickupCode(context.Background(), product.ID, "")
+	require.NoError(t, err)
+	newCode := env.mail.receiveCode(t, "client@example.com")
+	assert.Zero(t, env.product(t, product.ID).PickupAttempts)
+
+	issued, err := productService.IssueProduct(env.employee, product.ID, newCode)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductIssued, issued.Status)
+	require.NotNil(t, issued.IssuedAt)
+
+	stored := env.product(t, product.ID)
+	assert.Empty(t, stored.PickupCodeHash)
+	assert.Zero(t, stored.PickupAttempts)
+
+	_, err = productService.IssueProduct(env.employee, product.ID, newCode)
+	assert.ErrorIs(t, err, errors.ErrProductStatus, "товар уже выдан")
+}
+
+func TestProductService_ReturnProduct(t *testing.T) {
+	env := newPickupEnv(t)
+	product, _ := env.addProduct(t)
+	productService := env.services.ProductService
+
+	_, err := productService.ReturnProduct(env.employee, product.ID)
+	assert.ErrorIs(t, err, errors.ErrProductStatus, "приемка еще открыта")
+
+	require.NoError(t, env.services.PvzService.CloseLastReception(env.employee, env.pvzID))
+
+	_, err = productService.ReturnProduct(env.stranger, product.ID)
+	assert.ErrorIs(t, err, errors.ErrWrongPVZ)
+	assert.Equal(t, models.ProductReadyForPickup, env.product(t, product.ID).Status)
+
+	returned, err := productService.ReturnProduct(env.employee, product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductReturned, returned.Status)
+	assert.Empty(t, env.product(t, product.ID).PickupCodeHash)
+
+	_, err = productService.ResetPickupCode(context.Background(), product.ID, "")
+	assert.ErrorIs(t, err, errors.ErrProductStatus, "для возвращенного товара код не выпускается")
+}
+
+func TestProductService_ResetPickupCode(t *testing.T) {
+	env := newPickupEnv(t)
+	product, code := env.addProduct(t)
+	productService := env.services.ProductService
+
+	_, err := productService.ResetPickupCode(context.Background(), product.ID, "not an email")
+	assert.ErrorIs(t, err, errors.ErrInvalidInput)
+
+	updated, err := productService.ResetPickupCode(context.Background(), product.ID, "New@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", updated.RecipientEmail)
+	newCode := env.mail.receiveCode(t, "new@example.com")
+
+	require.NoError(t, env.services.PvzService.CloseLastReception(env.employee, env.pvzID))
+
+	if newCode != code {
+		_, err = productService.IssueProduct(env.employee, product.ID, code)
+		assert.ErrorIs(t, err, errors.ErrInvalidPickupCode, "старый код не действует")
+	}
+	_, err = productService.IssueProduct(env.employee, product.ID, newCode)
+	assert.NoError(t, err)
+}
+
+func TestProductService_AddProductInvalidRecipient(t *testing.T) {
+	env := newPickupEnv(t)
+
+	_, err := env.services.ReceptionService.CreateReception(env.employee, models.Reception{PvzId: env.pvzID}, true)
+	require.NoError(t, err)
+
+	for _, email := range []string{"", "not an email", "Client <client@example.com>"} {
+		_, err := env.services.ProductService.AddProduct(env.employee,
+			models.Product{Type: "электроника", RecipientEmail: email}, env.pvzID)
+		assert.ErrorIs(t, err, errors.ErrInvalidInput, "email %q", email)
+	}
+}
+
+func TestReceptionService_ProcessStaleReceptionsMarksReady(t *testing.T) {
+	env := newPickupEnv(t)
+	product, code := env.addProduct(t)
+
+	// приемка и товар только что созданы, отрицательный срок делает их устаревшими
+	processed, err := env.services.ReceptionService.ProcessStaleReceptions(context.Background(), -time.Minute, models.StaleReceptionClose)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Equal(t, models.ProductReadyForPickup, env.product(t, product.ID).Status)
+
+	issued, err := env.services.ProductService.IssueProduct(env.employee, product.ID, code)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductIssued, issued.Status)
+}
@@ -2,75 +2,195 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"math"
 	"pvz-service/internal/models"
 	"pvz-service/internal/pkg/errors"
 	"pvz-service/internal/repositories"
-	"strconv"
+	"pvz-service/internal/tracing"
 	"strings"
 	"sync"
 	"time"
+)
+
+// cityTimeZones - часовой пояс ПВЗ по умолчанию. Казань живет по
+// московскому времени, отдельного пояса IANA у нее нет.
+var cityTimeZones = map[string]string{
+	"москва":          "Europe/Moscow",
+	"санкт-петербург": "Europe/Moscow",
+	"казань":          "Europe/Moscow",
+}
 
-	"github.com/sirupsen/logrus"
+const (
+	defaultNearbyRadius = 5000.0
+	maxNearbyRadius     = 50000.0
+	defaultNearbyLimit  = 10
+	maxNearbyLimit      = 100
 )
 
 type PVZService struct {
 	repos *repositories.Repos
+	cache *PVZCache
 }
 
-func NewPVZService(repos *repositories.Repos) *PVZService {
-	return &PVZService{repos: repos}
+func NewPVZService(repos *repositories.Repos, cache *PVZCache) *PVZService {
+	return &PVZService{repos: repos, cache: cache}
 }
 
-func (s *PVZService) GetAll(ctx context.Context, pageStr, limitStr, fromStr, toStr string) ([]models.FullPVZ, error) {
+// GetAll возвращает страницу ПВЗ. Периоды filter - календарные дни в поясе
+// filter.Period.TimeZone, а если он не задан - в поясе каждого ПВЗ. Время в
+// ответе переводится в тот же пояс.
+func (s *PVZService) GetAll(ctx context.Context, page int, filter models.PVZFilter) ([]models.FullPVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.GetAll")
+	defer span.End()
 
-	page := 1
-	if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-		page = p
+	limit, offset, err := PageOffset(page, filter.Limit, 10)
+	if err != nil {
+		return nil, err
 	}
-	limit := 10
-	if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-		limit = l
+	filter.Limit, filter.Offset = limit, offset
+
+	if err := normalizePVZFilter(&filter); err != nil {
+		return nil, err
 	}
+	tz := filter.Period.TimeZone
 
-	offset := (page - 1) * limit
+	key, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
 
-	var from, to time.Time
-	var err error
+	// расписания кэшируются вместе со списком, открыт ли ПВЗ - считается при каждом запросе
+	cached, err := cachedRead(ctx, s.cache, pvzListCachePrefix+string(key), func(ctx context.Context) (pvzListWithSchedules, error) {
+		pvzs, err := s.getAll(ctx, filter)
+		if err != nil {
+			return pvzListWithSchedules{}, err
+		}
 
-	if fromStr != "" {
-		from, err = time.Parse("2006-01-02", fromStr)
+		ids := make([]string, 0, len(pvzs))
+		for _, pvz := range pvzs {
+			ids = append(ids, pvz.ID)
+		}
+		schedules, err := s.repos.ScheduleRepo.GetSchedules(ctx, ids)
 		if err != nil {
-			return nil, errors.ErrInvalidInput
+			return pvzListWithSchedules{}, err
 		}
+
+		return pvzListWithSchedules{PVZs: pvzs, Schedules: schedules}, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if toStr != "" {
-		to, err = time.Parse("2006-01-02", toStr)
-		if err != nil {
-			return nil, errors.ErrInvalidInput
+	now := time.Now()
+	for i, pvz := range cached.PVZs {
+		schedule := cached.Schedules[pvz.ID]
+		schedule.TimeZone = pvz.TimeZone
+		cached.PVZs[i].IsOpenNow = schedule.IsOpenAt(now)
+
+		zone := pvz.TimeZone
+		if tz != "" {
+			zone = tz
+		}
+		localizeFullPVZ(&cached.PVZs[i], models.Location(zone))
+	}
+
+	return cached.PVZs, nil
+}
+
+// normalizePVZFilter проверяет фильтр списка ПВЗ и выставляет сортировку по
+// умолчанию: даты - от новых к старым, город - по алфавиту
+func normalizePVZFilter(filter *models.PVZFilter) error {
+	for _, period := range []models.DayPeriod{filter.Period, filter.Registered} {
+		if !period.From.IsZero() && !period.To.IsZero() && period.To.Before(period.From) {
+			return fmt.Errorf("%w: конец периода раньше начала", errors.ErrInvalidInput)
 		}
 	}
 
-	// TODO - добавить получение приемок и их товаров
-	PVZs, err := s.repos.PvzRepo.GetAll(ctx, limit, offset, from, to)
+	if filter.Period.TimeZone != "" {
+		if err := validateTimeZone(filter.Period.TimeZone); err != nil {
+			return err
+		}
+	}
+
+	filter.City = strings.ToLower(strings.TrimSpace(filter.City))
+	if filter.MinProducts < 0 {
+		return fmt.Errorf("%w: minProducts не может быть отрицательным", errors.ErrInvalidInput)
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = models.PVZSortRegistrationDate
+	case models.PVZSortRegistrationDate, models.PVZSortCity, models.PVZSortLastReception:
+	default:
+		return fmt.Errorf("%w: сортировка возможна по registrationDate, city или lastReception", errors.ErrInvalidInput)
+	}
+
+	switch filter.Order {
+	case "":
+		filter.Order = models.SortDesc
+		if filter.Sort == models.PVZSortCity {
+			filter.Order = models.SortAsc
+		}
+	case models.SortAsc, models.SortDesc:
+	default:
+		return fmt.Errorf("%w: order должен быть asc или desc", errors.ErrInvalidInput)
+	}
+
+	return nil
+}
+
+// localizeFullPVZ переводит время ПВЗ, приемок и товаров в пояс loc,
+// чтобы в ответе было местное время со смещением
+func localizeFullPVZ(pvz *models.FullPVZ, loc *time.Location) {
+	pvz.RegistrationDate = pvz.RegistrationDate.In(loc)
+	for id, reception := range pvz.Receptions {
+		reception.DateTime = reception.DateTime.In(loc)
+		for i := range reception.Products {
+			reception.Products[i].DateTime = reception.Products[i].DateTime.In(loc)
+		}
+		pvz.Receptions[id] = reception
+	}
+}
+
+func localizePVZ(pvz *models.PVZ) {
+	pvz.RegistrationDate = pvz.RegistrationDate.In(models.Location(pvz.TimeZone))
+}
+
+type pvzListWithSchedules struct {
+	PVZs      []models.FullPVZ
+	Schedules map[string]models.PVZSchedule
+}
+
+type pvzWithSchedule struct {
+	PVZ      models.PVZ
+	Schedule models.PVZSchedule
+}
+
+func (s *PVZService) getAll(ctx context.Context, filter models.PVZFilter) ([]models.FullPVZ, error) {
+	PVZs, err := s.repos.PvzRepo.GetAll(ctx, filter)
 	if err != nil {
-		return nil, errors.ErrInvalidInput
+		return nil, err
 	}
 
 	var wg sync.WaitGroup
+	errs := make([]error, len(PVZs))
 
-	for _, pvz := range PVZs {
+	for i, pvz := range PVZs {
 		wg.Add(1)
 
 		go func() {
 			defer wg.Done()
 			for key, reception := range pvz.Receptions {
-				prodcuts, err := s.repos.ProductRepo.GetByReceptionID(ctx, reception.ID)
+				products, err := s.repos.ProductRepo.GetByReceptionID(ctx, reception.ID)
 				if err != nil {
+					errs[i] = fmt.Errorf("products of reception %s: %w", reception.ID, err)
 					return
 				}
 
-				reception.Products = prodcuts
+				reception.Products = products
 				pvz.Receptions[key] = reception
 			}
 		}()
@@ -79,61 +199,301 @@ func (s *PVZService) GetAll(ctx context.Context, pageStr, limitStr, fromStr, toS
 
 	wg.Wait()
 
-	return PVZs, err
+	if err := stdErrors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return PVZs, nil
 }
 
 func (s *PVZService) CreatePVZ(ctx context.Context, pvz models.PVZ) (models.PVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.CreatePVZ")
+	defer span.End()
+
 	pvz.City = strings.ToLower(pvz.City)
 
-	allowedCities := map[string]bool{
-		"москва":          true,
-		"санкт-петербург": true,
-		"казань":          true,
+	if _, ok := models.PVZCities[pvz.City]; !ok {
+		return models.PVZ{}, errors.ErrCityNotAllowed
 	}
 
-	if _, ok := allowedCities[pvz.City]; !ok {
-		return models.PVZ{}, errors.ErrCityNotAllowed
+	pvz.Address = strings.TrimSpace(pvz.Address)
+	if err := validateLocation(pvz.Latitude, pvz.Longitude); err != nil {
+		return models.PVZ{}, err
 	}
 
-	return s.repos.PvzRepo.CreatePVZ(ctx, pvz)
+	if pvz.TimeZone == "" {
+		pvz.TimeZone = cityTimeZones[pvz.City]
+	}
+	if err := validateTimeZone(pvz.TimeZone); err != nil {
+		return models.PVZ{}, err
+	}
+
+	var created models.PVZ
+	err := s.withInvalidation(ctx, "", func(ctx context.Context) error {
+		var err error
+		created, err = s.repos.PvzRepo.CreatePVZ(ctx, pvz)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditPVZCreate, "pvz", created.ID, nil, created)
+	})
+	if err != nil {
+		return models.PVZ{}, err
+	}
+
+	// расписание еще не настроено
+	created.IsOpenNow = true
+	localizePVZ(&created)
+	return created, nil
 }
 
 func (s *PVZService) GetPVZByID(ctx context.Context, id string) (models.PVZ, error) {
-	return s.repos.PvzRepo.GetPVZByID(ctx, id)
+	ctx, span := tracing.Start(ctx, "PVZService.GetPVZByID")
+	defer span.End()
+
+	cached, err := s.getWithSchedule(ctx, id)
+	if err != nil {
+		return models.PVZ{}, err
+	}
+
+	cached.PVZ.IsOpenNow = cached.Schedule.IsOpenAt(time.Now())
+	localizePVZ(&cached.PVZ)
+	return cached.PVZ, nil
 }
 
-func (s *PVZService) DeletePVZ(ctx context.Context, id string) error {
-	return s.repos.PvzRepo.DeletePVZ(ctx, id)
+// getWithSchedule возвращает ПВЗ с расписанием из кэша, расписание
+// сбрасывается вместе с записью ПВЗ
+func (s *PVZService) getWithSchedule(ctx context.Context, id string) (pvzWithSchedule, error) {
+	return cachedRead(ctx, s.cache, pvzCachePrefix+id, func(ctx context.Context) (pvzWithSchedule, error) {
+		pvz, err := s.repos.PvzRepo.GetPVZByID(ctx, id)
+		if err != nil {
+			return pvzWithSchedule{}, err
+		}
+
+		schedules, err := s.repos.ScheduleRepo.GetSchedules(ctx, []string{id})
+		if err != nil {
+			return pvzWithSchedule{}, err
+		}
+
+		schedule := schedules[id]
+		schedule.TimeZone = pvz.TimeZone
+		return pvzWithSchedule{PVZ: pvz, Schedule: schedule}, nil
+	})
 }
 
-func (s *PVZService) DeleteLastProduct(ctx context.Context, id string) error {
-	pvz, err := s.repos.PvzRepo.GetPVZByID(ctx, id)
+func (s *PVZService) ListPVZ(ctx context.Context, limit, offset int) ([]models.PVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.ListPVZ")
+	defer span.End()
+
+	pvzs, err := s.repos.PvzRepo.List(ctx, limit, offset)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := s.setOpenStatus(ctx, len(pvzs), func(i int) *models.PVZ { return &pvzs[i] }); err != nil {
+		return nil, err
+	}
+
+	return pvzs, nil
+}
+
+// setOpenStatus заполняет IsOpenNow у n ПВЗ и переводит их время в местное,
+// pvz(i) возвращает i-й из них
+func (s *PVZService) setOpenStatus(ctx context.Context, n int, pvz func(i int) *models.PVZ) error {
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ids = append(ids, pvz(i).ID)
 	}
 
-	reception, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, pvz.ID)
+	schedules, err := s.repos.ScheduleRepo.GetSchedules(ctx, ids)
 	if err != nil {
 		return err
 	}
 
-	if reception == nil {
-		return errors.ErrNoReceprionsFound
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		p := pvz(i)
+		schedule := schedules[p.ID]
+		schedule.TimeZone = p.TimeZone
+		p.IsOpenNow = schedule.IsOpenAt(now)
+		localizePVZ(p)
 	}
 
-	logrus.Info(pvz, reception)
-	return s.repos.ProductRepo.DeleteLastProduct(ctx, reception.ID)
+	return nil
 }
 
-func (s *PVZService) CloseLastReception(ctx context.Context, id string) error {
-	reception, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, id)
-	if err != nil {
-		return err
+// Nearby ищет ПВЗ рядом с точкой. Радиус в метрах, по умолчанию 5 км;
+// радиус и размер выдачи ограничены, чтобы запрос не превращался в полный перебор.
+func (s *PVZService) Nearby(ctx context.Context, q models.NearbyQuery) ([]models.NearbyPVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.Nearby")
+	defer span.End()
+
+	if err := validateLocation(&q.Lat, &q.Lon); err != nil {
+		return nil, err
+	}
+
+	if q.Radius == 0 {
+		q.Radius = defaultNearbyRadius
+	}
+	if q.Radius < 0 || q.Radius > maxNearbyRadius {
+		return nil, fmt.Errorf("%w: радиус должен быть от 0 до %.0f м", errors.ErrInvalidInput, maxNearbyRadius)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultNearbyLimit
+	}
+	if q.Limit < 0 || q.Limit > maxNearbyLimit {
+		return nil, fmt.Errorf("%w: limit должен быть от 1 до %d", errors.ErrInvalidInput, maxNearbyLimit)
+	}
+
+	if !q.OpenNow {
+		pvzs, err := s.repos.PvzRepo.Nearby(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.setOpenStatus(ctx, len(pvzs), func(i int) *models.PVZ { return &pvzs[i].PVZ }); err != nil {
+			return nil, err
+		}
+		return pvzs, nil
+	}
+
+	// открытость зависит от расписания в местном времени и не считается в SQL:
+	// ближайшие ПВЗ читаются страницами, пока не наберется limit открытых
+	result := make([]models.NearbyPVZ, 0, q.Limit)
+	batch := q
+	batch.Limit = maxNearbyLimit
+	for {
+		pvzs, err := s.repos.PvzRepo.Nearby(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.setOpenStatus(ctx, len(pvzs), func(i int) *models.PVZ { return &pvzs[i].PVZ }); err != nil {
+			return nil, err
+		}
+
+		for _, pvz := range pvzs {
+			if pvz.IsOpenNow {
+				result = append(result, pvz)
+			}
+			if len(result) == q.Limit {
+				return result, nil
+			}
+		}
+
+		if len(pvzs) < batch.Limit {
+			return result, nil
+		}
+		batch.Offset += batch.Limit
+	}
+}
+
+// validateLocation проверяет, что координаты заданы парой и лежат в допустимых диапазонах
+func validateLocation(lat, lon *float64) error {
+	if msg := locationError(lat, lon); msg != "" {
+		return fmt.Errorf("%w: %s", errors.ErrInvalidInput, msg)
+	}
+
+	return nil
+}
+
+func validateTimeZone(name string) error {
+	if name == "" || name == "Local" {
+		return fmt.Errorf("%w: не указан часовой пояс", errors.ErrInvalidInput)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("%w: неизвестный часовой пояс %q", errors.ErrInvalidInput, name)
+	}
+
+	return nil
+}
+
+func locationError(lat, lon *float64) string {
+	switch {
+	case (lat == nil) != (lon == nil):
+		return "широта и долгота задаются вместе"
+	case lat == nil:
+		return ""
+	case math.IsNaN(*lat) || *lat < -90 || *lat > 90:
+		return "широта должна быть от -90 до 90"
+	case math.IsNaN(*lon) || *lon < -180 || *lon > 180:
+		return "долгота должна быть от -180 до 180"
 	}
 
-	if reception == nil {
-		return errors.ErrNoReceprionsFound
+	return ""
+}
+
+func (s *PVZService) DeletePVZ(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "PVZService.DeletePVZ")
+	defer span.End()
+
+	return s.withInvalidation(ctx, id, func(ctx context.Context) error {
+		pvz, err := s.repos.PvzRepo.GetPVZByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repos.PvzRepo.DeletePVZ(ctx, id); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditPVZDelete, "pvz", id, pvz, nil)
+	})
+}
+
+func (s *PVZService) DeleteLastProduct(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "PVZService.DeleteLastProduct")
+	defer span.End()
+
+	return s.withInvalidation(ctx, id, func(ctx context.Context) error {
+		pvz, err := s.repos.PvzRepo.GetPVZByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		reception, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZIDForUpdate(ctx, pvz.ID)
+		if err != nil {
+			return err
+		}
+
+		if reception == nil {
+			return errors.ErrNoReceprionsFound
+		}
+
+		deleted, err := s.repos.ProductRepo.DeleteLastProduct(ctx, reception.ID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditProductDeleteLast, "product", deleted.ID, deleted, nil)
+	})
+}
+
+// CloseLastReception блокирует приемку, поэтому товар, добавляемый
+// параллельно, либо попадет в нее до закрытия, либо получит ошибку.
+func (s *PVZService) CloseLastReception(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "PVZService.CloseLastReception")
+	defer span.End()
+
+	return s.withInvalidation(ctx, id, func(ctx context.Context) error {
+		reception, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if reception == nil {
+			return errors.ErrNoReceprionsFound
+		}
+
+		return closeReception(ctx, s.repos, *reception, models.AuditReceptionClose)
+	})
+}
+
+// withInvalidation выполняет fn в транзакции и после коммита сбрасывает кэш ПВЗ pvzID
+func (s *PVZService) withInvalidation(ctx context.Context, pvzID string, fn func(ctx context.Context) error) error {
+	if err := s.repos.TxManager.WithinTransaction(ctx, fn); err != nil {
+		return err
 	}
+	s.cache.invalidate(ctx, pvzID)
 
-	return s.repos.ReceptionRepo.CloseReception(ctx, reception.ID)
+	return nil
 }
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/pkg/cache"
+	"pvz-service/internal/repositories"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	pvzCachePrefix     = "pvz:"
+	pvzListCachePrefix = "pvz:list:"
+)
+
+// PVZCache кэширует чтения ПВЗ. Списки включают приемки и товары, поэтому
+// любое изменение ПВЗ, приемки или товара сбрасывает все списки и запись
+// самого ПВЗ - на этой реплике сразу, на остальных через CacheNotifier.
+type PVZCache struct {
+	cache    cache.Cache
+	ttl      time.Duration
+	notifier repositories.CacheNotifierInterface
+
+	// gen меняется при каждом сбросе: значение, загруженное до сброса,
+	// не должно попасть в кэш после него
+	mu  sync.Mutex
+	gen uint64
+}
+
+func NewPVZCache(repos *repositories.Repos) *PVZCache {
+	cfg := repos.Cfg.Cache
+
+	var c cache.Cache = cache.Nop{}
+	if cfg.Size > 0 {
+		c = cache.NewLRU(cfg.Size)
+	}
+
+	return &PVZCache{cache: c, ttl: cfg.TTL, notifier: repos.CacheNotifier}
+}
+
+// Evict сбрасывает на этой реплике запись ПВЗ pvzID и все списки.
+// Пустой pvzID сбрасывает только списки.
+func (c *PVZCache) Evict(ctx context.Context, pvzID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+
+	if pvzID != "" {
+		if err := c.cache.Delete(ctx, pvzCachePrefix+pvzID); err != nil {
+			logger.FromContext(ctx).Errorf("не удалось сбросить кэш ПВЗ %s: %v", pvzID, err)
+		}
+	}
+	if err := c.cache.DeletePrefix(ctx, pvzListCachePrefix); err != nil {
+		logger.FromContext(ctx).Errorf("не удалось сбросить кэш списков ПВЗ: %v", err)
+	}
+}
+
+// Reset сбрасывает на этой реплике все записи о ПВЗ
+func (c *PVZCache) Reset(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+
+	if err := c.cache.DeletePrefix(ctx, pvzCachePrefix); err != nil {
+		logger.FromContext(ctx).Errorf("не удалось сбросить кэш ПВЗ: %v", err)
+	}
+}
+
+// invalidate вызывается после успешного изменения данных ПВЗ pvzID
+func (c *PVZCache) invalidate(ctx context.Context, pvzID string) {
+	c.Evict(ctx, pvzID)
+
+	if err := c.notifier.Notify(ctx, pvzID); err != nil {
+		logger.FromContext(ctx).Errorf("не удалось разослать инвалидацию кэша ПВЗ %s: %v", pvzID, err)
+	}
+}
+
+// cachedRead возвращает значение из кэша или загружает его через load.
+// Ошибки кэша не прерывают запрос, ошибки load не кэшируются. load читает
+// из primary: отстающая реплика вернула бы данные до сброса, и они остались
+// бы в кэше до истечения TTL.
+func cachedRead[T any](ctx context.Context, c *PVZCache, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	span := trace.SpanFromContext(ctx)
+
+	data, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("ошибка чтения кэша %s: %v", key, err)
+	}
+	if ok {
+		var value T
+		if err := json.Unmarshal(data, &value); err == nil {
+			span.SetAttributes(attribute.Bool("cache.hit", true))
+			return value, nil
+		}
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
+	c.mu.Lock()
+	gen := c.gen
+	c.mu.Unlock()
+
+	value, err := load(repositories.WithPrimary(ctx))
+	if err != nil {
+		return value, err
+	}
+
+	data, err = json.Marshal(value)
+	if err != nil {
+		return value, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		if err := c.cache.Set(ctx, key, data, c.ttl); err != nil {
+			logger.FromContext(ctx).Errorf("ошибка записи в кэш %s: %v", key, err)
+		}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/tracing"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxImportRows ограничивает размер одного файла импорта
+const maxImportRows = 5000
+
+var importColumns = map[string]bool{
+	"city":              true,
+	"registration_date": true,
+	"address":           true,
+	"latitude":          true,
+	"longitude":         true,
+	"time_zone":         true,
+}
+
+// ImportPVZ разбирает CSV с заголовком и создает ПВЗ одной транзакцией.
+// При dryRun или наличии ошибок хотя бы в одной строке в базу ничего не пишется.
+func (s *PVZService) ImportPVZ(ctx context.Context, r io.Reader, dryRun bool) (models.PVZImportResult, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.ImportPVZ")
+	defer span.End()
+
+	result := models.PVZImportResult{
+		DryRun: dryRun,
+		Errors: make([]models.PVZImportRowError, 0),
+		PVZs:   make([]models.PVZ, 0),
+	}
+
+	reader, err := newImportReader(r)
+	if err != nil {
+		return result, errors.ErrInvalidInput
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return result, errors.ErrInvalidInput
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !importColumns[name] {
+			result.Errors = append(result.Errors, models.PVZImportRowError{Row: 1, Column: name, Message: "неизвестная колонка"})
+			continue
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["city"]; !ok {
+		result.Errors = append(result.Errors, models.PVZImportRowError{Row: 1, Column: "city", Message: "отсутствует обязательная колонка"})
+	}
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	// rows - номера строк файла для result.PVZs, по ним ошибки записи
+	// привязываются к строкам
+	var rows []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		// строки с ошибками разбора тоже считаются, иначе лимит обходится битым файлом
+		result.Total++
+		if result.Total > maxImportRows {
+			return result, fmt.Errorf("%w: больше %d строк", errors.ErrInvalidInput, maxImportRows)
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !stdErrors.As(err, &parseErr) {
+				return result, err
+			}
+			result.Errors = append(result.Errors, models.PVZImportRowError{Row: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		row, _ := reader.FieldPos(0)
+
+		pvz, rowErrors := parseImportRow(row, record, columns)
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+
+		result.Valid++
+		result.PVZs = append(result.PVZs, pvz)
+		rows = append(rows, row)
+	}
+
+	if result.Total == 0 {
+		return result, errors.ErrInvalidInput
+	}
+
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	err = s.withInvalidation(ctx, "", func(ctx context.Context) error {
+		created, err := s.repos.PvzRepo.CreatePVZBatch(ctx, result.PVZs)
+		if err != nil {
+			return err
+		}
+
+		result.PVZs = created
+		result.Created = len(created)
+		for i := range result.PVZs {
+			// расписание у новых ПВЗ еще не настроено
+			result.PVZs[i].IsOpenNow = true
+			localizePVZ(&result.PVZs[i])
+		}
+
+		for _, pvz := range created {
+			if err := recordAudit(ctx, s.repos, models.AuditPVZImport, "pvz", pvz.ID, nil, pvz); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// строку, отвергнутую ограничениями базы, показываем как ошибку строки:
+	// транзакция откатилась, и ничего не создано
+	var batchErr *errors.BatchError
+	if stdErrors.As(err, &batchErr) && batchErr.Index < len(rows) {
+		rowErr := models.PVZImportRowError{Row: rows[batchErr.Index], Message: batchErr.Err.Error()}
+		if stdErrors.Is(batchErr.Err, errors.ErrCityNotAllowed) {
+			rowErr.Column = "city"
+		}
+		result.Errors = append(result.Errors, rowErr)
+		result.Valid--
+		return result, nil
+	}
+
+	return result, err
+}
+
+// newImportReader определяет разделитель по первой строке: Excel в русской
+// локали сохраняет CSV через точку с запятой.
+func newImportReader(r io.Reader) (*csv.Reader, error) {
+	buffered := bufio.NewReader(r)
+	firstLine, err := buffered.Peek(buffered.Size())
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	reader := csv.NewReader(buffered)
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	return reader, nil
+}
+
+func parseImportRow(row int, record []string, columns map[string]int) (models.PVZ, []models.PVZImportRowError) {
+	var rowErrors []models.PVZImportRowError
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	pvz := models.PVZ{City: strings.ToLower(field("city"))}
+	if pvz.City == "" {
+		rowErrors = append(rowErrors, models.PVZImportRowError{Row: row, Column: "city", Message: "город не указан"})
+	} else if !models.PVZCities[pvz.City] {
+		rowErrors = append(rowErrors, models.PVZImportRowError{Row: row, Column: "city", Message: errors.ErrCityNotAllowed.Error()})
+	}
+
+	pvz.TimeZone = field("time_zone")
+	if pvz.TimeZone == "" {
+		pvz.TimeZone = cityTimeZones[pvz.City]
+	}
+	if pvz.TimeZone != "" && validateTimeZone(pvz.TimeZone) != nil {
+		rowErrors = append(rowErrors, models.PVZImportRowError{Row: row, Column: "time_zone", Message: "неизвестный часовой пояс"})
+	}
+
+	if date := field("registration_date"); date != "" {
+		parsed, err := parseImportDate(date, models.Location(pvz.TimeZone))
+		if err != nil {
+			rowErrors = append(rowErrors, models.PVZImportRowError{Row: row, Column: "registration_date", Message: "ожидается дата в формате 2006-01-02 или RFC 3339"})
+		}
+		pvz.RegistrationDate = parsed
+	}
+
+	pvz.Address = field("address")
+	pvz.Latitude = parseImportCoordinate(row, "latitude", field("latitude"), &rowErrors)
+	pvz.Longitude = parseImportCoordinate(row, "longitude", field("longitude"), &rowErrors)
+	if len(rowErrors) == 0 {
+		if msg := locationError(pvz.Latitude, pvz.Longitude); msg != "" {
+			rowErrors = append(rowErrors, models.PVZImportRowError{Row: row, Message: msg})
+		}
+	}
+
+	return pvz, rowErrors
+}
+
+// parseImportCoordinate принимает и запятую как десятичный разделитель,
+// как ее пишет Excel в русской локали
+func parseImportCoordinate(row int, column, value string, rowErrors *[]models.PVZImportRowError) *float64 {
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		*rowErrors = append(*rowErrors, models.PVZImportRowError{Row: row, Column: column, Message: "ожидается число"})
+		return nil
+	}
+
+	return &parsed
+}
+
+// parseImportDate читает дату без времени как полночь в поясе ПВЗ loc
+func parseImportDate(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	t, err := time.ParseInLocation(models.DateLayout, value, loc)
+	return t.UTC(), err
+}
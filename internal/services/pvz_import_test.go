@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"pvz-service/config"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/mailer"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/repositories/memory"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rejectingPVZRepo отвергает пакет так, как это делает CHECK в базе
+type rejectingPVZRepo struct {
+	repositories.PVZRepositoryInterface
+	err error
+}
+
+func (r rejectingPVZRepo) CreatePVZBatch(ctx context.Context, pvzs []models.PVZ) ([]models.PVZ, error) {
+	return nil, r.err
+}
+
+func newImportServices(t *testing.T) (*Services, *repositories.Repos) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Storage = "memory"
+	repos := memory.NewRepos(&cfg)
+	return NewServices(repos, mailer.LogMailer{}), repos
+}
+
+func TestPVZService_ImportPVZ(t *testing.T) {
+	services, repos := newImportServices(t)
+	csv := "city;address\nСанкт-Петербург;Невский, 1\nказань;\n"
+
+	result, err := services.PvzService.ImportPVZ(context.Background(), strings.NewReader(csv), true)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, 2, result.Valid)
+
+	result, err = services.PvzService.ImportPVZ(context.Background(), strings.NewReader(csv), false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	require.Equal(t, 2, result.Created)
+	assert.Equal(t, "санкт-петербург", result.PVZs[0].City)
+
+	list, err := repos.PvzRepo.List(context.Background(), 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func TestPVZService_ImportPVZRejectedRow(t *testing.T) {
+	services, repos := newImportServices(t)
+	repos.PvzRepo = rejectingPVZRepo{
+		PVZRepositoryInterface: repos.PvzRepo,
+		err:                    &errors.BatchError{Index: 1, Err: errors.ErrCityNotAllowed},
+	}
+
+	// пустая строка пропускается: второй ПВЗ пакета - строка 4 файла
+	csv := "city\nмосква\n\nказань\n"
+	result, err := services.PvzService.ImportPVZ(context.Background(), strings.NewReader(csv), false)
+	require.NoError(t, err, "отказ базы по строке - ошибка строки, а не 500")
+	assert.Equal(t, []models.PVZImportRowError{{Row: 4, Column: "city", Message: errors.ErrCityNotAllowed.Error()}}, result.Errors)
+	assert.Zero(t, result.Created)
+	assert.Equal(t, 1, result.Valid)
+
+	repos.PvzRepo = rejectingPVZRepo{PVZRepositoryInterface: repos.PvzRepo, err: assert.AnError}
+	_, err = services.PvzService.ImportPVZ(context.Background(), strings.NewReader(csv), false)
+	assert.ErrorIs(t, err, assert.AnError, "прочие ошибки записи возвращаются как есть")
+}
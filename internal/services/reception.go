@@ -2,35 +2,315 @@ package services
 
 import (
 	"context"
+	stdErrors "errors"
+	"fmt"
 	"pvz-service/internal/models"
 	"pvz-service/internal/pkg/errors"
 	"pvz-service/internal/repositories"
+	"pvz-service/internal/tracing"
 	"time"
 )
 
 type ReceptionService struct {
 	repos *repositories.Repos
+	cache *PVZCache
 }
 
-func NewReceptionService(repos *repositories.Repos) *ReceptionService {
-	return &ReceptionService{repos: repos}
+func NewReceptionService(repos *repositories.Repos, cache *PVZCache) *ReceptionService {
+	return &ReceptionService{repos: repos, cache: cache}
 }
 
-func (s *ReceptionService) CreateReception(ctx context.Context, reception models.Reception) error {
-	active, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, reception.PvzId)
+// CreateReception блокирует строку ПВЗ, поэтому две одновременные попытки
+// открыть приемку в одном ПВЗ выполняются по очереди. В нерабочее время ПВЗ
+// приемка открывается только с override, решение о праве на него принимает вызывающий.
+// Созданная приемка возвращается во времени ПВЗ.
+func (s *ReceptionService) CreateReception(ctx context.Context, reception models.Reception, override bool) (models.Reception, error) {
+	ctx, span := tracing.Start(ctx, "ReceptionService.CreateReception")
+	defer span.End()
+
+	var created models.Reception
+	var loc *time.Location
+	err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		pvz, err := s.repos.PvzRepo.GetPVZByIDForUpdate(ctx, reception.PvzId)
+		if err != nil {
+			return err
+		}
+
+		schedules, err := s.repos.ScheduleRepo.GetSchedules(ctx, []string{pvz.ID})
+		if err != nil {
+			return err
+		}
+		schedule := schedules[pvz.ID]
+		schedule.TimeZone = pvz.TimeZone
+		loc = models.Location(pvz.TimeZone)
+
+		action := models.AuditReceptionCreate
+		if !schedule.IsOpenAt(time.Now()) {
+			if !override {
+				return errors.ErrPVZClosed
+			}
+			action = models.AuditReceptionOverride
+		}
+
+		active, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, reception.PvzId)
+		if err != nil {
+			return err
+		}
+		if active != nil {
+			return errors.ErrInvalidInput
+		}
+
+		reception.DateTime = time.Now().UTC()
+		reception.Status = "in_progress"
+
+		created, err = s.repos.ReceptionRepo.CreateReception(ctx, reception)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, action, "reception", created.ID, nil, created)
+	})
 	if err != nil {
-		return err
-	}
-	if active != nil {
-		return errors.ErrInvalidInput
+		return models.Reception{}, err
 	}
+	s.cache.invalidate(ctx, reception.PvzId)
 
-	reception.DateTime = time.Now()
-	reception.Status = "in_progress"
-
-	return s.repos.ReceptionRepo.CreateReception(ctx, reception)
+	created.DateTime = created.DateTime.In(loc)
+	return created, nil
 }
 
 func (s *ReceptionService) GetActiveReceptionByPVZID(ctx context.Context, pvzID string) (*models.Reception, error) {
+	ctx, span := tracing.Start(ctx, "ReceptionService.GetActiveReceptionByPVZID")
+	defer span.End()
+
 	return s.repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, pvzID)
 }
+
+// GetReception возвращает приемку с товарами во времени ПВЗ
+func (s *ReceptionService) GetReception(ctx context.Context, id string) (models.FullReception, error) {
+	ctx, span := tracing.Start(ctx, "ReceptionService.GetReception")
+	defer span.End()
+
+	reception, err := s.repos.ReceptionRepo.GetByID(ctx, id)
+	if err != nil {
+		return models.FullReception{}, err
+	}
+
+	return s.withProducts(ctx, reception)
+}
+
+// GetActiveReception возвращает открытую приемку ПВЗ с товарами. Если ПВЗ
+// нет или открытой приемки в нем нет, возвращается ErrNotFound.
+func (s *ReceptionService) GetActiveReception(ctx context.Context, pvzID string) (models.FullReception, error) {
+	ctx, span := tracing.Start(ctx, "ReceptionService.GetActiveReception")
+	defer span.End()
+
+	if _, err := s.repos.PvzRepo.GetPVZByID(ctx, pvzID); err != nil {
+		return models.FullReception{}, err
+	}
+
+	active, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZID(ctx, pvzID)
+	if err != nil {
+		return models.FullReception{}, err
+	}
+	if active == nil {
+		return models.FullReception{}, fmt.Errorf("%w: в ПВЗ нет открытой приемки", errors.ErrNotFound)
+	}
+
+	return s.withProducts(ctx, *active)
+}
+
+// ListReceptions возвращает страницу приемок ПВЗ, новые первыми. Время
+// переводится в пояс filter.Period.TimeZone или, если он не задан, в пояс ПВЗ.
+func (s *ReceptionService) ListReceptions(ctx context.Context, filter models.ReceptionFilter) ([]models.Reception, error) {
+	ctx, span := tracing.Start(ctx, "ReceptionService.ListReceptions")
+	defer span.End()
+
+	switch filter.Status {
+	case "", models.ReceptionInProgress, models.ReceptionClosed:
+	default:
+		return nil, fmt.Errorf("%w: status должен быть in_progress или close", errors.ErrInvalidInput)
+	}
+	if !filter.Period.From.IsZero() && !filter.Period.To.IsZero() && filter.Period.To.Before(filter.Period.From) {
+		return nil, fmt.Errorf("%w: to раньше from", errors.ErrInvalidInput)
+	}
+	zone := filter.Period.TimeZone
+	if zone != "" {
+		if err := validateTimeZone(zone); err != nil {
+			return nil, err
+		}
+	}
+
+	pvz, err := s.repos.PvzRepo.GetPVZByID(ctx, filter.PvzID)
+	if err != nil {
+		return nil, err
+	}
+	if zone == "" {
+		zone = pvz.TimeZone
+	}
+
+	receptions, err := s.repos.ReceptionRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := models.Location(zone)
+	for i := range receptions {
+		receptions[i].DateTime = receptions[i].DateTime.In(loc)
+	}
+
+	return receptions, nil
+}
+
+// ListProducts возвращает страницу товаров приемки, последние добавленные первыми
+func (s *ReceptionService) ListProducts(ctx context.Context, receptionID string, limit, offset int) ([]models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ReceptionService.ListProducts")
+	defer span.End()
+
+	reception, err := s.repos.ReceptionRepo.GetByID(ctx, receptionID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.pvzLocation(ctx, reception.PvzId)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.repos.ProductRepo.ListByReceptionID(ctx, receptionID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].DateTime = products[i].DateTime.In(loc)
+	}
+
+	return products, nil
+}
+
+func (s *ReceptionService) withProducts(ctx context.Context, reception models.Reception) (models.FullReception, error) {
+	loc, err := s.pvzLocation(ctx, reception.PvzId)
+	if err != nil {
+		return models.FullReception{}, err
+	}
+
+	products, err := s.repos.ProductRepo.GetByReceptionID(ctx, reception.ID)
+	if err != nil {
+		return models.FullReception{}, err
+	}
+
+	full := models.FullReception{
+		ID:       reception.ID,
+		PvzId:    reception.PvzId,
+		Status:   reception.Status,
+		DateTime: reception.DateTime.In(loc),
+		Products: make([]models.Product, 0, len(products)),
+	}
+	for _, product := range products {
+		product.DateTime = product.DateTime.In(loc)
+		full.Products = append(full.Products, product)
+	}
+
+	return full, nil
+}
+
+func (s *ReceptionService) pvzLocation(ctx context.Context, pvzID string) (*time.Location, error) {
+	pvz, err := s.repos.PvzRepo.GetPVZByID(ctx, pvzID)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.Location(pvz.TimeZone), nil
+}
+
+// ProcessStaleReceptions закрывает или помечает в журнале аудита открытые
+// приемки без активности дольше idleFor. Каждая приемка обрабатывается в
+// своей транзакции с той же блокировкой, что и CloseLastReception, и
+// перепроверяется: пока шел обход, в нее могли добавить товар.
+func (s *ReceptionService) ProcessStaleReceptions(ctx context.Context, idleFor time.Duration, action string) (int, error) {
+	ctx, span := tracing.Start(ctx, "ReceptionService.ProcessStaleReceptions")
+	defer span.End()
+
+	idleSince := time.Now().Add(-idleFor)
+
+	stale, err := s.repos.ReceptionRepo.ListStale(ctx, idleSince)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	var errs []error
+	for _, reception := range stale {
+		var done bool
+		err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			done, err = s.processStaleReception(ctx, reception, idleSince, action)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("приемка %s: %w", reception.ID, err))
+			continue
+		}
+		if done {
+			processed++
+		}
+		if done && action != models.StaleReceptionFlag {
+			s.cache.invalidate(ctx, reception.PvzId)
+		}
+	}
+
+	return processed, stdErrors.Join(errs...)
+}
+
+func (s *ReceptionService) processStaleReception(ctx context.Context, reception models.Reception, idleSince time.Time, action string) (bool, error) {
+	locked, err := s.repos.ReceptionRepo.GetActiveReceptionByPVZIDForUpdate(ctx, reception.PvzId)
+	if err != nil {
+		return false, err
+	}
+	if locked == nil || locked.ID != reception.ID {
+		return false, nil
+	}
+
+	products, err := s.repos.ProductRepo.GetByReceptionID(ctx, locked.ID)
+	if err != nil {
+		return false, err
+	}
+	if len(products) > 0 && !products[0].DateTime.Before(idleSince) {
+		return false, nil
+	}
+
+	if action == models.StaleReceptionFlag {
+		flagged, err := s.repos.AuditRepo.List(ctx, models.AuditFilter{
+			Action:   models.AuditReceptionStale,
+			TargetID: locked.ID,
+			Limit:    1,
+		})
+		if err != nil || len(flagged) > 0 {
+			return false, err
+		}
+
+		if err := recordAudit(ctx, s.repos, models.AuditReceptionStale, "reception", locked.ID, nil, locked); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if err := closeReception(ctx, s.repos, *locked, models.AuditReceptionAutoClose); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// closeReception закрывает заблокированную вызывающим приемку, переводит ее
+// товары в выдачу и пишет аудит с действием action. Вызывается в транзакции.
+func closeReception(ctx context.Context, repos *repositories.Repos, reception models.Reception, action string) error {
+	if err := repos.ReceptionRepo.CloseReception(ctx, reception.ID); err != nil {
+		return err
+	}
+	if err := repos.ProductRepo.MarkReadyForPickup(ctx, reception.ID); err != nil {
+		return err
+	}
+
+	closed := reception
+	closed.Status = models.ReceptionClosed
+	return recordAudit(ctx, repos, action, "reception", reception.ID, reception, closed)
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/repositories"
+	"pvz-service/internal/tracing"
+	"strings"
+	"time"
+)
+
+// maxExceptionDays ограничивает длину одного исключения из расписания
+const maxExceptionDays = 366
+
+type ScheduleService struct {
+	repos *repositories.Repos
+	cache *PVZCache
+}
+
+func NewScheduleService(repos *repositories.Repos, cache *PVZCache) *ScheduleService {
+	return &ScheduleService{repos: repos, cache: cache}
+}
+
+func (s *ScheduleService) GetSchedule(ctx context.Context, pvzID string) (models.PVZSchedule, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleService.GetSchedule")
+	defer span.End()
+
+	return s.getSchedule(ctx, pvzID)
+}
+
+func (s *ScheduleService) getSchedule(ctx context.Context, pvzID string) (models.PVZSchedule, error) {
+	pvz, err := s.repos.PvzRepo.GetPVZByID(ctx, pvzID)
+	if err != nil {
+		return models.PVZSchedule{}, err
+	}
+
+	schedules, err := s.repos.ScheduleRepo.GetSchedules(ctx, []string{pvzID})
+	if err != nil {
+		return models.PVZSchedule{}, err
+	}
+
+	schedule := schedules[pvzID]
+	schedule.TimeZone = pvz.TimeZone
+	schedule.IsOpenNow = schedule.IsOpenAt(time.Now())
+
+	return schedule, nil
+}
+
+// UpdateSchedule заменяет недельное расписание ПВЗ и, если указан, часовой пояс.
+// Пустое расписание снимает ограничения: ПВЗ считается открытым всегда.
+func (s *ScheduleService) UpdateSchedule(ctx context.Context, pvzID string, update models.PVZSchedule) (models.PVZSchedule, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleService.UpdateSchedule")
+	defer span.End()
+
+	if err := validateWeek(update.Week); err != nil {
+		return models.PVZSchedule{}, err
+	}
+	if update.TimeZone != "" {
+		if err := validateTimeZone(update.TimeZone); err != nil {
+			return models.PVZSchedule{}, err
+		}
+	}
+
+	err := s.withInvalidation(ctx, pvzID, func(ctx context.Context) error {
+		if _, err := s.repos.PvzRepo.GetPVZByIDForUpdate(ctx, pvzID); err != nil {
+			return err
+		}
+
+		before, err := s.getSchedule(ctx, pvzID)
+		if err != nil {
+			return err
+		}
+
+		if update.TimeZone != "" {
+			if err := s.repos.PvzRepo.UpdateTimeZone(ctx, pvzID, update.TimeZone); err != nil {
+				return err
+			}
+		}
+		if err := s.repos.ScheduleRepo.ReplaceWeek(ctx, pvzID, update.Week); err != nil {
+			return err
+		}
+
+		after, err := s.getSchedule(ctx, pvzID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditPVZScheduleUpdate, "pvz", pvzID, scheduleAudit(before), scheduleAudit(after))
+	})
+	if err != nil {
+		return models.PVZSchedule{}, err
+	}
+
+	return s.getSchedule(repositories.WithPrimary(ctx), pvzID)
+}
+
+// AddException добавляет праздник, временное закрытие или особые часы работы.
+// Исключения одного ПВЗ не пересекаются, прошедшие даты не принимаются.
+func (s *ScheduleService) AddException(ctx context.Context, e models.ScheduleException) (models.ScheduleException, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleService.AddException")
+	defer span.End()
+
+	e.Reason = strings.TrimSpace(e.Reason)
+	if e.EndDate == "" {
+		e.EndDate = e.StartDate
+	}
+	if err := validateException(e); err != nil {
+		return models.ScheduleException{}, err
+	}
+
+	var created models.ScheduleException
+	err := s.withInvalidation(ctx, e.PvzID, func(ctx context.Context) error {
+		if _, err := s.repos.PvzRepo.GetPVZByIDForUpdate(ctx, e.PvzID); err != nil {
+			return err
+		}
+
+		schedules, err := s.repos.ScheduleRepo.GetSchedules(ctx, []string{e.PvzID})
+		if err != nil {
+			return err
+		}
+		for _, existing := range schedules[e.PvzID].Exceptions {
+			if e.StartDate <= existing.EndDate && existing.StartDate <= e.EndDate {
+				return fmt.Errorf("%w: пересекается с исключением %s - %s", errors.ErrInvalidInput, existing.StartDate, existing.EndDate)
+			}
+		}
+
+		created, err = s.repos.ScheduleRepo.CreateException(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditPVZExceptionCreate, "pvz", e.PvzID, nil, created)
+	})
+	if err != nil {
+		return models.ScheduleException{}, err
+	}
+
+	return created, nil
+}
+
+func (s *ScheduleService) DeleteException(ctx context.Context, pvzID, id string) error {
+	ctx, span := tracing.Start(ctx, "ScheduleService.DeleteException")
+	defer span.End()
+
+	return s.withInvalidation(ctx, pvzID, func(ctx context.Context) error {
+		deleted, err := s.repos.ScheduleRepo.DeleteException(ctx, pvzID, id)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditPVZExceptionDelete, "pvz", pvzID, deleted, nil)
+	})
+}
+
+// withInvalidation выполняет fn в транзакции и после коммита сбрасывает кэш ПВЗ pvzID
+func (s *ScheduleService) withInvalidation(ctx context.Context, pvzID string, fn func(ctx context.Context) error) error {
+	if err := s.repos.TxManager.WithinTransaction(ctx, fn); err != nil {
+		return err
+	}
+	s.cache.invalidate(ctx, pvzID)
+
+	return nil
+}
+
+// scheduleAudit оставляет в снимке для журнала только то, что меняет UpdateSchedule
+func scheduleAudit(schedule models.PVZSchedule) models.PVZSchedule {
+	schedule.IsOpenNow = false
+	schedule.Exceptions = nil
+	return schedule
+}
+
+func validateWeek(week []models.WorkingHours) error {
+	seen := make(map[int]bool, len(week))
+	for _, hours := range week {
+		if hours.Weekday < 1 || hours.Weekday > 7 {
+			return fmt.Errorf("%w: день недели должен быть от 1 (понедельник) до 7 (воскресенье)", errors.ErrInvalidInput)
+		}
+		if seen[hours.Weekday] {
+			return fmt.Errorf("%w: день недели %d указан дважды", errors.ErrInvalidInput, hours.Weekday)
+		}
+		seen[hours.Weekday] = true
+
+		if err := validateHours(hours.Opens, hours.Closes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateException(e models.ScheduleException) error {
+	start, err := time.Parse(models.DateLayout, e.StartDate)
+	if err != nil {
+		return fmt.Errorf("%w: startDate ожидается в формате 2006-01-02", errors.ErrInvalidInput)
+	}
+	end, err := time.Parse(models.DateLayout, e.EndDate)
+	if err != nil {
+		return fmt.Errorf("%w: endDate ожидается в формате 2006-01-02", errors.ErrInvalidInput)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("%w: endDate раньше startDate", errors.ErrInvalidInput)
+	}
+	if end.Sub(start) >= maxExceptionDays*24*time.Hour {
+		return fmt.Errorf("%w: исключение длиннее %d дней", errors.ErrInvalidInput, maxExceptionDays)
+	}
+	// вчера по UTC: в части часовых поясов этот день еще идет
+	if e.EndDate < time.Now().UTC().AddDate(0, 0, -1).Format(models.DateLayout) {
+		return fmt.Errorf("%w: исключение в прошлом", errors.ErrInvalidInput)
+	}
+	if len(e.Reason) > 200 {
+		return fmt.Errorf("%w: причина длиннее 200 символов", errors.ErrInvalidInput)
+	}
+
+	if e.Opens == "" && e.Closes == "" {
+		return nil
+	}
+	return validateHours(e.Opens, e.Closes)
+}
+
+func validateHours(opens, closes string) error {
+	from, err := models.ParseClock(opens)
+	if err != nil || from >= 24*60 {
+		return fmt.Errorf("%w: время открытия ожидается в формате 15:04", errors.ErrInvalidInput)
+	}
+	to, err := models.ParseClock(closes)
+	if err != nil {
+		return fmt.Errorf("%w: время закрытия ожидается в формате 15:04", errors.ErrInvalidInput)
+	}
+	if to <= from {
+		return fmt.Errorf("%w: закрытие должно быть позже открытия", errors.ErrInvalidInput)
+	}
+
+	return nil
+}
@@ -1,11 +1,38 @@
 package services
 
-import "pvz-service/config"
+import (
+	"pvz-service/config"
+	"pvz-service/internal/pkg/mailer"
+	"pvz-service/internal/repositories"
+)
 
 type Services struct {
-	UserService      *UserService
-	ProductService   *ProductService
-	PvzService       PVZServiceInterface
-	ReceptionService *ReceptionService
-	Cfg              *config.Config
+	UserService       UserServiceInterface
+	ProductService    ProductServiceInterface
+	PvzService        PVZServiceInterface
+	ReceptionService  ReceptionServiceInterface
+	ScheduleService   ScheduleServiceInterface
+	AuditService      AuditServiceInterface
+	InvitationService InvitationServiceInterface
+	PVZCache          *PVZCache
+	Background        *Background
+	Cfg               *config.Config
+}
+
+func NewServices(repos *repositories.Repos, mail mailer.Mailer) *Services {
+	pvzCache := NewPVZCache(repos)
+	background := &Background{}
+
+	return &Services{
+		UserService:       NewUserService(repos, mail, background),
+		ProductService:    NewProductService(repos, pvzCache, mail, background),
+		PvzService:        NewPVZService(repos, pvzCache),
+		ReceptionService:  NewReceptionService(repos, pvzCache),
+		ScheduleService:   NewScheduleService(repos, pvzCache),
+		AuditService:      NewAuditService(repos),
+		InvitationService: NewInvitationService(repos, mail),
+		PVZCache:          pvzCache,
+		Background:        background,
+		Cfg:               repos.Cfg,
+	}
 }
@@ -4,24 +4,65 @@ import (
 	"context"
 	"pvz-service/internal/models"
 	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/mailer"
 	"pvz-service/internal/repositories"
+	"pvz-service/internal/tracing"
 )
 
 type UserService struct {
-	repos *repositories.Repos
+	repos  *repositories.Repos
+	mailer mailer.Mailer
+	tasks  *Background
 }
 
-func NewUserService(repos *repositories.Repos) *UserService {
-	return &UserService{repos: repos}
+func NewUserService(repos *repositories.Repos, mail mailer.Mailer, tasks *Background) *UserService {
+	return &UserService{repos: repos, mailer: mail, tasks: tasks}
 }
 
+// CreateUser регистрирует пользователя. Если EmailVerifiedAt не заполнен,
+// на адрес уходит письмо со ссылкой для подтверждения.
 func (s *UserService) CreateUser(ctx context.Context, user models.User) error {
-	if user.Role != "client" && user.Role != "moderator" {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	if !models.ValidRole(user.Role) {
 		return errors.ErrInvalidInput
 	}
-	return s.repos.AuthRepo.CreateUser(ctx, user)
+
+	var (
+		created models.User
+		plain   string
+	)
+	err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.repos.AuthRepo.CreateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		if created.EmailVerifiedAt == nil {
+			plain, err = s.issueToken(ctx, created.ID, models.TokenEmailVerification, s.repos.Cfg.Auth.EmailVerificationTTL)
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditUserRegister, "user", created.ID, nil, created)
+	})
+	if err != nil {
+		return err
+	}
+
+	if plain != "" {
+		s.send(ctx, verificationMessage(s.repos.Cfg.HTTP.PublicURL, created.Email, plain))
+	}
+
+	return nil
 }
 
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByEmail")
+	defer span.End()
+
 	return s.repos.AuthRepo.GetUserByEmail(ctx, email)
 }
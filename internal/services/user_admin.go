@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/actor"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/tracing"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (s *UserService) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListUsers")
+	defer span.End()
+
+	if filter.Role != "" && !models.ValidRole(filter.Role) {
+		return nil, errors.ErrInvalidInput
+	}
+	if filter.Status != "" && filter.Status != models.UserStatusActive && filter.Status != models.UserStatusDeactivated {
+		return nil, errors.ErrInvalidInput
+	}
+
+	return s.repos.AuthRepo.ListUsers(ctx, filter)
+}
+
+func (s *UserService) GetUser(ctx context.Context, id string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUser")
+	defer span.End()
+
+	return s.repos.AuthRepo.GetUserByID(ctx, id)
+}
+
+// ChangeRole меняет роль пользователя. Свою роль модератор менять не может,
+// чтобы случайно не остаться без доступа.
+func (s *UserService) ChangeRole(ctx context.Context, id, role string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeRole")
+	defer span.End()
+
+	if !models.ValidRole(role) {
+		return models.User{}, errors.ErrInvalidInput
+	}
+
+	return s.modifyUser(ctx, id, models.AuditUserRoleChange, func(ctx context.Context, user *models.User) error {
+		if user.Role == role {
+			return nil
+		}
+		if err := s.repos.AuthRepo.UpdateRole(ctx, user.ID, role); err != nil {
+			return err
+		}
+		user.Role = role
+		return nil
+	})
+}
+
+// DeactivateUser отключает учетную запись: вход и уже выданные токены
+// перестают работать. Повторное отключение ничего не меняет.
+func (s *UserService) DeactivateUser(ctx context.Context, id string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeactivateUser")
+	defer span.End()
+
+	return s.modifyUser(ctx, id, models.AuditUserDeactivate, func(ctx context.Context, user *models.User) error {
+		if user.DeactivatedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		if err := s.repos.AuthRepo.SetDeactivated(ctx, user.ID, &now); err != nil {
+			return err
+		}
+		user.DeactivatedAt = &now
+		return nil
+	})
+}
+
+func (s *UserService) ReactivateUser(ctx context.Context, id string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ReactivateUser")
+	defer span.End()
+
+	return s.modifyUser(ctx, id, models.AuditUserReactivate, func(ctx context.Context, user *models.User) error {
+		if user.DeactivatedAt == nil {
+			return nil
+		}
+		if err := s.repos.AuthRepo.SetDeactivated(ctx, user.ID, nil); err != nil {
+			return err
+		}
+		user.DeactivatedAt = nil
+		return nil
+	})
+}
+
+// modifyUser выполняет действие модератора над чужой учетной записью и пишет
+// аудит, если пользователь изменился
+func (s *UserService) modifyUser(ctx context.Context, id, action string, fn func(ctx context.Context, user *models.User) error) (models.User, error) {
+	if actor.FromContext(ctx).UserID == id {
+		return models.User{}, errors.ErrSelfAction
+	}
+
+	var updated models.User
+	err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.repos.AuthRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		updated = before
+		if err := fn(ctx, &updated); err != nil {
+			return err
+		}
+
+		if updated.Role != before.Role || (updated.DeactivatedAt == nil) != (before.DeactivatedAt == nil) {
+			return recordAudit(ctx, s.repos, action, "user", id, before, updated)
+		}
+		return nil
+	})
+
+	return updated, err
+}
+
+// UpdateProfile меняет email и/или пароль текущего пользователя. Любое
+// изменение требует текущего пароля. Новый email нужно подтвердить заново.
+func (s *UserService) UpdateProfile(ctx context.Context, id string, update models.ProfileUpdate) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateProfile")
+	defer span.End()
+
+	update.Email = strings.TrimSpace(update.Email)
+
+	var (
+		updated models.User
+		plain   string
+	)
+	err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.repos.AuthRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(before.Password), []byte(update.CurrentPassword)); err != nil {
+			return errors.ErrInvalidCredentials
+		}
+
+		updated = before
+		if update.Email != "" && update.Email != before.Email {
+			if err := s.repos.AuthRepo.UpdateEmail(ctx, id, update.Email); err != nil {
+				return err
+			}
+			updated.Email = update.Email
+			updated.EmailVerifiedAt = nil
+
+			plain, err = s.issueToken(ctx, id, models.TokenEmailVerification, s.repos.Cfg.Auth.EmailVerificationTTL)
+			if err != nil {
+				return err
+			}
+		}
+		if update.NewPasswordHash != "" {
+			now := time.Now()
+			if err := s.repos.AuthRepo.UpdatePassword(ctx, id, update.NewPasswordHash, now); err != nil {
+				return err
+			}
+			updated.Password = update.NewPasswordHash
+			updated.PasswordChangedAt = &now
+		}
+
+		return recordAudit(ctx, s.repos, models.AuditUserProfileUpdate, "user", id, before, updated)
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if plain != "" {
+		s.send(ctx, verificationMessage(s.repos.Cfg.HTTP.PublicURL, updated.Email, plain))
+	}
+
+	return updated, nil
+}
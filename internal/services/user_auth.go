@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	stdErrors "errors"
+	"pvz-service/internal/logger"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/tracing"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash сравнивается с паролем, когда пользователя нет, чтобы по времени
+// ответа нельзя было понять, зарегистрирован ли email
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// Authenticate проверяет пароль с учетом блокировки после серии неудачных
+// попыток. Для заблокированной учетной записи возвращает пользователя с
+// заполненным LockedUntil и ErrAccountLocked, результат проверки пароля при этом не учитывается.
+// При верном пароле, но отключенной учетной записи или неподтвержденном email
+// возвращает ErrAccountDeactivated или ErrEmailNotVerified.
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Authenticate")
+	defer span.End()
+
+	user, err := s.repos.AuthRepo.GetUserByEmail(ctx, email)
+	if stdErrors.Is(err, errors.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return models.User{}, errors.ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	now := time.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		// время ответа не должно выдавать блокировку
+		bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		return user, errors.ErrAccountLocked
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		if err := s.registerFailedLogin(ctx, user, now); err != nil {
+			logger.FromContext(ctx).Errorf("не удалось учесть неудачный вход %s: %v", user.ID, err)
+		}
+		return models.User{}, errors.ErrInvalidCredentials
+	}
+
+	if user.DeactivatedAt != nil {
+		return models.User{}, errors.ErrAccountDeactivated
+	}
+	if s.repos.Cfg.Auth.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		return models.User{}, errors.ErrEmailNotVerified
+	}
+
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := s.repos.AuthRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+			logger.FromContext(ctx).Errorf("не удалось сбросить счетчик входов %s: %v", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+func (s *UserService) registerFailedLogin(ctx context.Context, user models.User, now time.Time) error {
+	return s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		failures, err := s.repos.AuthRepo.IncrementFailedLogins(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		cfg := s.repos.Cfg
+		if failures < cfg.Auth.LoginMaxAttempts {
+			return nil
+		}
+
+		until := now.Add(lockoutDuration(failures-cfg.Auth.LoginMaxAttempts, cfg.Auth.LockoutBase, cfg.Auth.LockoutMax))
+		if err := s.repos.AuthRepo.LockUser(ctx, user.ID, until); err != nil {
+			return err
+		}
+
+		locked := user
+		locked.FailedLogins = failures
+		locked.LockedUntil = &until
+		return recordAudit(ctx, s.repos, models.AuditUserLock, "user", user.ID, nil, locked)
+	})
+}
+
+// lockoutDuration удваивает базовую блокировку за каждую ошибку сверх лимита
+func lockoutDuration(extra int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 0; i < extra && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	return d
+}
+
+// UnlockUser снимает блокировку и обнуляет счетчик неудачных входов
+func (s *UserService) UnlockUser(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "UserService.UnlockUser")
+	defer span.End()
+
+	return s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := s.repos.AuthRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repos.AuthRepo.ResetFailedLogins(ctx, id); err != nil {
+			return err
+		}
+
+		unlocked := user
+		unlocked.FailedLogins = 0
+		unlocked.LockedUntil = nil
+		return recordAudit(ctx, s.repos, models.AuditUserUnlock, "user", id, user, unlocked)
+	})
+}
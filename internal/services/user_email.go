@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	stdErrors "errors"
+	"pvz-service/internal/models"
+	"pvz-service/internal/pkg/errors"
+	"pvz-service/internal/pkg/mailer"
+	"pvz-service/internal/pkg/token"
+	"pvz-service/internal/tracing"
+	"strings"
+	"time"
+)
+
+// issueToken отзывает прежние ссылки пользователя с тем же назначением и
+// выпускает новую. Возвращает токен в открытом виде для письма.
+func (s *UserService) issueToken(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	plain, hash, err := token.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.repos.UserTokenRepo.InvalidateUser(ctx, userID, purpose, now); err != nil {
+		return "", err
+	}
+
+	_, err = s.repos.UserTokenRepo.Create(ctx, models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// useToken находит действующий токен и помечает его использованным
+func (s *UserService) useToken(ctx context.Context, purpose, plainToken string, now time.Time) (models.UserToken, error) {
+	t, err := s.repos.UserTokenRepo.GetByHashForUpdate(ctx, purpose, token.Hash(plainToken))
+	if stdErrors.Is(err, errors.ErrNotFound) {
+		return models.UserToken{}, errors.ErrInvalidToken
+	}
+	if err != nil {
+		return models.UserToken{}, err
+	}
+	if t.UsedAt != nil || !now.Before(t.ExpiresAt) {
+		return models.UserToken{}, errors.ErrInvalidToken
+	}
+
+	if err := s.repos.UserTokenRepo.MarkUsed(ctx, t.ID, now); err != nil {
+		return models.UserToken{}, err
+	}
+
+	return t, nil
+}
+
+// background выполняет fn после ответа на запрос, не дольше MAILER_SEND_TIMEOUT:
+// зависший SMTP не держит запрос, а время ответа не выдает, зарегистрирован
+// ли адрес. Ошибка только логируется, пользователь может запросить письмо повторно.
+func (s *UserService) background(ctx context.Context, fn func(ctx context.Context) error) {
+	s.tasks.Go(ctx, s.repos.Cfg.Mail.SendTimeout, fn)
+}
+
+// send отправляет письмо в фоне, токен из него уже сохранен
+func (s *UserService) send(ctx context.Context, msg mailer.Message) {
+	s.background(ctx, func(ctx context.Context) error {
+		return s.mailer.Send(ctx, msg)
+	})
+}
+
+// RequestPasswordReset отправляет ссылку для сброса пароля. Для неизвестного
+// или отключенного email ничего не делает и ошибку не возвращает, чтобы по
+// ответу нельзя было проверить, зарегистрирован ли адрес.
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.RequestPasswordReset")
+	defer span.End()
+
+	user, err := s.repos.AuthRepo.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if stdErrors.Is(err, errors.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.DeactivatedAt != nil {
+		return nil
+	}
+
+	// токен выпускается тоже в фоне, чтобы известный адрес не отвечал дольше неизвестного
+	s.background(ctx, func(ctx context.Context) error {
+		var plain string
+		err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			plain, err = s.issueToken(ctx, user.ID, models.TokenPasswordReset, s.repos.Cfg.Auth.PasswordResetTTL)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.mailer.Send(ctx, passwordResetMessage(s.repos.Cfg.HTTP.PublicURL, user.Email, plain))
+	})
+	return nil
+}
+
+// ResetPassword меняет пароль по одноразовой ссылке. Заодно снимает
+// блокировку входа и подтверждает email: ссылка пришла на этот адрес.
+func (s *UserService) ResetPassword(ctx context.Context, plainToken, passwordHash string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPassword")
+	defer span.End()
+
+	return s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		t, err := s.useToken(ctx, models.TokenPasswordReset, plainToken, now)
+		if err != nil {
+			return err
+		}
+
+		user, err := s.repos.AuthRepo.GetUserByID(ctx, t.UserID)
+		if err != nil {
+			return err
+		}
+		if user.DeactivatedAt != nil {
+			return errors.ErrInvalidToken
+		}
+
+		if err := s.repos.AuthRepo.UpdatePassword(ctx, user.ID, passwordHash, now); err != nil {
+			return err
+		}
+		if err := s.repos.AuthRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt == nil {
+			if err := s.repos.AuthRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+				return err
+			}
+		}
+
+		updated := user
+		updated.PasswordChangedAt = &now
+		updated.FailedLogins = 0
+		updated.LockedUntil = nil
+		if updated.EmailVerifiedAt == nil {
+			updated.EmailVerifiedAt = &now
+		}
+		return recordAudit(ctx, s.repos, models.AuditUserPasswordReset, "user", user.ID, user, updated)
+	})
+}
+
+// VerifyEmail подтверждает email по ссылке из письма
+func (s *UserService) VerifyEmail(ctx context.Context, plainToken string) error {
+	ctx, span := tracing.Start(ctx, "UserService.VerifyEmail")
+	defer span.End()
+
+	return s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		t, err := s.useToken(ctx, models.TokenEmailVerification, plainToken, now)
+		if err != nil {
+			return err
+		}
+
+		user, err := s.repos.AuthRepo.GetUserByID(ctx, t.UserID)
+		if err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		if err := s.repos.AuthRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+			return err
+		}
+
+		verified := user
+		verified.EmailVerifiedAt = &now
+		return recordAudit(ctx, s.repos, models.AuditUserEmailVerified, "user", user.ID, user, verified)
+	})
+}
+
+// ResendVerification повторно отправляет письмо для подтверждения. Как и
+// RequestPasswordReset, молча игнорирует неизвестные, отключенные и уже
+// подтвержденные адреса.
+func (s *UserService) ResendVerification(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResendVerification")
+	defer span.End()
+
+	user, err := s.repos.AuthRepo.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if stdErrors.Is(err, errors.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil || user.DeactivatedAt != nil {
+		return nil
+	}
+
+	s.background(ctx, func(ctx context.Context) error {
+		var plain string
+		err := s.repos.TxManager.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			plain, err = s.issueToken(ctx, user.ID, models.TokenEmailVerification, s.repos.Cfg.Auth.EmailVerificationTTL)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.mailer.Send(ctx, verificationMessage(s.repos.Cfg.HTTP.PublicURL, user.Email, plain))
+	})
+	return nil
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"pvz-service/internal/logger"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type queryKey struct{}
+
+type queryStart struct {
+	span  trace.Span
+	sql   string
+	start time.Time
+}
+
+// QueryTracer открывает спан на каждый запрос pgx и пишет в лог запросы
+// дольше SlowThreshold. Параметры запроса не записываются ни туда, ни туда.
+type QueryTracer struct {
+	SlowThreshold time.Duration
+}
+
+func NewQueryTracer(slowThreshold time.Duration) *QueryTracer {
+	return &QueryTracer{SlowThreshold: slowThreshold}
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, span := Start(ctx, "db "+queryOperation(data.SQL),
+		semconv.DBSystemPostgreSQL,
+		semconv.DBQueryText(data.SQL),
+	)
+	span.SetAttributes(attribute.Int("db.args", len(data.Args)))
+
+	return context.WithValue(ctx, queryKey{}, queryStart{span: span, sql: data.SQL, start: time.Now()})
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	elapsed := time.Since(q.start)
+	q.span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	End(q.span, data.Err)
+
+	if t.SlowThreshold > 0 && elapsed >= t.SlowThreshold {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"duration_ms": float64(elapsed.Microseconds()) / 1000.0,
+			"sql":         q.sql,
+		}).Warn("медленный запрос к базе")
+	}
+}
+
+// queryOperation возвращает первое слово запроса для имени спана: SELECT, INSERT...
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Старый код допускал несколько открытых приемок на ПВЗ: оставляем открытой
+-- самую новую, остальные закрываем, иначе уникальный индекс не создастся
+UPDATE reception SET status = 'close'
+WHERE status = 'in_progress'
+  AND id NOT IN (
+    SELECT DISTINCT ON (pvz_id) id
+    FROM reception
+    WHERE status = 'in_progress'
+    ORDER BY pvz_id, date_time DESC, id DESC
+  );
+
+-- Не больше одной открытой приемки на ПВЗ даже при гонке транзакций
+CREATE UNIQUE INDEX reception_single_active ON reception (pvz_id) WHERE status = 'in_progress';
+
+-- +goose Down
+DROP INDEX IF EXISTS reception_single_active;
@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('client', 'moderator', 'customer'));
+ALTER TABLE users ADD COLUMN pvz_id UUID REFERENCES pvz(id) ON DELETE SET NULL;
+
+CREATE TABLE invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token_hash TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('client', 'moderator')),
+    pvz_id UUID REFERENCES pvz(id) ON DELETE CASCADE,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    used_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX invitations_created_at_idx ON invitations (created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS invitations;
+
+ALTER TABLE users DROP COLUMN IF EXISTS pvz_id;
+-- откат невозможен, пока есть пользователи с ролью customer
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('client', 'moderator'));
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE pvz
+    ADD COLUMN address TEXT NOT NULL DEFAULT '',
+    ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    ADD CONSTRAINT pvz_coordinates_pair CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- поиск ближайших ПВЗ отсекает строки по прямоугольнику широты и долготы
+CREATE INDEX pvz_location_idx ON pvz (latitude, longitude) WHERE latitude IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS pvz_location_idx;
+ALTER TABLE pvz
+    DROP CONSTRAINT IF EXISTS pvz_coordinates_pair,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS address;
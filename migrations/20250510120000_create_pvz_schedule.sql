@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE pvz ADD COLUMN time_zone TEXT NOT NULL DEFAULT 'Europe/Moscow';
+
+CREATE TABLE pvz_working_hours (
+    pvz_id UUID NOT NULL REFERENCES pvz(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 1 AND 7),
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL CHECK (closes_at > opens_at),
+    PRIMARY KEY (pvz_id, weekday)
+);
+
+CREATE TABLE pvz_schedule_exceptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pvz_id UUID NOT NULL REFERENCES pvz(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL CHECK (end_date >= start_date),
+    -- без часов работы ПВЗ закрыт весь период
+    opens_at TIME,
+    closes_at TIME CHECK (closes_at > opens_at),
+    reason TEXT NOT NULL DEFAULT '',
+    CHECK ((opens_at IS NULL) = (closes_at IS NULL))
+);
+
+CREATE INDEX pvz_schedule_exceptions_pvz_idx ON pvz_schedule_exceptions (pvz_id, end_date);
+
+-- +goose Down
+DROP TABLE IF EXISTS pvz_schedule_exceptions;
+DROP TABLE IF EXISTS pvz_working_hours;
+ALTER TABLE pvz DROP COLUMN IF EXISTS time_zone;
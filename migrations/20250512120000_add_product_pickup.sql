@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE products
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'accepted'
+        CHECK (status IN ('accepted', 'ready_for_pickup', 'issued', 'returned')),
+    -- HMAC кода получения с ключом PICKUP_CODE_SECRET, сам код уходит письмом
+    -- на recipient_email, сотрудник ПВЗ его не видит
+    ADD COLUMN pickup_code_hash TEXT,
+    ADD COLUMN recipient_email TEXT,
+    ADD COLUMN pickup_attempts INT NOT NULL DEFAULT 0 CHECK (pickup_attempts >= 0),
+    ADD COLUMN issued_at TIMESTAMPTZ,
+    ADD CONSTRAINT products_issued_at CHECK (status <> 'issued' OR issued_at IS NOT NULL);
+
+-- товары закрытых приемок уже лежат на складе; кода у них нет, его
+-- перевыпускает модератор
+UPDATE products SET status = 'ready_for_pickup'
+FROM reception
+WHERE reception.id = products.reception_id AND reception.status = 'close';
+
+-- +goose Down
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS products_issued_at,
+    DROP COLUMN IF EXISTS issued_at,
+    DROP COLUMN IF EXISTS pickup_attempts,
+    DROP COLUMN IF EXISTS recipient_email,
+    DROP COLUMN IF EXISTS pickup_code_hash,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Токены, выпущенные до смены пароля, перестают приниматься
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- CHECK из create_pvz требовал 'санкт-Петербург', а сервис приводит город к
+-- нижнему регистру: ПВЗ в Санкт-Петербурге в базу не попадали
+ALTER TABLE pvz DROP CONSTRAINT IF EXISTS pvz_city_check;
+UPDATE pvz SET city = 'санкт-петербург' WHERE city = 'санкт-Петербург';
+ALTER TABLE pvz ADD CONSTRAINT pvz_city_check CHECK (city IN ('москва', 'санкт-петербург', 'казань'));
+
+-- +goose Down
+ALTER TABLE pvz DROP CONSTRAINT IF EXISTS pvz_city_check;
+UPDATE pvz SET city = 'санкт-Петербург' WHERE city = 'санкт-петербург';
+ALTER TABLE pvz ADD CONSTRAINT pvz_city_check CHECK (city IN ('москва', 'санкт-Петербург', 'казань'));
@@ -0,0 +1,8 @@
+// Package migrations встраивает SQL миграции в бинарник сервиса,
+// чтобы для их применения не нужен был goose CLI и доступ к сети.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS